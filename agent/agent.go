@@ -0,0 +1,269 @@
+// Package agent lets a program in any language play the game through a
+// gym environment, as a subprocess talking newline-delimited JSON over its
+// standard input and output. The agent sends one command per line and gets
+// one message back for each, so the game only moves when the agent does.
+//
+// The first command has to be a hello with the protocol version:
+//
+//	{"type":"hello","version":1}
+//	{"type":"reset","seed":42}
+//	{"type":"step","action":20}
+//	{"type":"close"}
+//
+// Hello is answered with what the environment is like, reset and step with
+// an observation, and anything wrong with a command with an error message,
+// after which the agent can carry on.
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+
+	"github.com/rodolfato/asteroids/gym"
+	"github.com/rodolfato/asteroids/sensor"
+)
+
+// Version changes whenever the messages do.
+const Version = 1
+
+// MAX_LINE is the longest command that's read.
+const MAX_LINE = 64 * 1024
+
+type command struct {
+	Type    string  `json:"type"`
+	Version int     `json:"version"`
+	Seed    *uint64 `json:"seed"`
+	Action  *int    `json:"action"`
+}
+
+type Hello struct {
+	Type      string   `json:"type"`
+	Version   int      `json:"version"`
+	Actions   int      `json:"actions"`
+	Controls  []string `json:"controls"`
+	Size      int      `json:"size"`
+	Encoding  string   `json:"encoding"`
+	Rays      int      `json:"rays,omitempty"`
+	Entities  int      `json:"entities,omitempty"`
+	FrameSkip int      `json:"frameSkip"`
+	Kinds     []string `json:"kinds"`
+}
+
+// Step answers reset and step. Vector is the observation flattened, the
+// same way for every step.
+type Step struct {
+	Type        string      `json:"type"`
+	Seed        *uint64     `json:"seed,omitempty"`
+	Observation Observation `json:"observation"`
+	Vector      []float32   `json:"vector"`
+	Reward      float32     `json:"reward"`
+	Done        bool        `json:"done"`
+	Info        *Info       `json:"info,omitempty"`
+}
+
+type Observation struct {
+	Alive    bool       `json:"alive"`
+	Vel      [2]float32 `json:"vel"`
+	Heading  float32    `json:"heading"`
+	Rays     []Ray      `json:"rays,omitempty"`
+	Entities []Entity   `json:"entities,omitempty"`
+}
+
+type Ray struct {
+	Angle    float32 `json:"angle"`
+	Distance float32 `json:"distance"`
+	Kind     string  `json:"kind"`
+}
+
+type Entity struct {
+	Kind   string     `json:"kind"`
+	Pos    [2]float32 `json:"pos"`
+	Vel    [2]float32 `json:"vel"`
+	Radius float32    `json:"radius"`
+	Class  int        `json:"class"`
+}
+
+type Info struct {
+	Tick      int   `json:"tick"`
+	Score     int   `json:"score"`
+	Lives     int   `json:"lives"`
+	Wave      int   `json:"wave"`
+	Rounds    int   `json:"rounds"`
+	Kills     []int `json:"kills"`
+	Deaths    int   `json:"deaths"`
+	Truncated bool  `json:"truncated"`
+}
+
+type Error struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// CONTROLS are the names of the bits of an action, lowest first.
+var CONTROLS = []string{"left", "right", "thrust", "reverse", "fire"}
+
+// session is the state of the conversation with an agent.
+type session struct {
+	env     *gym.Env
+	out     *bufio.Writer
+	greeted bool
+	started bool
+	done    bool
+}
+
+// Serve plays env for the agent on the other end of in and out until it
+// closes, its input ends or it can't be understood anymore: a line that's
+// too long, or the wrong protocol version.
+func Serve(in io.Reader, out io.Writer, env *gym.Env) error {
+	s := &session{env: env, out: bufio.NewWriter(out)}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 4096), MAX_LINE)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		closed, err := s.handle(line)
+		if err != nil {
+			return err
+		}
+		if closed {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		s.send(Error{Type: "error", Message: err.Error()})
+		return err
+	}
+	return nil
+}
+
+// handle answers a command. It returns whether the session is over, and an
+// error if it can't go on.
+func (s *session) handle(line []byte) (bool, error) {
+	var c command
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&c); err != nil {
+		return false, s.fail("malformed command: %v", err)
+	}
+	if !s.greeted && c.Type != "hello" {
+		return false, s.fail("the first command has to be a hello with the version, %d", Version)
+	}
+	switch c.Type {
+	case "hello":
+		if c.Version != Version {
+			s.fail("version %d isn't supported, this is version %d", c.Version, Version)
+			return false, fmt.Errorf("the agent speaks version %d", c.Version)
+		}
+		s.greeted = true
+		return false, s.hello()
+	case "reset":
+		seed := rand.Uint64()
+		if c.Seed != nil {
+			seed = *c.Seed
+		}
+		o := s.env.Reset(seed)
+		s.started = true
+		s.done = false
+		return false, s.send(Step{Type: "observation", Seed: &seed, Observation: observation(o), Vector: o.Vector(s.env.Config())})
+	case "step":
+		switch {
+		case !s.started:
+			return false, s.fail("reset before the first step")
+		case s.done:
+			return false, s.fail("the episode is over, reset to start another one")
+		case c.Action == nil:
+			return false, s.fail("step needs an action")
+		case *c.Action < 0 || *c.Action >= gym.NUM_ACTIONS:
+			return false, s.fail("the action is a number from 0 to %d, not %d", gym.NUM_ACTIONS-1, *c.Action)
+		}
+		o, reward, done, info := s.env.Step(gym.Action(*c.Action))
+		s.done = done
+		return false, s.send(Step{
+			Type:        "observation",
+			Observation: observation(o),
+			Vector:      o.Vector(s.env.Config()),
+			Reward:      reward,
+			Done:        done,
+			Info: &Info{
+				Tick:      info.Tick,
+				Score:     info.Score,
+				Lives:     info.Lives,
+				Wave:      info.Wave,
+				Rounds:    info.Rounds,
+				Kills:     info.Kills,
+				Deaths:    info.Deaths,
+				Truncated: info.Truncated,
+			},
+		})
+	case "close":
+		return true, nil
+	case "":
+		return false, s.fail("the command has no type")
+	}
+	return false, s.fail("unknown command %q, it can be hello, reset, step or close", c.Type)
+}
+
+func (s *session) hello() error {
+	config := s.env.Config()
+	hello := Hello{
+		Type:      "hello",
+		Version:   Version,
+		Actions:   gym.NUM_ACTIONS,
+		Controls:  CONTROLS,
+		Size:      config.Size(),
+		Encoding:  config.Encoding.String(),
+		FrameSkip: config.FrameSkip,
+		Kinds:     sensor.KIND_NAMES,
+	}
+	if config.Encoding == gym.Entities {
+		hello.Entities = config.Entities
+	} else {
+		hello.Rays = config.Rays
+	}
+	return s.send(hello)
+}
+
+// fail tells the agent what was wrong with its command. The error returned
+// is only about sending it.
+func (s *session) fail(format string, args ...any) error {
+	return s.send(Error{Type: "error", Message: fmt.Sprintf(format, args...)})
+}
+
+func (s *session) send(message any) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err := s.out.Write(data); err != nil {
+		return err
+	}
+	return s.out.Flush()
+}
+
+func observation(o gym.Observation) Observation {
+	obs := Observation{
+		Alive:   o.Alive,
+		Vel:     [2]float32{o.Vel.X, o.Vel.Y},
+		Heading: o.Heading,
+	}
+	for _, r := range o.Rays {
+		obs.Rays = append(obs.Rays, Ray{Angle: r.Angle, Distance: r.Distance, Kind: r.Kind.String()})
+	}
+	for _, e := range o.Entities {
+		obs.Entities = append(obs.Entities, Entity{
+			Kind:   e.Kind.String(),
+			Pos:    [2]float32{e.Pos.X, e.Pos.Y},
+			Vel:    [2]float32{e.Vel.X, e.Vel.Y},
+			Radius: e.Radius,
+			Class:  e.Class,
+		})
+	}
+	return obs
+}
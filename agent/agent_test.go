@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rodolfato/asteroids/gym"
+)
+
+const HELLO = `{"type":"hello","version":1}`
+
+// reply is any message the agent gets back, with the fields of all of them.
+type reply struct {
+	Type        string      `json:"type"`
+	Message     string      `json:"message"`
+	Version     int         `json:"version"`
+	Actions     int         `json:"actions"`
+	Size        int         `json:"size"`
+	Seed        *uint64     `json:"seed"`
+	Vector      []float32   `json:"vector"`
+	Observation Observation `json:"observation"`
+	Info        *Info       `json:"info"`
+}
+
+// serve plays a session with the agent sending the lines, and returns what
+// it got back, one reply per line, and what Serve returned.
+func serve(t *testing.T, lines ...string) ([]reply, error) {
+	t.Helper()
+	env, err := gym.New(gym.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err = Serve(strings.NewReader(strings.Join(lines, "\n")+"\n"), &out, env)
+	replies := []reply{}
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		var r reply
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("the reply %q isn't JSON: %v", line, err)
+		}
+		replies = append(replies, r)
+	}
+	return replies, err
+}
+
+// wantError fails unless the reply is an error message saying what.
+func wantError(t *testing.T, r reply, what string) {
+	t.Helper()
+	if r.Type != "error" || !strings.Contains(r.Message, what) {
+		t.Errorf("got %+v, want an error about %q", r, what)
+	}
+}
+
+func TestSession(t *testing.T) {
+	replies, err := serve(t,
+		HELLO,
+		`{"type":"reset","seed":42}`,
+		`{"type":"step","action":20}`,
+		"",
+		`{"type":"close"}`,
+		`{"type":"step","action":20}`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 3 {
+		t.Fatalf("got %d replies, want one for each command before close", len(replies))
+	}
+	size := gym.DefaultConfig().Size()
+	if hello := replies[0]; hello.Type != "hello" || hello.Version != Version || hello.Actions != gym.NUM_ACTIONS || hello.Size != size {
+		t.Errorf("got the hello %+v", hello)
+	}
+	if reset := replies[1]; reset.Type != "observation" || reset.Seed == nil || *reset.Seed != 42 || len(reset.Vector) != size {
+		t.Errorf("the reset was answered with %+v", reset)
+	}
+	if step := replies[2]; step.Type != "observation" || step.Info == nil || step.Info.Tick != gym.DefaultFrameSkip || len(step.Vector) != size {
+		t.Errorf("the step was answered with %+v", step)
+	}
+}
+
+func TestVersionMismatch(t *testing.T) {
+	replies, err := serve(t, `{"type":"hello","version":2}`, `{"type":"reset"}`)
+	if err == nil {
+		t.Error("the session went on with an agent of another version")
+	}
+	if len(replies) != 1 {
+		t.Fatalf("got %d replies, want only the error", len(replies))
+	}
+	wantError(t, replies[0], "version 2 isn't supported")
+}
+
+func TestHelloComesFirst(t *testing.T) {
+	replies, err := serve(t, `{"type":"reset"}`, HELLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 2 || replies[1].Type != "hello" {
+		t.Fatalf("got %+v, want an error and then the hello", replies)
+	}
+	wantError(t, replies[0], "first command has to be a hello")
+}
+
+func TestBadCommandsAreAnswered(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{`{"type":"jump"}`, `unknown command "jump"`},
+		{`{"action":1}`, "no type"},
+		{`{"type":"step"`, "malformed command"},
+		{`not json`, "malformed command"},
+		{`{"type":"step","action":1,"speed":2}`, "malformed command"},
+		{`{"type":"step","action":1}`, "reset before the first step"},
+	}
+	for _, test := range tests {
+		//Despues de un error el agente puede seguir, el reset se tiene que contestar
+		replies, err := serve(t, HELLO, test.line, `{"type":"reset","seed":1}`)
+		if err != nil {
+			t.Fatalf("%s: %v", test.line, err)
+		}
+		if len(replies) != 3 {
+			t.Fatalf("%s: got %d replies, want 3", test.line, len(replies))
+		}
+		wantError(t, replies[1], test.want)
+		if replies[2].Type != "observation" {
+			t.Errorf("%s: the reset after it was answered with %+v", test.line, replies[2])
+		}
+	}
+}
+
+func TestBadActions(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{`{"type":"step"}`, "needs an action"},
+		{`{"type":"step","action":-1}`, "not -1"},
+		{`{"type":"step","action":32}`, "not 32"},
+	}
+	for _, test := range tests {
+		replies, err := serve(t, HELLO, `{"type":"reset","seed":1}`, test.line)
+		if err != nil {
+			t.Fatalf("%s: %v", test.line, err)
+		}
+		if len(replies) != 3 {
+			t.Fatalf("%s: got %d replies, want 3", test.line, len(replies))
+		}
+		wantError(t, replies[2], test.want)
+	}
+}
+
+func TestLineTooLong(t *testing.T) {
+	replies, err := serve(t, HELLO, `{"type":"`+strings.Repeat("a", MAX_LINE)+`"}`)
+	if err == nil {
+		t.Error("the session went on after a line longer than MAX_LINE")
+	}
+	if len(replies) != 2 || replies[1].Type != "error" {
+		t.Errorf("got %+v, want the hello and an error", replies)
+	}
+}
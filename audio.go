@@ -0,0 +1,129 @@
+package main
+
+import (
+	"log"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/rodolfato/asteroids/sim"
+	"github.com/rodolfato/asteroids/synth"
+)
+
+const (
+	AUDIO_BUFFER_SIZE   = 1024
+	SPATIAL_ATTENUATION = 0.6
+)
+
+// Audio plays the synthesized sounds through a single raylib audio stream
+// that is refilled from the mixer every frame. A nil *Audio plays nothing.
+type Audio struct {
+	stream rl.AudioStream
+	mixer  *synth.Mixer
+	buffer []float32
+	sounds map[string][]float32
+	thrust *synth.Voice
+	music  *Music
+}
+
+func newAudio() *Audio {
+	rl.InitAudioDevice()
+	if !rl.IsAudioDeviceReady() {
+		log.Println("No audio device, playing without sound")
+		return nil
+	}
+	rl.SetAudioStreamBufferSizeDefault(AUDIO_BUFFER_SIZE)
+
+	a := &Audio{
+		stream: rl.LoadAudioStream(synth.SampleRate, 32, 2),
+		mixer:  synth.NewMixer(),
+		buffer: make([]float32, AUDIO_BUFFER_SIZE*2),
+		sounds: map[string][]float32{},
+		music:  newMusic(),
+	}
+	for name, sound := range synth.Presets {
+		a.sounds[name] = sound.Render(synth.SampleRate)
+	}
+	rl.PlayAudioStream(a.stream)
+	return a
+}
+
+func (a *Audio) update() {
+	if a == nil {
+		return
+	}
+	for rl.IsAudioStreamProcessed(a.stream) {
+		a.mixer.Mix(a.buffer)
+		//raylib toma el largo del slice como la cantidad de frames, no de samples
+		rl.UpdateAudioStream(a.stream, a.buffer[:AUDIO_BUFFER_SIZE])
+	}
+}
+
+func (a *Audio) play(name string) {
+	if a == nil {
+		return
+	}
+	a.mixer.Play(synth.BusSFX, a.sounds[name], 1, false)
+}
+
+// playAt plays the sound panned and attenuated by where its source is
+// relative to the listener, going the shortest way around the screen edges.
+func (a *Audio) playAt(name string, source rl.Vector2, listener rl.Vector2) {
+	if a == nil {
+		return
+	}
+	pan, gain := spatialize(source, listener)
+	a.mixer.Play(synth.BusSFX, a.sounds[name], gain, false).SetPan(pan)
+}
+
+// spatialize returns the stereo pan and the volume for a sound coming from
+// source as heard from listener.
+func spatialize(source rl.Vector2, listener rl.Vector2) (float32, float32) {
+	delta := rl.Vector2(sim.WrappedDelta(sim.Vector2(listener), sim.Vector2(source)))
+	pan := delta.X / (SCREEN_SIZE_X / 2)
+	farthest := rl.Vector2Length(rl.NewVector2(SCREEN_SIZE_X/2, SCREEN_SIZE_Y/2))
+	gain := 1 - SPATIAL_ATTENUATION*min(1, rl.Vector2Length(delta)/farthest)
+	return pan, gain
+}
+
+// setThrust keeps the engine noise looping while the ship is thrusting.
+func (a *Audio) setThrust(thrusting bool) {
+	if a == nil {
+		return
+	}
+	if thrusting && (a.thrust == nil || !a.thrust.Playing()) {
+		a.thrust = a.mixer.Play(synth.BusSFX, a.sounds["thrust"], 1, true)
+	}
+	if !thrusting && a.thrust != nil {
+		a.thrust.Stop()
+		a.thrust = nil
+	}
+}
+
+func (a *Audio) applySettings(s Settings) {
+	if a == nil {
+		return
+	}
+	a.mixer.SetMasterVolume(s.MasterVolume)
+	a.mixer.SetBusVolume(synth.BusSFX, s.SFXVolume)
+	a.mixer.SetBusVolume(synth.BusMusic, s.MusicVolume)
+	a.mixer.SetMuted(s.Muted)
+}
+
+func (a *Audio) close() {
+	if a == nil {
+		return
+	}
+	rl.UnloadAudioStream(a.stream)
+	rl.CloseAudioDevice()
+}
+
+// explosionSound picks the explosion that fits an asteroid of the given class.
+func explosionSound(class int) string {
+	switch class {
+	case 0:
+		return "explosion_large"
+	case 1:
+		return "explosion_medium"
+	default:
+		return "explosion_small"
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"log"
+	"math/rand/v2"
+
+	"github.com/rodolfato/asteroids/bot"
+	"github.com/rodolfato/asteroids/neuro"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// ATTRACT_DIFFICULTY is how well the ship behind the title screen flies.
+const ATTRACT_DIFFICULTY = bot.Normal
+
+// pilot flies a ship in place of the keys: a bot, or a trained genome.
+type pilot interface {
+	Input(w *sim.World) sim.Input
+}
+
+// newTitleWorld starts the game played behind the title screen and the menus,
+// by a pilot that starts it again every time it's over.
+func (g *GameState) newTitleWorld() {
+	seed := rand.Uint64()
+	g.world = sim.NewWorld(seed, sim.Rules{Mode: sim.ModeCoop, Players: 1})
+	g.attract = bot.New(0, ATTRACT_DIFFICULTY, seed)
+	g.attract.Restart = true
+}
+
+func (g *GameState) attractInputs() []sim.Input {
+	return []sim.Input{g.attract.Input(g.world)}
+}
+
+// newAutopilot makes the pilot F2 hands the ship of the player to: the
+// genome loaded with -pilot if there is one, or a bot. A genome that can't
+// fly is dropped for the bot.
+func (g *GameState) newAutopilot(player int) pilot {
+	if g.genome != nil {
+		p, err := neuro.NewPilot(player, g.genome)
+		if err == nil {
+			p.Restart = true
+			return p
+		}
+		log.Printf("Can't fly with %s, the bot flies instead: %v", g.genomePath, err)
+		g.genome = nil
+	}
+	p := bot.New(player, g.autopilotDifficulty, rand.Uint64())
+	p.Restart = true
+	return p
+}
+
+// toggleAutopilot hands the first set of keys over to a pilot, or takes them
+// back. The pilot starts the game again after a game over, so it can be left
+// playing for as long as needed.
+func (g *GameState) toggleAutopilot() {
+	if g.autopilot != nil {
+		g.autopilot = nil
+		return
+	}
+	g.autopilot = g.newAutopilot(0)
+	g.autopilotPlayer = 0
+}
+
+// pilotInput is what the first set of keys does for the player: what's held
+// down, or what the autopilot decides when it's flying. When players take
+// turns each one gets a pilot of their own.
+func (g *GameState) pilotInput(player int) sim.Input {
+	if g.autopilot == nil {
+		return CONTROLS[0].read()
+	}
+	if player != g.autopilotPlayer {
+		g.autopilot = g.newAutopilot(player)
+		g.autopilotPlayer = player
+	}
+	return g.autopilot.Input(g.world)
+}
+
+// autopilotName is what the debug overlay calls the autopilot.
+func (g *GameState) autopilotName() string {
+	if g.genome != nil {
+		return g.genomePath
+	}
+	return g.autopilotDifficulty.String()
+}
@@ -0,0 +1,197 @@
+// Package balance plays lots of games with bots flying every ship and no
+// window, and sums up how they went: how long ships last, what they score,
+// which waves they die in, which asteroids they shoot and what destroys
+// them. Games are seeded, so the same options always give the same numbers,
+// and every variant of the rules is played on the same seeds so variants are
+// compared on the same games.
+package balance
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+
+	"github.com/rodolfato/asteroids/bot"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+const (
+	DefaultGames = 1000
+	// DefaultMaxTicks is ten minutes of play. A game still going by then is
+	// cut short, so a bot that never dies doesn't hold the whole run up.
+	DefaultMaxTicks = 10 * 60 * sim.TICK_RATE
+)
+
+// Variant is a way of playing: the rules and how well the bots fly.
+type Variant struct {
+	Name       string
+	Rules      sim.Rules
+	Difficulty bot.Difficulty
+}
+
+// Options are what gets played.
+type Options struct {
+	Variants []Variant
+	// Games is how many games every variant plays.
+	Games int
+	// Workers is how many games are played at the same time.
+	Workers  int
+	MaxTicks int
+	Seed     uint64
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Games:    DefaultGames,
+		Workers:  runtime.NumCPU(),
+		MaxTicks: DefaultMaxTicks,
+	}
+}
+
+func (o Options) check() error {
+	if len(o.Variants) == 0 {
+		return fmt.Errorf("there are no variants to play")
+	}
+	for _, v := range o.Variants {
+		switch {
+		case v.Rules.Players < 1 || v.Rules.Players > sim.MAX_SHIPS:
+			return fmt.Errorf("%s: a game is for 1 to %d players, not %d", v.Name, sim.MAX_SHIPS, v.Rules.Players)
+		case v.Rules.Mode == sim.ModeDuel && v.Rules.Players != 2:
+			return fmt.Errorf("%s: a duel is for 2 players, not %d", v.Name, v.Rules.Players)
+		}
+	}
+	if o.Games < 1 || o.MaxTicks < 1 {
+		return fmt.Errorf("there has to be at least a game of a tick")
+	}
+	return nil
+}
+
+// Game is how one game went.
+type Game struct {
+	Variant string `json:"variant"`
+	Seed    uint64 `json:"seed"`
+	Ticks   int    `json:"ticks"`
+	// Cut is true when the game was still going after MaxTicks.
+	Cut bool `json:"cut"`
+	// Wave is the furthest wave anyone got to, or the last round of a duel.
+	Wave    int      `json:"wave"`
+	Players []Player `json:"players"`
+	Deaths  []Death  `json:"deaths"`
+}
+
+// Player is how a player did in a game.
+type Player struct {
+	Score int `json:"score"`
+	// Flying is how many ticks the player's ship was in play and in one
+	// piece.
+	Flying int `json:"flying"`
+	// Kills are the asteroids the player shot, by class.
+	Kills  []int `json:"kills"`
+	Rounds int   `json:"rounds,omitempty"`
+}
+
+// Death is a ship being destroyed. Class is the asteroid's, when it was one.
+type Death struct {
+	Player int    `json:"player"`
+	Tick   int    `json:"tick"`
+	Wave   int    `json:"wave"`
+	Cause  string `json:"cause"`
+	Class  int    `json:"class"`
+}
+
+// Run plays every variant the number of games in the options, on the same
+// seeds. The games come back in order, variant by variant.
+func Run(options Options) ([]Game, error) {
+	if err := options.check(); err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewPCG(options.Seed, 0))
+	seeds := make([]uint64, options.Games)
+	for i := range seeds {
+		seeds[i] = rng.Uint64()
+	}
+
+	games := make([]Game, len(options.Variants)*len(seeds))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range max(1, options.Workers) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				v := options.Variants[i/len(seeds)]
+				games[i] = Play(v, seeds[i%len(seeds)], options.MaxTicks)
+			}
+		}()
+	}
+	for i := range games {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return games, nil
+}
+
+// Play plays a game of the variant until it's over or maxTicks have gone by.
+// Bots fly every ship, and the first one presses start between the rounds of
+// a duel.
+func Play(v Variant, seed uint64, maxTicks int) Game {
+	w := sim.NewWorld(seed, v.Rules)
+	pilots := make([]*bot.Pilot, len(w.Players))
+	for i := range pilots {
+		pilots[i] = bot.New(i, v.Difficulty, seed+uint64(i)+1)
+	}
+	g := Game{Variant: v.Name, Seed: seed, Players: make([]Player, len(w.Players))}
+	for i := range g.Players {
+		g.Players[i].Kills = make([]int, len(sim.SCORES))
+	}
+
+	inputs := make([]sim.Input, len(pilots))
+	for w.Phase != sim.PhaseGameOver && w.Tick < maxTicks {
+		for i, p := range pilots {
+			inputs[i] = p.Input(w)
+		}
+		if w.Phase == sim.PhaseRoundOver {
+			inputs[0] |= sim.InputStart
+		}
+		if w.Phase == sim.PhasePlaying {
+			for _, s := range w.Ships {
+				if !s.Collision {
+					g.Players[s.Player].Flying += 1
+				}
+			}
+		}
+		w.Step(inputs)
+		g.Wave = max(g.Wave, wave(w))
+		for _, e := range w.Events {
+			switch e.Kind {
+			case sim.EventAsteroidHit:
+				kills := g.Players[e.Player].Kills
+				kills[max(0, min(len(kills)-1, e.Class))] += 1
+			case sim.EventShipDestroyed:
+				d := Death{Player: e.Player, Tick: w.Tick, Wave: wave(w), Cause: e.Cause.String()}
+				if e.Cause == sim.CauseAsteroid {
+					d.Class = e.Class
+				}
+				g.Deaths = append(g.Deaths, d)
+			}
+		}
+	}
+
+	g.Ticks = w.Tick
+	g.Cut = w.Phase != sim.PhaseGameOver
+	for i, p := range w.Players {
+		g.Players[i].Score = p.Score
+		g.Players[i].Rounds = p.Rounds
+	}
+	return g
+}
+
+// wave is the wave being played, or the round in a duel.
+func wave(w *sim.World) int {
+	if w.Rules.Mode == sim.ModeDuel {
+		return w.Round
+	}
+	return max(1, w.Wave)
+}
@@ -0,0 +1,90 @@
+package balance
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rodolfato/asteroids/bot"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// TEST_TICKS keeps the games short: a bot could otherwise fly for the ten
+// minutes of DefaultMaxTicks.
+const TEST_TICKS = 1500
+
+func testOptions() Options {
+	options := DefaultOptions()
+	options.Games = 3
+	options.MaxTicks = TEST_TICKS
+	options.Seed = 7
+	options.Variants = []Variant{
+		{Name: "coop", Rules: sim.Rules{Mode: sim.ModeCoop, Players: 2}, Difficulty: bot.Easy},
+		{Name: "duel", Rules: sim.Rules{Mode: sim.ModeDuel, Players: 2, DuelRounds: 3}, Difficulty: bot.Hard},
+	}
+	return options
+}
+
+func TestRunIsTheSameWithAnyWorkers(t *testing.T) {
+	var first []Game
+	for _, workers := range []int{1, 2, 5} {
+		options := testOptions()
+		options.Workers = workers
+		games, err := Run(options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(games) != options.Games*len(options.Variants) {
+			t.Fatalf("%d workers played %d games, want %d", workers, len(games), options.Games*len(options.Variants))
+		}
+		if first == nil {
+			first = games
+		} else if !reflect.DeepEqual(games, first) {
+			t.Errorf("%d workers played other games than 1 worker", workers)
+		}
+	}
+
+	//Cada variante juega las mismas semillas, en orden
+	for i, g := range first {
+		v := testOptions().Variants[i/3]
+		if g.Variant != v.Name || g.Seed != first[i%3].Seed {
+			t.Errorf("game %d is %s on seed %d, want %s on seed %d", i, g.Variant, g.Seed, v.Name, first[i%3].Seed)
+		}
+	}
+}
+
+func TestSeedsPlayDifferentGames(t *testing.T) {
+	games, err := Run(testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := testOptions()
+	options.Seed += 1
+	other, err := Run(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(games, other) {
+		t.Error("two seeds played the same games")
+	}
+}
+
+func TestRunChecksTheOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(o *Options)
+	}{
+		{"no variants", func(o *Options) { o.Variants = nil }},
+		{"no games", func(o *Options) { o.Games = 0 }},
+		{"no ticks", func(o *Options) { o.MaxTicks = 0 }},
+		{"no players", func(o *Options) { o.Variants[0].Rules.Players = 0 }},
+		{"too many players", func(o *Options) { o.Variants[0].Rules.Players = sim.MAX_SHIPS + 1 }},
+		{"a duel for three", func(o *Options) { o.Variants[1].Rules.Players = 3 }},
+	}
+	for _, test := range tests {
+		options := testOptions()
+		test.change(&options)
+		if _, err := Run(options); err == nil {
+			t.Errorf("%s: the options were taken", test.name)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package balance
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// CLASS_NAMES are the sizes of the asteroids of every class.
+var CLASS_NAMES = []string{"large", "medium", "small"}
+
+func className(class int) string {
+	if class < 0 || class >= len(CLASS_NAMES) {
+		return fmt.Sprintf("class %d", class)
+	}
+	return CLASS_NAMES[class]
+}
+
+// Report sums up the games of a variant. Times are in seconds, and every
+// player of every game counts once for the scores and the times.
+type Report struct {
+	Variant string `json:"variant"`
+	Games   int    `json:"games"`
+	Cut     int    `json:"cut"`
+	// Survival is how long a player's ship flies in a game, and Life how long
+	// it flies before it's destroyed, on average.
+	Survival float64      `json:"survival"`
+	Life     float64      `json:"life"`
+	Wave     float64      `json:"wave"`
+	Score    Distribution `json:"score"`
+	// Reached is the share of games that get to every wave, from the first.
+	Reached []float64 `json:"reached"`
+	// DeathsPerWave are the ships destroyed in every wave per game, from the
+	// first wave, or the first round of a duel.
+	DeathsPerWave []float64 `json:"deaths_per_wave"`
+	// Kills are the asteroids a player shoots in a game by class, and
+	// KillRate how many a minute of flying.
+	Kills    []float64 `json:"kills"`
+	KillRate []float64 `json:"kill_rate"`
+	// Causes are what destroys ships, the most common first.
+	Causes []Cause `json:"causes"`
+}
+
+type Cause struct {
+	Cause  string  `json:"cause"`
+	Deaths int     `json:"deaths"`
+	Share  float64 `json:"share"`
+}
+
+// Distribution describes a set of values by their mean, standard deviation
+// and percentiles.
+type Distribution struct {
+	Mean      float64 `json:"mean"`
+	Deviation float64 `json:"deviation"`
+	Min       float64 `json:"min"`
+	P10       float64 `json:"p10"`
+	P25       float64 `json:"p25"`
+	Median    float64 `json:"median"`
+	P75       float64 `json:"p75"`
+	P90       float64 `json:"p90"`
+	Max       float64 `json:"max"`
+}
+
+func distribution(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	d := Distribution{
+		Min:    sorted[0],
+		P10:    percentile(sorted, 0.1),
+		P25:    percentile(sorted, 0.25),
+		Median: percentile(sorted, 0.5),
+		P75:    percentile(sorted, 0.75),
+		P90:    percentile(sorted, 0.9),
+		Max:    sorted[len(sorted)-1],
+	}
+	for _, v := range values {
+		d.Mean += v
+	}
+	d.Mean /= float64(len(values))
+	for _, v := range values {
+		d.Deviation += (v - d.Mean) * (v - d.Mean)
+	}
+	d.Deviation = math.Sqrt(d.Deviation / float64(len(values)))
+	return d
+}
+
+// percentile interpolates between the two sorted values around p.
+func percentile(sorted []float64, p float64) float64 {
+	at := p * float64(len(sorted)-1)
+	i := int(at)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (sorted[i+1]-sorted[i])*(at-float64(i))
+}
+
+// cause is what the report calls the cause of a death: asteroids go by size.
+func (d Death) cause() string {
+	if d.Cause == sim.CauseAsteroid.String() {
+		return className(d.Class) + " asteroid"
+	}
+	return d.Cause
+}
+
+// Summarize makes a report for every variant in the games, in the order they
+// first show up.
+func Summarize(games []Game) []Report {
+	reports := []Report{}
+	byVariant := map[string][]Game{}
+	for _, g := range games {
+		if _, ok := byVariant[g.Variant]; !ok {
+			reports = append(reports, Report{Variant: g.Variant})
+		}
+		byVariant[g.Variant] = append(byVariant[g.Variant], g)
+	}
+	for i := range reports {
+		reports[i].summarize(byVariant[reports[i].Variant])
+	}
+	return reports
+}
+
+func (r *Report) summarize(games []Game) {
+	r.Games = len(games)
+	r.Kills = make([]float64, len(sim.SCORES))
+	r.KillRate = make([]float64, len(sim.SCORES))
+	scores := []float64{}
+	flying, deaths := 0, 0
+	causes := map[string]int{}
+	for _, g := range games {
+		if g.Cut {
+			r.Cut += 1
+		}
+		r.Wave += float64(g.Wave)
+		for len(r.Reached) < g.Wave {
+			r.Reached = append(r.Reached, 0)
+		}
+		for wave := range g.Wave {
+			r.Reached[wave] += 1
+		}
+		for _, p := range g.Players {
+			scores = append(scores, float64(p.Score))
+			flying += p.Flying
+			for class, kills := range p.Kills {
+				r.Kills[class] += float64(kills)
+			}
+		}
+		for _, d := range g.Deaths {
+			wave := max(1, d.Wave)
+			for len(r.DeathsPerWave) < wave {
+				r.DeathsPerWave = append(r.DeathsPerWave, 0)
+			}
+			r.DeathsPerWave[wave-1] += 1
+			causes[d.cause()] += 1
+			deaths += 1
+		}
+	}
+
+	r.Score = distribution(scores)
+	seconds := float64(flying) / sim.TICK_RATE
+	r.Survival = seconds / float64(max(1, len(scores)))
+	r.Life = seconds / float64(max(1, deaths))
+	r.Wave /= float64(max(1, r.Games))
+	for i := range r.Reached {
+		r.Reached[i] /= float64(max(1, r.Games))
+	}
+	for i := range r.DeathsPerWave {
+		r.DeathsPerWave[i] /= float64(max(1, r.Games))
+	}
+	for class, kills := range r.Kills {
+		r.Kills[class] = kills / float64(max(1, len(scores)))
+		r.KillRate[class] = kills / max(seconds/60, 1.0/60)
+	}
+	for cause, n := range causes {
+		r.Causes = append(r.Causes, Cause{Cause: cause, Deaths: n, Share: float64(n) / float64(deaths)})
+	}
+	slices.SortFunc(r.Causes, func(a, b Cause) int {
+		if c := cmp.Compare(b.Deaths, a.Deaths); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Cause, b.Cause)
+	})
+}
+
+// WriteJSON writes the reports and, if there are any, every game they sum
+// up.
+func WriteJSON(w io.Writer, reports []Report, games []Game) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(struct {
+		Reports []Report `json:"reports"`
+		Games   []Game   `json:"games,omitempty"`
+	}{reports, games})
+}
+
+// WriteCSV writes a row for every report, with a column for every wave and
+// every cause of death any of them have.
+func WriteCSV(w io.Writer, reports []Report) error {
+	waves := 0
+	causes := []string{}
+	for _, r := range reports {
+		waves = max(waves, len(r.DeathsPerWave))
+		for _, c := range r.Causes {
+			if !slices.Contains(causes, c.Cause) {
+				causes = append(causes, c.Cause)
+			}
+		}
+	}
+	slices.Sort(causes)
+
+	header := []string{"variant", "games", "cut", "survival", "life", "wave",
+		"score_mean", "score_deviation", "score_min", "score_p10", "score_p25",
+		"score_median", "score_p75", "score_p90", "score_max"}
+	for class := range sim.SCORES {
+		header = append(header, "kills_"+className(class), "kill_rate_"+className(class))
+	}
+	for wave := range waves {
+		header = append(header, fmt.Sprintf("deaths_wave_%d", wave+1))
+	}
+	for _, cause := range causes {
+		header = append(header, "death_share_"+strings.ReplaceAll(cause, " ", "_"))
+	}
+
+	out := csv.NewWriter(w)
+	out.Write(header)
+	for _, r := range reports {
+		s := r.Score
+		row := []string{r.Variant, strconv.Itoa(r.Games), strconv.Itoa(r.Cut)}
+		for _, v := range []float64{r.Survival, r.Life, r.Wave, s.Mean, s.Deviation, s.Min, s.P10, s.P25, s.Median, s.P75, s.P90, s.Max} {
+			row = append(row, number(v))
+		}
+		for class := range sim.SCORES {
+			row = append(row, number(r.Kills[class]), number(r.KillRate[class]))
+		}
+		for wave := range waves {
+			deaths := 0.0
+			if wave < len(r.DeathsPerWave) {
+				deaths = r.DeathsPerWave[wave]
+			}
+			row = append(row, number(deaths))
+		}
+		for _, cause := range causes {
+			share := 0.0
+			for _, c := range r.Causes {
+				if c.Cause == cause {
+					share = c.Share
+				}
+			}
+			row = append(row, number(share))
+		}
+		out.Write(row)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func number(v float64) string {
+	return strconv.FormatFloat(v, 'f', 3, 64)
+}
+
+// WriteText writes the reports for people to read.
+func WriteText(w io.Writer, reports []Report) {
+	for _, r := range reports {
+		fmt.Fprintf(w, "%s: %d games", r.Variant, r.Games)
+		if r.Cut > 0 {
+			fmt.Fprintf(w, ", %d cut short", r.Cut)
+		}
+		fmt.Fprintf(w, "\n  survival %.1fs, %.1fs a life, wave %.1f\n", r.Survival, r.Life, r.Wave)
+		s := r.Score
+		fmt.Fprintf(w, "  score %.0f ± %.0f, p10 %.0f, median %.0f, p90 %.0f, max %.0f\n", s.Mean, s.Deviation, s.P10, s.Median, s.P90, s.Max)
+		fmt.Fprintf(w, "  kills a minute:")
+		for class, rate := range r.KillRate {
+			fmt.Fprintf(w, " %s %.1f", className(class), rate)
+		}
+		fmt.Fprintf(w, "\n  deaths by wave:")
+		for wave, deaths := range r.DeathsPerWave {
+			fmt.Fprintf(w, " %d: %.2f", wave+1, deaths)
+		}
+		fmt.Fprintf(w, "\n  causes of death:")
+		for _, c := range r.Causes {
+			fmt.Fprintf(w, " %s %.0f%%", c.Cause, c.Share*100)
+		}
+		fmt.Fprintln(w)
+	}
+}
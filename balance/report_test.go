@@ -0,0 +1,75 @@
+package balance
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		sorted []float64
+		p      float64
+		want   float64
+	}{
+		{[]float64{7}, 0, 7},
+		{[]float64{7}, 0.5, 7},
+		{[]float64{7}, 1, 7},
+		{[]float64{1, 2, 3, 4, 5}, 0, 1},
+		{[]float64{1, 2, 3, 4, 5}, 0.5, 3},
+		{[]float64{1, 2, 3, 4, 5}, 1, 5},
+		{[]float64{1, 2, 3, 4, 5}, 0.25, 2},
+		{[]float64{10, 20}, 0.5, 15},
+		{[]float64{10, 20}, 0.9, 19},
+		{[]float64{0, 10, 20, 30}, 0.5, 15},
+	}
+	for _, test := range tests {
+		if got := percentile(test.sorted, test.p); !near(got, test.want) {
+			t.Errorf("percentile(%v, %v) = %v, want %v", test.sorted, test.p, got, test.want)
+		}
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   Distribution
+	}{
+		{nil, Distribution{}},
+		{[]float64{4}, Distribution{Mean: 4, Min: 4, P10: 4, P25: 4, Median: 4, P75: 4, P90: 4, Max: 4}},
+		{
+			//Desordenados a proposito, la distribucion los ordena sin tocar los originales
+			[]float64{5, 1, 4, 2, 3},
+			Distribution{Mean: 3, Deviation: math.Sqrt2, Min: 1, P10: 1.4, P25: 2, Median: 3, P75: 4, P90: 4.6, Max: 5},
+		},
+		{
+			[]float64{2, 4, 4, 4, 5, 5, 7, 9},
+			Distribution{Mean: 5, Deviation: 2, Min: 2, P10: 3.4, P25: 4, Median: 4.5, P75: 5.5, P90: 7.6, Max: 9},
+		},
+	}
+	for _, test := range tests {
+		values := append([]float64(nil), test.values...)
+		got := distribution(test.values)
+		fields := [][2]float64{
+			{got.Mean, test.want.Mean}, {got.Deviation, test.want.Deviation},
+			{got.Min, test.want.Min}, {got.P10, test.want.P10}, {got.P25, test.want.P25},
+			{got.Median, test.want.Median}, {got.P75, test.want.P75}, {got.P90, test.want.P90},
+			{got.Max, test.want.Max},
+		}
+		for _, f := range fields {
+			if !near(f[0], f[1]) {
+				t.Errorf("distribution(%v) = %+v, want %+v", test.values, got, test.want)
+				break
+			}
+		}
+		for i := range values {
+			if values[i] != test.values[i] {
+				t.Errorf("distribution(%v) changed the order of the values", values)
+				break
+			}
+		}
+	}
+}
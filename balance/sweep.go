@@ -0,0 +1,352 @@
+package balance
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// DefaultSweepGames is fewer games than a single report plays, since a
+// sweep plays them for every configuration.
+const DefaultSweepGames = 200
+
+// DEFAULT_CURVE is a game most players get a few waves into and few get
+// far: the share of games that get to every wave, from the first.
+var DEFAULT_CURVE = Curve{1, 0.9, 0.75, 0.55, 0.4, 0.25, 0.15, 0.1}
+
+// parameter is a constant a sweep can change, by the name of its field in
+// the tuning or of the constant itself.
+type parameter struct {
+	name     string
+	constant string
+	integer  bool
+	get      func(t sim.Tuning) float64
+	set      func(t *sim.Tuning, v float64)
+}
+
+var parameters = []parameter{
+	{"turn_speed", "PLAYER_SHIP_TURN_SPEED", false,
+		func(t sim.Tuning) float64 { return float64(t.TurnSpeed) },
+		func(t *sim.Tuning, v float64) { t.TurnSpeed = float32(v) }},
+	{"thrust", "PLAYER_SHIP_SPEED", false,
+		func(t sim.Tuning) float64 { return float64(t.Thrust) },
+		func(t *sim.Tuning, v float64) { t.Thrust = float32(v) }},
+	{"max_speed", "MAX_SPEED", false,
+		func(t sim.Tuning) float64 { return float64(t.MaxSpeed) },
+		func(t *sim.Tuning, v float64) { t.MaxSpeed = float32(v) }},
+	{"projectile_ttl", "TTL_PRJECTILE", true,
+		func(t sim.Tuning) float64 { return float64(t.ProjectileTTL) },
+		func(t *sim.Tuning, v float64) { t.ProjectileTTL = int(v) }},
+	{"asteroid_speed", "ASTEROID_SPEED", false,
+		func(t sim.Tuning) float64 { return float64(t.AsteroidSpeed) },
+		func(t *sim.Tuning, v float64) { t.AsteroidSpeed = float32(v) }},
+	{"asteroids", "MAX_ASTEROIDS", true,
+		func(t sim.Tuning) float64 { return float64(t.Asteroids) },
+		func(t *sim.Tuning, v float64) { t.Asteroids = int(v) }},
+	{"lives", "LIVES", true,
+		func(t sim.Tuning) float64 { return float64(t.Lives) },
+		func(t *sim.Tuning, v float64) { t.Lives = int(v) }},
+}
+
+func findParameter(name string) (parameter, bool) {
+	for _, p := range parameters {
+		if strings.EqualFold(name, p.name) || strings.EqualFold(name, p.constant) {
+			return p, true
+		}
+	}
+	return parameter{}, false
+}
+
+// Range is the values a constant is tried with: Steps values evenly spread
+// from Min to Max on a grid, or any value between them at random.
+type Range struct {
+	Name  string
+	Min   float64
+	Max   float64
+	Steps int
+}
+
+func (r Range) parameter() parameter {
+	p, _ := findParameter(r.Name)
+	return p
+}
+
+// ParseRanges reads ranges separated by commas, each like
+// turn_speed=0.04:0.1:4, with the number of steps last. Without it a grid
+// tries 3 values. Constants can go by their own name too, like
+// TTL_PRJECTILE=30:60.
+func ParseRanges(list string) ([]Range, error) {
+	ranges := []Range{}
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, values, ok := strings.Cut(field, "=")
+		p, found := findParameter(strings.TrimSpace(name))
+		if !ok || !found {
+			return nil, fmt.Errorf("bad range %q, it's a constant like turn_speed=0.04:0.1:4 and the constants are %s", field, parameterNames())
+		}
+		r := Range{Name: p.name, Steps: 3}
+		numbers := strings.Split(values, ":")
+		var err error
+		if len(numbers) < 2 || len(numbers) > 3 {
+			return nil, fmt.Errorf("bad range %q, it needs a minimum and a maximum", field)
+		}
+		if r.Min, err = strconv.ParseFloat(numbers[0], 64); err != nil {
+			return nil, fmt.Errorf("bad range %q: %w", field, err)
+		}
+		if r.Max, err = strconv.ParseFloat(numbers[1], 64); err != nil {
+			return nil, fmt.Errorf("bad range %q: %w", field, err)
+		}
+		if len(numbers) == 3 {
+			if r.Steps, err = strconv.Atoi(numbers[2]); err != nil || r.Steps < 1 {
+				return nil, fmt.Errorf("bad range %q, the steps are a number above 0", field)
+			}
+		}
+		if !(r.Min > 0 && r.Max >= r.Min) || math.IsInf(r.Max, 0) {
+			return nil, fmt.Errorf("bad range %q, the values go up from above 0", field)
+		}
+		if slices.ContainsFunc(ranges, func(other Range) bool { return other.Name == r.Name }) {
+			return nil, fmt.Errorf("%s has more than one range", r.Name)
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("there are no ranges to sweep, the constants are %s", parameterNames())
+	}
+	return ranges, nil
+}
+
+func parameterNames() string {
+	names := []string{}
+	for _, p := range parameters {
+		names = append(names, p.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// value is the value of the range at step i of a grid.
+func (r Range) value(i int) float64 {
+	v := r.Min
+	if r.Steps > 1 {
+		v += (r.Max - r.Min) * float64(i) / float64(r.Steps-1)
+	}
+	return r.round(v)
+}
+
+func (r Range) round(v float64) float64 {
+	if r.parameter().integer {
+		return max(1, math.Round(v))
+	}
+	return v
+}
+
+// Curve is the share of games that should get to every wave, from the
+// first. The further waves are the harder the game is.
+type Curve []float64
+
+// ParseCurve reads shares separated by commas, like 1,0.8,0.5.
+func ParseCurve(list string) (Curve, error) {
+	curve := Curve{}
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		share, err := strconv.ParseFloat(field, 64)
+		if err != nil || !(share >= 0 && share <= 1) {
+			return nil, fmt.Errorf("bad share %q, it goes from 0 to 1", field)
+		}
+		curve = append(curve, share)
+	}
+	if len(curve) == 0 {
+		return nil, fmt.Errorf("the curve is empty")
+	}
+	return curve, nil
+}
+
+// distance is the root mean square difference between the curve and how
+// many games of the report got to every wave.
+func (c Curve) distance(r Report) float64 {
+	sum := 0.0
+	for i, target := range c {
+		reached := 0.0
+		if i < len(r.Reached) {
+			reached = r.Reached[i]
+		}
+		sum += (reached - target) * (reached - target)
+	}
+	return math.Sqrt(sum / float64(len(c)))
+}
+
+// SweepOptions are the constants to try and how to judge them. Every
+// configuration plays the games of the options, on the same seeds.
+type SweepOptions struct {
+	Options
+	Ranges []Range
+	// Random tries that many configurations picked at random within the
+	// ranges. 0 tries every one on the grid instead.
+	Random int
+	Target Curve
+}
+
+func DefaultSweepOptions() SweepOptions {
+	options := DefaultOptions()
+	options.Games = DefaultSweepGames
+	return SweepOptions{Options: options, Target: DEFAULT_CURVE}
+}
+
+// Trial is how a configuration played. Distance is how far it is from the
+// target curve, averaged over the variants: the closer to 0 the better.
+type Trial struct {
+	Tuning   sim.Tuning `json:"tuning"`
+	Distance float64    `json:"distance"`
+	Reports  []Report   `json:"reports"`
+}
+
+// Configurations are the tunings a sweep tries: the whole grid, or random
+// picks.
+func (o SweepOptions) Configurations() []sim.Tuning {
+	configurations := []sim.Tuning{}
+	if o.Random > 0 {
+		rng := rand.New(rand.NewPCG(o.Seed, 1))
+		for range o.Random {
+			t := sim.Tuning{}
+			for _, r := range o.Ranges {
+				r.parameter().set(&t, r.round(r.Min+(r.Max-r.Min)*rng.Float64()))
+			}
+			configurations = append(configurations, t)
+		}
+		return configurations
+	}
+	steps := make([]int, len(o.Ranges))
+	for {
+		t := sim.Tuning{}
+		for i, r := range o.Ranges {
+			r.parameter().set(&t, r.value(steps[i]))
+		}
+		configurations = append(configurations, t)
+		//Como un odometro: avanza el primero y arrastra a los siguientes
+		i := 0
+		for ; i < len(steps); i++ {
+			steps[i] += 1
+			if steps[i] < o.Ranges[i].Steps {
+				break
+			}
+			steps[i] = 0
+		}
+		if i == len(steps) {
+			return configurations
+		}
+	}
+}
+
+// Sweep plays every configuration and ranks them, the closest to the target
+// first. done is called after every configuration, in the order they're
+// played.
+func Sweep(options SweepOptions, done func(i int, t Trial)) ([]Trial, error) {
+	if len(options.Ranges) == 0 {
+		return nil, fmt.Errorf("there are no ranges to sweep")
+	}
+	for _, r := range options.Ranges {
+		if _, ok := findParameter(r.Name); !ok {
+			return nil, fmt.Errorf("%s isn't a constant a sweep can change, they are %s", r.Name, parameterNames())
+		}
+	}
+	if len(options.Target) == 0 {
+		return nil, fmt.Errorf("there's no target curve")
+	}
+	trials := []Trial{}
+	for i, tuning := range options.Configurations() {
+		run := options.Options
+		run.Variants = slices.Clone(options.Variants)
+		for v := range run.Variants {
+			run.Variants[v].Rules.Tuning = tuning
+		}
+		games, err := Run(run)
+		if err != nil {
+			return nil, err
+		}
+		t := Trial{Tuning: tuning, Reports: Summarize(games)}
+		for _, r := range t.Reports {
+			t.Distance += options.Target.distance(r)
+		}
+		t.Distance /= float64(len(t.Reports))
+		trials = append(trials, t)
+		if done != nil {
+			done(i, t)
+		}
+	}
+	slices.SortStableFunc(trials, func(a, b Trial) int {
+		return cmp.Compare(a.Distance, b.Distance)
+	})
+	return trials, nil
+}
+
+// WriteSweepJSON writes the trials, best first, with the target they were
+// ranked by.
+func WriteSweepJSON(w io.Writer, target Curve, trials []Trial) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(struct {
+		Target Curve   `json:"target"`
+		Trials []Trial `json:"trials"`
+	}{target, trials})
+}
+
+// WriteSweepCSV writes a row for every trial and variant, best trial first,
+// with the constants swept and how far the games got.
+func WriteSweepCSV(w io.Writer, ranges []Range, target Curve, trials []Trial) error {
+	header := []string{"rank", "distance"}
+	for _, r := range ranges {
+		header = append(header, r.Name)
+	}
+	header = append(header, "variant", "survival", "wave", "score_mean")
+	for wave := range target {
+		header = append(header, fmt.Sprintf("reached_wave_%d", wave+1))
+	}
+
+	out := csv.NewWriter(w)
+	out.Write(header)
+	for rank, t := range trials {
+		tuned := sim.Rules{Tuning: t.Tuning}.Tuned()
+		for _, report := range t.Reports {
+			row := []string{strconv.Itoa(rank + 1), number(t.Distance)}
+			for _, r := range ranges {
+				row = append(row, strconv.FormatFloat(r.parameter().get(tuned), 'g', 6, 64))
+			}
+			row = append(row, report.Variant, number(report.Survival), number(report.Wave), number(report.Score.Mean))
+			for wave := range target {
+				reached := 0.0
+				if wave < len(report.Reached) {
+					reached = report.Reached[wave]
+				}
+				row = append(row, number(reached))
+			}
+			out.Write(row)
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// Describe says what a trial's constants are, like turn_speed=0.06
+// asteroid_speed=1.5.
+func Describe(ranges []Range, t Trial) string {
+	tuned := sim.Rules{Tuning: t.Tuning}.Tuned()
+	fields := []string{}
+	for _, r := range ranges {
+		fields = append(fields, fmt.Sprintf("%s=%s", r.Name, strconv.FormatFloat(r.parameter().get(tuned), 'g', 4, 64)))
+	}
+	return strings.Join(fields, " ")
+}
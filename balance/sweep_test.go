@@ -0,0 +1,145 @@
+package balance
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+func TestParseRanges(t *testing.T) {
+	ranges, err := ParseRanges(" turn_speed=0.04:0.1:4, TTL_PRJECTILE=30:60 ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Range{
+		{Name: "turn_speed", Min: 0.04, Max: 0.1, Steps: 4},
+		{Name: "projectile_ttl", Min: 30, Max: 60, Steps: 3},
+	}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("got %+v, want %+v", ranges, want)
+	}
+}
+
+func TestBadRanges(t *testing.T) {
+	tests := []string{
+		"",
+		" , ",
+		"turn_speed",
+		"warp=1:2",
+		"turn_speed=0.1",
+		"turn_speed=0.1:0.2:3:4",
+		"turn_speed=a:0.2",
+		"turn_speed=0.1:b",
+		"turn_speed=0.1:0.2:0",
+		"turn_speed=0.1:0.2:many",
+		"turn_speed=0:0.2",
+		"turn_speed=0.2:0.1",
+		"turn_speed=nan:0.2",
+		"turn_speed=0.1:nan",
+		"turn_speed=0.1:inf",
+		"turn_speed=0.1:0.2,PLAYER_SHIP_TURN_SPEED=0.1:0.3",
+	}
+	for _, list := range tests {
+		if ranges, err := ParseRanges(list); err == nil {
+			t.Errorf("%q was read as %+v", list, ranges)
+		}
+	}
+}
+
+func TestParseCurve(t *testing.T) {
+	curve, err := ParseCurve("1, 0.8,0.5,0,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Curve{1, 0.8, 0.5, 0}); !reflect.DeepEqual(curve, want) {
+		t.Errorf("got %v, want %v", curve, want)
+	}
+	for _, list := range []string{"", ",", "1,x", "1,-0.1", "1.5", "1,,0.5,nan"} {
+		if curve, err := ParseCurve(list); err == nil {
+			t.Errorf("%q was read as %v", list, curve)
+		}
+	}
+}
+
+func TestGridIsAnOdometer(t *testing.T) {
+	options := DefaultSweepOptions()
+	options.Ranges = []Range{
+		{Name: "lives", Min: 1, Max: 3, Steps: 3},
+		{Name: "max_speed", Min: 4, Max: 6, Steps: 2},
+	}
+	//El primero cambia en cada configuracion y arrastra al siguiente
+	want := [][2]float64{{1, 4}, {2, 4}, {3, 4}, {1, 6}, {2, 6}, {3, 6}}
+	configurations := options.Configurations()
+	if len(configurations) != len(want) {
+		t.Fatalf("got %d configurations, want %d", len(configurations), len(want))
+	}
+	for i, c := range configurations {
+		if got := [2]float64{float64(c.Lives), float64(c.MaxSpeed)}; got != want[i] {
+			t.Errorf("configuration %d has lives and max speed %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+func TestIntegerRangesAreRounded(t *testing.T) {
+	options := DefaultSweepOptions()
+	options.Ranges = []Range{{Name: "asteroids", Min: 1, Max: 2, Steps: 4}}
+	got := []int{}
+	for _, c := range options.Configurations() {
+		got = append(got, c.Asteroids)
+	}
+	if want := []int{1, 1, 2, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v asteroids, want %v", got, want)
+	}
+}
+
+func TestRandomConfigurations(t *testing.T) {
+	options := DefaultSweepOptions()
+	options.Ranges = []Range{{Name: "thrust", Min: 0.1, Max: 0.2}, {Name: "lives", Min: 2, Max: 5}}
+	options.Random = 20
+	configurations := options.Configurations()
+	if len(configurations) != options.Random {
+		t.Fatalf("got %d configurations, want %d", len(configurations), options.Random)
+	}
+	for _, c := range configurations {
+		if c.Thrust < 0.1 || c.Thrust > 0.2 || c.Lives < 2 || c.Lives > 5 {
+			t.Errorf("%+v is out of the ranges", c)
+		}
+	}
+	if !reflect.DeepEqual(configurations, options.Configurations()) {
+		t.Error("the same seed picked other configurations")
+	}
+	options.Seed += 1
+	if reflect.DeepEqual(configurations, options.Configurations()) {
+		t.Error("another seed picked the same configurations")
+	}
+}
+
+func TestCurveDistance(t *testing.T) {
+	curve := Curve{1, 0.5}
+	tests := []struct {
+		reached []float64
+		want    float64
+	}{
+		{[]float64{1, 0.5}, 0},
+		{[]float64{1, 0.5, 0.2}, 0},
+		{[]float64{1}, 0.5 / math.Sqrt2},
+		{[]float64{0, 0.5}, 1 / math.Sqrt2},
+	}
+	for _, test := range tests {
+		if got := curve.distance(Report{Reached: test.reached}); !near(got, test.want) {
+			t.Errorf("distance to %v = %v, want %v", test.reached, got, test.want)
+		}
+	}
+}
+
+func TestConfigurationsOnlySetTheRanges(t *testing.T) {
+	//Lo que no se barre queda en cero, asi el juego usa sus valores de siempre
+	options := DefaultSweepOptions()
+	options.Ranges = []Range{{Name: "turn_speed", Min: 0.05, Max: 0.05, Steps: 1}}
+	configurations := options.Configurations()
+	if want := (sim.Tuning{TurnSpeed: 0.05}); len(configurations) != 1 || configurations[0] != want {
+		t.Errorf("got %+v, want %+v", configurations, want)
+	}
+}
@@ -0,0 +1,369 @@
+// Package bot flies a ship by itself, through the same inputs the keys of a
+// player turn into. It shoots whatever it can hit soonest, leading the shot
+// to where the target will be when the projectile gets there, and gets out
+// of the way of anything about to hit it. Every distance is measured the
+// short way around the edges of the screen, as things wrap around them.
+//
+// In a duel the other ship is a target too, and its shots and the star are
+// things to stay away from.
+package bot
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/rodolfato/asteroids/sensor"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+type Difficulty int
+
+const (
+	Easy Difficulty = iota
+	Normal
+	Hard
+)
+
+var DIFFICULTY_NAMES = []string{"easy", "normal", "hard"}
+
+func (d Difficulty) String() string {
+	if d < 0 || int(d) >= len(DIFFICULTY_NAMES) {
+		return fmt.Sprintf("Difficulty(%d)", int(d))
+	}
+	return DIFFICULTY_NAMES[d]
+}
+
+func ParseDifficulty(name string) (Difficulty, error) {
+	for i, n := range DIFFICULTY_NAMES {
+		if n == name {
+			return Difficulty(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown difficulty %q, it can be easy, normal or hard", name)
+}
+
+// Level is how well a pilot flies.
+type Level struct {
+	// Reaction is how many ticks something has to be there before the pilot
+	// does something about it: dodge a threat or shoot a new target.
+	Reaction int
+	// AimError is the most, in radians, a shot can be off from where it
+	// should go.
+	AimError float32
+	// FireInterval is the fewest ticks between two shots.
+	FireInterval int
+	// Lookahead is how many ticks ahead the pilot sees a collision coming.
+	Lookahead float32
+	// Cruise is the speed the pilot keeps under when it isn't dodging.
+	Cruise float32
+}
+
+// LEVELS are indexed by Difficulty.
+var LEVELS = []Level{
+	Easy:   {Reaction: 18, AimError: 0.12, FireInterval: 24, Lookahead: 25, Cruise: 1.5},
+	Normal: {Reaction: 8, AimError: 0.05, FireInterval: 12, Lookahead: 45, Cruise: 1},
+	Hard:   {Reaction: 2, AimError: 0.01, FireInterval: 6, Lookahead: 70, Cruise: 0.5},
+}
+
+// SHOT_SPEED is how fast a projectile leaves the ship, on top of the ship's
+// own velocity.
+const SHOT_SPEED = sim.PROJECTILE_SPEED + sim.PLAYER_SHIP_SPEED
+
+// LINE_OF_FIRE looks down the barrel, with a narrow fan of rays as far as a
+// shot goes. A game with tuned constants reaches as far as its shots do.
+var LINE_OF_FIRE = sensor.Sensor{Angles: []float32{-0.05, 0, 0.05}, Range: SHOT_SPEED * sim.TTL_PRJECTILE}
+
+// MUZZLE is how far in front of the ship's center projectiles appear.
+const MUZZLE = sim.PLAYER_SHIP_SIZE + 10
+
+// Pilot flies the ship of a player. Everything random about it comes from
+// its seed, so a game played by pilots can be played again.
+type Pilot struct {
+	Player int
+	Level  Level
+	// Restart presses start after a round or a game over, so a game left to
+	// pilots keeps going.
+	Restart bool
+	rng     *rand.Rand
+	seen    int
+	target  int
+	aimed   int
+	reload  int
+	aim     float32
+	tuning  sim.Tuning
+}
+
+func New(player int, difficulty Difficulty, seed uint64) *Pilot {
+	return &Pilot{
+		Player: player,
+		Level:  LEVELS[max(0, min(int(difficulty), len(LEVELS)-1))],
+		rng:    rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)),
+		target: -1,
+	}
+}
+
+// Input decides what the pilot does this tick, looking at the world after
+// the last one.
+func (p *Pilot) Input(w *sim.World) sim.Input {
+	p.tuning = w.Rules.Tuned()
+	if p.reload > 0 {
+		p.reload -= 1
+	}
+	if w.Phase != sim.PhasePlaying {
+		if p.Restart && w.Tick%sim.TICK_RATE == 0 {
+			return sim.InputStart
+		}
+		return 0
+	}
+	ship := p.ship(w)
+	if ship == nil || ship.Collision {
+		p.seen = 0
+		return 0
+	}
+
+	t, ok := p.threat(w, ship)
+	if ok {
+		p.seen += 1
+	} else {
+		p.seen = 0
+	}
+	//Esquivar es solo empujar: la nave sigue apuntando y disparando
+	in := p.brake(ship)
+	if ok && p.seen >= p.Level.Reaction {
+		in = p.dodge(ship, t)
+	}
+	if target, ok := p.choose(w, ship); ok {
+		in |= p.shoot(w, ship, target)
+	}
+	return in
+}
+
+// ship returns the pilot's ship if it's in play.
+func (p *Pilot) ship(w *sim.World) *sim.PlayerShip {
+	for _, s := range w.Ships {
+		if s.Player == p.Player {
+			return s
+		}
+	}
+	return nil
+}
+
+// threat is something that will hit the ship if nothing changes. Closest is
+// where it will be, from the ship, when they're closest, in Time ticks.
+type threat struct {
+	closest sim.Vector2
+	time    float32
+}
+
+// approach returns when and where something at delta from the ship, moving
+// at vel relative to it, gets closest within the lookahead.
+func (p *Pilot) approach(delta, vel sim.Vector2) (sim.Vector2, float32) {
+	t := float32(0)
+	if speed := sim.Vector2DotProduct(vel, vel); speed > 0 {
+		t = -sim.Vector2DotProduct(delta, vel) / speed
+	}
+	t = max(0, min(p.Level.Lookahead, t))
+	return sim.Vector2Add(delta, sim.Vector2Scale(vel, t)), t
+}
+
+// threat finds what's going to hit the ship soonest. The ship moves twice
+// its velocity every tick, once when steered and once more with the rest.
+func (p *Pilot) threat(w *sim.World, ship *sim.PlayerShip) (threat, bool) {
+	shipVel := sim.Vector2Scale(ship.Vel, 2)
+	found := threat{time: math.MaxFloat32}
+	check := func(pos, vel sim.Vector2, radius float32) {
+		closest, t := p.approach(sim.WrappedDelta(ship.Pos, pos), sim.Vector2Subtract(vel, shipVel))
+		if sim.Vector2Length(closest) < radius && t < found.time {
+			found = threat{closest: closest, time: t}
+		}
+	}
+
+	for _, a := range w.Asteroids {
+		check(a.Pos, a.Vel, a.Radius()+ship.Size)
+	}
+	duel := w.Rules.Mode == sim.ModeDuel
+	for _, other := range w.Ships {
+		if other == ship {
+			continue
+		}
+		if duel && !other.Collision {
+			check(other.Pos, sim.Vector2Scale(other.Vel, 2), other.Size+ship.Size)
+		}
+		if duel || w.Rules.FriendlyFire {
+			for _, shot := range other.Projectiles {
+				check(shot.Pos, shot.Vel, shot.Size+ship.Size)
+			}
+		}
+	}
+	if duel {
+		check(sim.STAR_POS, sim.Vector2Zero(), sim.STAR_RADIUS+ship.Size*2)
+	}
+	return found, found.time < math.MaxFloat32
+}
+
+// dodge pushes the ship away from where the threat will pass, thrusting or
+// reversing, whichever is closer to that way. It doesn't turn: turning is
+// slow, and the ship is better off still aiming at what it shoots.
+func (p *Pilot) dodge(ship *sim.PlayerShip, t threat) sim.Input {
+	away := sim.Vector2Negate(t.closest)
+	if sim.Vector2Length(away) < 1 {
+		//De frente: se escapa hacia un costado
+		away = sim.GetDirection(ship.Orientation + math.Pi/2)
+	}
+	if sim.Vector2DotProduct(away, sim.GetDirection(ship.Orientation)) >= 0 {
+		return sim.InputThrust
+	}
+	return sim.InputReverse
+}
+
+// brake slows the ship down when it goes faster than the cruise speed, as
+// much as it can without turning away from what it's aiming at.
+func (p *Pilot) brake(ship *sim.PlayerShip) sim.Input {
+	if sim.Vector2Length(ship.Vel) <= p.Level.Cruise {
+		return 0
+	}
+	along := sim.Vector2DotProduct(ship.Vel, sim.GetDirection(ship.Orientation))
+	switch {
+	case along > p.Level.Cruise/2:
+		return sim.InputReverse
+	case along < -p.Level.Cruise/2:
+		return sim.InputThrust
+	}
+	return 0
+}
+
+// target is something to shoot: Aim is where to point to hit it and Time how
+// many ticks the shot takes to get there.
+type target struct {
+	id     int
+	aim    sim.Vector2
+	time   float32
+	radius float32
+}
+
+// intercept returns where to shoot from the ship to hit something at pos
+// moving at vel, and how long the shot takes, if it gets there before it
+// runs out.
+func (p *Pilot) intercept(ship *sim.PlayerShip, pos, vel sim.Vector2) (sim.Vector2, float32, bool) {
+	//Los proyectiles heredan la velocidad de la nave
+	delta := sim.WrappedDelta(ship.Pos, pos)
+	rel := sim.Vector2Subtract(vel, ship.Vel)
+	a := sim.Vector2DotProduct(rel, rel) - SHOT_SPEED*SHOT_SPEED
+	b := 2 * sim.Vector2DotProduct(delta, rel)
+	c := sim.Vector2DotProduct(delta, delta)
+	discriminant := b*b - 4*a*c
+	if a >= 0 || discriminant < 0 {
+		return sim.Vector2{}, 0, false
+	}
+	t := (-b - float32(math.Sqrt(float64(discriminant)))) / (2 * a)
+	aim := sim.Vector2Add(delta, sim.Vector2Scale(rel, t))
+	//El proyectil sale desde la punta de la nave
+	t = max(0, t-MUZZLE/SHOT_SPEED)
+	return aim, t, t < float32(p.tuning.ProjectileTTL)
+}
+
+// choose picks the target that takes the least time to turn to and hit,
+// sticking to the current one unless another is clearly better.
+func (p *Pilot) choose(w *sim.World, ship *sim.PlayerShip) (target, bool) {
+	best := target{id: -2}
+	bestCost := float32(math.MaxFloat32)
+	consider := func(id int, pos, vel sim.Vector2, r float32) {
+		aim, t, ok := p.intercept(ship, pos, vel)
+		if !ok {
+			return
+		}
+		turning := float32(math.Abs(float64(angleDiff(angleTo(aim), ship.Orientation)))) / p.tuning.TurnSpeed
+		cost := t + turning
+		if id == p.target {
+			cost *= 0.7
+		}
+		if cost < bestCost {
+			best = target{id: id, aim: aim, time: t, radius: r}
+			bestCost = cost
+		}
+	}
+	for _, a := range w.Asteroids {
+		consider(a.ID, a.Pos, a.Vel, a.Radius())
+	}
+	if w.Rules.Mode == sim.ModeDuel {
+		for _, other := range w.Ships {
+			if other != ship && !other.Collision {
+				consider(-1-other.Player, other.Pos, sim.Vector2Scale(other.Vel, 2), other.Size*0.6)
+			}
+		}
+	}
+	if best.id == -2 {
+		p.target = -2
+		return best, false
+	}
+	if best.id != p.target {
+		p.target = best.id
+		p.aimed = 0
+		p.aim = (p.rng.Float32()*2 - 1) * p.Level.AimError
+	}
+	return best, true
+}
+
+// shoot turns towards the target and fires once the ship points at it,
+// it's been aimed at long enough and the gun is ready.
+func (p *Pilot) shoot(w *sim.World, ship *sim.PlayerShip, t target) sim.Input {
+	p.aimed += 1
+	diff := angleDiff(angleTo(t.aim)+p.aim, ship.Orientation)
+	in := p.turn(diff)
+	//Se gira antes de disparar en el mismo tick
+	switch {
+	case in.Has(sim.InputRight):
+		diff -= p.tuning.TurnSpeed
+	case in.Has(sim.InputLeft):
+		diff += p.tuning.TurnSpeed
+	}
+	distance := max(sim.Vector2Length(t.aim), 1)
+	tolerance := float32(math.Atan(float64(t.radius * 0.8 / distance)))
+	if float32(math.Abs(float64(diff))) > tolerance || p.reload > 0 || p.aimed < p.Level.Reaction {
+		return in
+	}
+	if p.blocked(w, ship, distance) {
+		return in
+	}
+	p.reload = p.Level.FireInterval
+	p.aim = (p.rng.Float32()*2 - 1) * p.Level.AimError
+	return in | sim.InputFire
+}
+
+// blocked is true when a friendly ship that can be shot is in the line of
+// fire before the target.
+func (p *Pilot) blocked(w *sim.World, ship *sim.PlayerShip, distance float32) bool {
+	if w.Rules.Mode != sim.ModeCoop || !w.Rules.FriendlyFire {
+		return false
+	}
+	fire := LINE_OF_FIRE
+	fire.Range = SHOT_SPEED * float32(p.tuning.ProjectileTTL)
+	for _, r := range fire.Read(w, ship) {
+		if r.Kind == sensor.KindShip && r.Distance < distance {
+			return true
+		}
+	}
+	return false
+}
+
+// turn returns the key that turns the ship by diff radians, if it's worth
+// turning. The ship turns clockwise, to bigger angles, with right.
+func (p *Pilot) turn(diff float32) sim.Input {
+	switch {
+	case diff > p.tuning.TurnSpeed/2:
+		return sim.InputRight
+	case diff < -p.tuning.TurnSpeed/2:
+		return sim.InputLeft
+	}
+	return 0
+}
+
+func angleTo(v sim.Vector2) float32 {
+	return float32(math.Atan2(float64(v.Y), float64(v.X)))
+}
+
+// angleDiff returns how much to turn from b to a, between -Pi and Pi.
+func angleDiff(a, b float32) float32 {
+	return float32(math.Remainder(float64(a-b), 2*math.Pi))
+}
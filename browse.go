@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/rodolfato/asteroids/discovery"
+	"github.com/rodolfato/asteroids/netplay"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// HOST_ADDR is where the games hosted from the browser listen.
+const HOST_ADDR = ":7777"
+
+// JOIN_KEYS join the games listed in the browser, in order.
+var JOIN_KEYS = []int32{rl.KeyOne, rl.KeyTwo, rl.KeyThree, rl.KeyFour, rl.KeyFive, rl.KeySix, rl.KeySeven, rl.KeyEight, rl.KeyNine}
+
+// openBrowser lists the games hosted on the local network, found by their
+// announcements.
+func (g *GameState) openBrowser() {
+	browser, err := discovery.Browse("")
+	if err != nil {
+		log.Println("Can't look for games on the local network:", err)
+		g.message = err.Error()
+		return
+	}
+	g.browser = browser
+	g.message = ""
+	g.scene = SCENE_BROWSE
+}
+
+func (g *GameState) closeBrowser() {
+	g.browser.Close()
+	g.browser = nil
+}
+
+func (g *GameState) updateBrowser() {
+	games := g.browser.Games()
+	for i, key := range JOIN_KEYS {
+		if !rl.IsKeyPressed(key) || i >= len(games) || games[i].Version != netplay.Version {
+			continue
+		}
+		g.closeBrowser()
+		if err := g.joinGame(games[i].Addr, g.netOptions); err != nil {
+			g.message = err.Error()
+			g.scene = SCENE_TITLE
+		}
+		return
+	}
+
+	var err error
+	switch {
+	case rl.IsKeyPressed(rl.KeyH):
+		g.closeBrowser()
+		err = g.hostGame(HOST_ADDR, sim.ModeCoop, g.settings.CoopShips, g.netOptions)
+	case rl.IsKeyPressed(rl.KeyV):
+		g.closeBrowser()
+		err = g.hostGame(HOST_ADDR, sim.ModeDuel, 2, g.netOptions)
+	case rl.IsKeyPressed(rl.KeyBackspace):
+		g.closeBrowser()
+		g.scene = SCENE_TITLE
+	}
+	if err != nil {
+		g.message = err.Error()
+		g.scene = SCENE_TITLE
+	}
+}
+
+func (g *GameState) drawBrowser() {
+	rl.DrawTextPro(rl.GetFontDefault(), "Local network", rl.Vector2{
+		X: SCREEN_SIZE_X / 2,
+		Y: 150,
+	}, rl.Vector2Scale(rl.MeasureTextEx(rl.GetFontDefault(), "Local network", 80.0, 1.0), 0.5), 0.0, 80.0, 1.0, rl.White)
+	games := g.browser.Games()
+	if len(games) == 0 {
+		rl.DrawTextPro(rl.GetFontDefault(), "Looking for games...", rl.Vector2{
+			X: SCREEN_SIZE_X / 2,
+			Y: SCREEN_SIZE_Y / 2,
+		}, rl.Vector2Scale(rl.MeasureTextEx(rl.GetFontDefault(), "Looking for games...", 30.0, 1.0), 0.5), 0.0, 30.0, 1.0, rl.Gray)
+	}
+	for i, game := range games[:min(len(games), len(JOIN_KEYS))] {
+		mode := "co-op"
+		if game.Mode == sim.ModeDuel {
+			mode = "versus"
+		}
+		label := fmt.Sprintf("%d  %-20s %-7s %d/%d", i+1, game.Name, mode, game.Joined, game.Players)
+		color := rl.White
+		if game.Version != netplay.Version {
+			label += fmt.Sprintf("  version %d", game.Version)
+			color = rl.DarkGray
+		}
+		rl.DrawTextEx(rl.GetFontDefault(), label, rl.Vector2{
+			X: SCREEN_SIZE_X/2 - 300,
+			Y: 240 + 45*float32(i),
+		}, 30.0, 2.0, color)
+	}
+	rl.DrawTextEx(rl.GetFontDefault(), "Number to join, H to host co-op, V to host versus, Backspace to go back", rl.Vector2{
+		X: SCREEN_SIZE_X/2 - 350,
+		Y: SCREEN_SIZE_Y - 60,
+	}, 15.0, 1.0, rl.Gray)
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rodolfato/asteroids/agent"
+	"github.com/rodolfato/asteroids/balance"
+	"github.com/rodolfato/asteroids/bot"
+	"github.com/rodolfato/asteroids/gym"
+	"github.com/rodolfato/asteroids/neuro"
+	"github.com/rodolfato/asteroids/render"
+	"github.com/rodolfato/asteroids/server"
+	"github.com/rodolfato/asteroids/sim"
+	"github.com/rodolfato/asteroids/spectate"
+)
+
+// runServer runs matches with no window for the games that connect to it,
+// until it's killed.
+func runServer(args []string) {
+	flags := flag.NewFlagSet("server", flag.ExitOnError)
+	addr := flags.String("addr", ":7778", "address to listen on, over both TCP and UDP")
+	matches := flags.Int("max-matches", server.DefaultMaxMatches, "most matches running at the same time")
+	spectateAddr := flags.String("spectate", "", "let browsers watch the matches at this address, like localhost:8080")
+	flags.Parse(args)
+
+	s := server.New()
+	s.MaxMatches = *matches
+	if *spectateAddr != "" {
+		s.Spectators = spectate.NewHub()
+		go func() {
+			log.Fatal(s.Spectators.ListenAndServe(*spectateAddr))
+		}()
+	}
+	if err := s.Listen(*addr); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("server: listening on %s", s.Addr())
+	if err := s.Serve(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runAgent lets an agent drive the game through its standard input and
+// output, with no window, until it closes. Logs go to the standard error.
+func runAgent(args []string) {
+	config := gym.DefaultConfig()
+	flags := flag.NewFlagSet("agent", flag.ExitOnError)
+	mode := flags.String("mode", "coop", "the game the agent plays: coop or versus")
+	flags.IntVar(&config.Rules.Players, "players", config.Rules.Players, "how many ships there are, the agent's and the bots'")
+	flags.IntVar(&config.Player, "player", config.Player, "the ship the agent flies, from 0")
+	flags.BoolVar(&config.Rules.FriendlyFire, "friendly-fire", false, "let the shots of the ships in a co-op game destroy each other")
+	flags.IntVar(&config.Rules.DuelRounds, "rounds", 3, "rounds in a duel")
+	flags.IntVar(&config.FrameSkip, "frame-skip", config.FrameSkip, "ticks every action is held for")
+	encoding := flags.String("encoding", config.Encoding.String(), "what observations describe: rays or entities")
+	flags.IntVar(&config.Rays, "rays", config.Rays, "how many rays observations have")
+	flags.IntVar(&config.Entities, "entities", config.Entities, "the most entities observations have")
+	flags.IntVar(&config.MaxSteps, "max-steps", 0, "steps an episode is cut short after, 0 for no limit")
+	opponents := flags.String("opponents", config.Opponents.String(), "how well the bots flying the other ships play: easy, normal or hard")
+	flags.Parse(args)
+
+	var err error
+	if config.Rules.Mode, err = parseMode(*mode); err != nil {
+		log.Fatal(err)
+	}
+	if config.Rules.Mode == sim.ModeDuel {
+		config.Rules.Players = 2
+	}
+	if config.Encoding, err = gym.ParseEncoding(*encoding); err != nil {
+		log.Fatal(err)
+	}
+	if config.Opponents, err = bot.ParseDifficulty(*opponents); err != nil {
+		log.Fatal(err)
+	}
+	env, err := gym.New(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := agent.Serve(os.Stdin, os.Stdout, env); err != nil {
+		log.Fatal("agent: ", err)
+	}
+}
+
+// runTrain evolves genomes that fly a ship, saving the population in the
+// output directory after every generation, and the best genome whenever one
+// beats it, for -pilot to load.
+func runTrain(args []string) {
+	options := neuro.DefaultOptions()
+	flags := flag.NewFlagSet("train", flag.ExitOnError)
+	out := flags.String("out", "training", "directory to save the population and the best genome to")
+	resume := flags.Bool("resume", false, "go on from the population saved in the output directory")
+	generations := flags.Int("generations", 100, "generations to evolve")
+	flags.IntVar(&options.Population, "population", options.Population, "genomes in every generation")
+	flags.IntVar(&options.Games, "games", options.Games, "games every genome plays per generation")
+	flags.IntVar(&options.Workers, "workers", options.Workers, "games played at the same time")
+	elite := flags.Int("elite", 0, "best genomes kept unchanged in the next generation, a tenth of the population if 0")
+	hidden := flags.String("hidden", "16", "sizes of the hidden layers, separated by commas")
+	flags.Float64Var(&options.MutationRate, "mutation-rate", options.MutationRate, "chance of every weight of a child changing")
+	flags.Float64Var(&options.MutationSize, "mutation-size", options.MutationSize, "standard deviation of a weight's change")
+	flags.Float64Var(&options.Survival, "survival", options.Survival, "fitness every second alive is worth, on top of the score")
+	flags.Uint64Var(&options.Seed, "seed", 1, "seed of the whole run")
+	flags.IntVar(&options.Env.MaxSteps, "max-steps", options.Env.MaxSteps, "steps a game is cut short after")
+	flags.IntVar(&options.Env.FrameSkip, "frame-skip", options.Env.FrameSkip, "ticks every decision is held for")
+	encoding := flags.String("encoding", options.Env.Encoding.String(), "what the networks see: rays or entities")
+	flags.IntVar(&options.Env.Rays, "rays", options.Env.Rays, "how many rays the networks see")
+	flags.IntVar(&options.Env.Entities, "entities", options.Env.Entities, "the most entities the networks see")
+	flags.Parse(args)
+
+	var err error
+	if options.Env.Encoding, err = gym.ParseEncoding(*encoding); err != nil {
+		log.Fatal(err)
+	}
+	if options.Hidden, err = parseSizes(*hidden); err != nil {
+		log.Fatal(err)
+	}
+	options.Elite = *elite
+	if options.Elite == 0 {
+		options.Elite = max(1, options.Population/10)
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	populationPath := filepath.Join(*out, "population.json")
+	bestPath := filepath.Join(*out, "best.json")
+
+	population := neuro.NewPopulation(options)
+	if *resume {
+		if population, err = neuro.LoadPopulation(populationPath, options); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("train: going on from generation %d", population.Generation)
+	}
+	best := math.Inf(-1)
+	if population.Best != nil {
+		best = population.Best.Fitness
+	}
+	for range *generations {
+		start := time.Now()
+		if err := population.Evaluate(options); err != nil {
+			log.Fatal(err)
+		}
+		mean := 0.0
+		for _, g := range population.Genomes {
+			mean += g.Fitness
+		}
+		mean /= float64(len(population.Genomes))
+		log.Printf("train: generation %d: best %.0f, mean %.0f, in %v", population.Generation, population.Genomes[0].Fitness, mean, time.Since(start).Round(time.Millisecond))
+		if population.Best.Fitness > best {
+			best = population.Best.Fitness
+			if err := population.Best.Save(bestPath); err != nil {
+				log.Fatal(err)
+			}
+			log.Printf("train: saved the best genome so far to %s", bestPath)
+		}
+		population = population.Next(options)
+		if err := population.Save(populationPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// runSimulate plays games with bots flying every ship, for every variant
+// of the rules the flags list, and reports how they went.
+func runSimulate(args []string) {
+	options := balance.DefaultOptions()
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	flags.IntVar(&options.Games, "games", options.Games, "games every variant plays")
+	flags.IntVar(&options.Workers, "workers", options.Workers, "games played at the same time")
+	flags.Uint64Var(&options.Seed, "seed", 1, "seed the seeds of the games come from")
+	minutes := flags.Float64("max-minutes", float64(options.MaxTicks)/(60*sim.TICK_RATE), "minutes of play a game is cut short after")
+	modes := flags.String("modes", "coop", "games to play, separated by commas: coop, turns or versus")
+	players := flags.String("players", "1", "how many players the games have, separated by commas")
+	difficulties := flags.String("difficulties", "normal", "how well the bots fly, separated by commas: easy, normal or hard")
+	friendlyFire := flags.Bool("friendly-fire", false, "let the shots of the ships in a co-op game destroy each other")
+	rounds := flags.Int("rounds", 3, "rounds in a duel")
+	csvPath := flags.String("csv", "", "write the reports as CSV to this file")
+	jsonPath := flags.String("json", "", "write the reports and every game as JSON to this file")
+	flags.Parse(args)
+
+	options.MaxTicks = int(*minutes * 60 * sim.TICK_RATE)
+	var err error
+	if options.Variants, err = parseVariants(*modes, *players, *difficulties); err != nil {
+		log.Fatal(err)
+	}
+	for i := range options.Variants {
+		options.Variants[i].Rules.FriendlyFire = *friendlyFire
+		options.Variants[i].Rules.DuelRounds = *rounds
+	}
+
+	start := time.Now()
+	games, err := balance.Run(options)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("simulate: played %d games in %v", len(games), time.Since(start).Round(time.Millisecond))
+	reports := balance.Summarize(games)
+	balance.WriteText(os.Stdout, reports)
+	if *csvPath != "" {
+		if err := writeFile(*csvPath, func(f *os.File) error { return balance.WriteCSV(f, reports) }); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *jsonPath != "" {
+		if err := writeFile(*jsonPath, func(f *os.File) error { return balance.WriteJSON(f, reports, games) }); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// runSweep plays the variants the flags list with every configuration of
+// the constants in the ranges, and ranks the configurations by how close
+// they get to the target difficulty curve.
+func runSweep(args []string) {
+	options := balance.DefaultSweepOptions()
+	flags := flag.NewFlagSet("sweep", flag.ExitOnError)
+	ranges := flags.String("ranges", "", "constants to try and their values, like turn_speed=0.04:0.1:4,asteroid_speed=0.5:2")
+	flags.IntVar(&options.Random, "random", 0, "try this many configurations picked at random instead of the whole grid")
+	target := flags.String("target", "", "share of games that should get to every wave, from the first, like 1,0.8,0.5")
+	top := flags.Int("top", 10, "how many of the best configurations to print")
+	flags.IntVar(&options.Games, "games", options.Games, "games every variant plays per configuration")
+	flags.IntVar(&options.Workers, "workers", options.Workers, "games played at the same time")
+	flags.Uint64Var(&options.Seed, "seed", 1, "seed the seeds of the games and the random configurations come from")
+	minutes := flags.Float64("max-minutes", float64(options.MaxTicks)/(60*sim.TICK_RATE), "minutes of play a game is cut short after")
+	modes := flags.String("modes", "coop", "games to play, separated by commas: coop, turns or versus")
+	players := flags.String("players", "1", "how many players the games have, separated by commas")
+	difficulties := flags.String("difficulties", "normal", "how well the bots fly, separated by commas: easy, normal or hard")
+	csvPath := flags.String("csv", "", "write every configuration as CSV to this file, the best first")
+	jsonPath := flags.String("json", "", "write every configuration and its reports as JSON to this file, the best first")
+	flags.Parse(args)
+
+	options.MaxTicks = int(*minutes * 60 * sim.TICK_RATE)
+	var err error
+	if options.Ranges, err = balance.ParseRanges(*ranges); err != nil {
+		log.Fatal(err)
+	}
+	if *target != "" {
+		if options.Target, err = balance.ParseCurve(*target); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if options.Variants, err = parseVariants(*modes, *players, *difficulties); err != nil {
+		log.Fatal(err)
+	}
+
+	count := len(options.Configurations())
+	trials, err := balance.Sweep(options, func(i int, t balance.Trial) {
+		log.Printf("sweep: %d/%d: %s: %.3f from the target", i+1, count, balance.Describe(options.Ranges, t), t.Distance)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i, t := range trials[:min(*top, len(trials))] {
+		fmt.Printf("%d. %.3f from the target: %s\n", i+1, t.Distance, balance.Describe(options.Ranges, t))
+	}
+	if *csvPath != "" {
+		if err := writeFile(*csvPath, func(f *os.File) error { return balance.WriteSweepCSV(f, options.Ranges, options.Target, trials) }); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *jsonPath != "" {
+		if err := writeFile(*jsonPath, func(f *os.File) error { return balance.WriteSweepJSON(f, options.Target, trials) }); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// runScreenshot plays a game with bots flying every ship and draws frames
+// of it into PNG files, with no window.
+func runScreenshot(args []string) {
+	flags := flag.NewFlagSet("screenshot", flag.ExitOnError)
+	mode := flags.String("mode", "coop", "the game to play: coop, turns or versus")
+	players := flags.Int("players", 1, "how many ships there are")
+	difficulty := flags.String("difficulty", "normal", "how well the bots fly: easy, normal or hard")
+	seed := flags.Uint64("seed", 1, "seed of the game")
+	tick := flags.Int("tick", 10*sim.TICK_RATE, "tick of the frame to draw")
+	every := flags.Int("every", 0, "also draw a frame every this many ticks until then")
+	out := flags.String("out", "screenshot.png", "file to write the frame to; with -every, every frame's tick is added to the name")
+	flags.Parse(args)
+
+	variants, err := parseVariants(*mode, strconv.Itoa(*players), *difficulty)
+	if err != nil {
+		log.Fatal(err)
+	}
+	v := variants[0]
+	w := sim.NewWorld(*seed, v.Rules)
+	pilots := []*bot.Pilot{}
+	for i := range w.Players {
+		p := bot.New(i, v.Difficulty, *seed+uint64(i)+1)
+		p.Restart = true
+		pilots = append(pilots, p)
+	}
+	save := func(path string) {
+		canvas := render.NewCanvas()
+		render.Frame(canvas, w)
+		if err := canvas.SavePNG(path); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	inputs := make([]sim.Input, len(pilots))
+	for w.Tick < *tick {
+		for i, p := range pilots {
+			inputs[i] = p.Input(w)
+		}
+		w.Step(inputs)
+		if *every > 0 && w.Tick%*every == 0 && w.Tick < *tick {
+			save(fmt.Sprintf("%s_%06d.png", strings.TrimSuffix(*out, ".png"), w.Tick))
+		}
+	}
+	if *every > 0 {
+		save(fmt.Sprintf("%s_%06d.png", strings.TrimSuffix(*out, ".png"), w.Tick))
+	} else {
+		save(*out)
+	}
+}
+
+// parseVariants makes a variant for every mode, number of players and
+// difficulty in the lists. Duels are always for two.
+func parseVariants(modes, players, difficulties string) ([]balance.Variant, error) {
+	counts, err := parseSizes(players)
+	if err != nil {
+		return nil, err
+	}
+	variants := []balance.Variant{}
+	for _, modeName := range strings.Split(modes, ",") {
+		modeName = strings.TrimSpace(modeName)
+		mode := sim.ModeTurns
+		if modeName != "turns" {
+			if mode, err = parseMode(modeName); err != nil {
+				return nil, err
+			}
+		}
+		for _, count := range counts {
+			if mode == sim.ModeDuel {
+				count = 2
+			}
+			for _, difficultyName := range strings.Split(difficulties, ",") {
+				difficulty, err := bot.ParseDifficulty(strings.TrimSpace(difficultyName))
+				if err != nil {
+					return nil, err
+				}
+				name := fmt.Sprintf("%s-%d-%s", modeName, count, difficulty)
+				if !slices.ContainsFunc(variants, func(v balance.Variant) bool { return v.Name == name }) {
+					variants = append(variants, balance.Variant{
+						Name:       name,
+						Rules:      sim.Rules{Mode: mode, Players: count},
+						Difficulty: difficulty,
+					})
+				}
+			}
+		}
+	}
+	return variants, nil
+}
+
+// writeFile creates the file and writes it with write.
+func writeFile(path string, write func(f *os.File) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// parseSizes reads lists of sizes like 16,8.
+func parseSizes(list string) ([]int, error) {
+	sizes := []int{}
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		size, err := strconv.Atoi(field)
+		if err != nil || size < 1 {
+			return nil, fmt.Errorf("bad size %q", field)
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
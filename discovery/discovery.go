@@ -0,0 +1,242 @@
+// Package discovery finds the games hosted on the local network, so nobody
+// has to type an address to join one. A host announces its game every
+// ANNOUNCE_INTERVAL over UDP, to a multicast group and as a broadcast, and a
+// browser listening on the discovery port keeps a list of the games it heard
+// of lately.
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rodolfato/asteroids/sim"
+	"github.com/rodolfato/asteroids/wire"
+)
+
+const (
+	DefaultGroup     = "239.255.77.77:7779"
+	DefaultBroadcast = "255.255.255.255:7779"
+	// ANNOUNCE_INTERVAL is how often a host announces its game, and a game
+	// not heard of for EXPIRY is taken off the list.
+	ANNOUNCE_INTERVAL = time.Second
+	EXPIRY            = 3 * time.Second
+	MAX_PACKET_SIZE   = 512
+)
+
+const magic = "ASTL"
+
+// msgAnnounce is the only message there is, but it's numbered like the
+// messages of the other network packages.
+const msgAnnounce = 1
+
+// Game is a game waiting for players somewhere on the network. Version is
+// the netplay version the host plays, which has to be ours to join it.
+type Game struct {
+	Name    string
+	Mode    sim.Mode
+	Joined  int
+	Players int
+	Version uint32
+	// Addr is where to join the game. The host only announces its port, the
+	// address comes from where the announcement came from.
+	Addr string
+	port int
+	seen time.Time
+}
+
+func encodeGame(g Game) []byte {
+	w := wire.NewWriter(msgAnnounce)
+	w.Bytes([]byte(magic))
+	w.U32(g.Version)
+	w.String(g.Name)
+	w.U8(int(g.Mode))
+	w.U8(g.Joined)
+	w.U8(g.Players)
+	w.U16(g.port)
+	return w.Data
+}
+
+func decodeGame(data []byte) (Game, error) {
+	r := wire.NewReader(data)
+	if r.U8() != msgAnnounce || string(r.Bytes(len(magic))) != magic {
+		return Game{}, errors.New("not an asteroids announcement")
+	}
+	g := Game{
+		Version: r.U32(),
+		Name:    r.String(),
+		Mode:    sim.Mode(r.U8()),
+		Joined:  r.U8(),
+		Players: r.U8(),
+		port:    r.U16(),
+	}
+	return g, r.Err
+}
+
+// Announcer keeps announcing a game until it's closed. The game can be
+// updated as players join.
+type Announcer struct {
+	conn    net.PacketConn
+	targets []net.Addr
+	mu      sync.Mutex
+	game    Game
+	done    chan struct{}
+}
+
+// Announce starts announcing the game, hosted on port, to the targets. With
+// no targets it goes to DefaultGroup and DefaultBroadcast.
+func Announce(game Game, port int, targets ...string) (*Announcer, error) {
+	if len(targets) == 0 {
+		targets = []string{DefaultGroup, DefaultBroadcast}
+	}
+	a := &Announcer{done: make(chan struct{})}
+	for _, target := range targets {
+		addr, err := net.ResolveUDPAddr("udp4", target)
+		if err != nil {
+			return nil, err
+		}
+		a.targets = append(a.targets, addr)
+	}
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	a.conn = conn
+	game.port = port
+	a.game = game
+	go a.run()
+	return a, nil
+}
+
+func (a *Announcer) run() {
+	ticker := time.NewTicker(ANNOUNCE_INTERVAL)
+	defer ticker.Stop()
+	for {
+		a.announce()
+		select {
+		case <-ticker.C:
+		case <-a.done:
+			return
+		}
+	}
+}
+
+// announce sends the game to every target. A target that can't be reached,
+// like a multicast group on a machine without a multicast route, is skipped.
+func (a *Announcer) announce() {
+	a.mu.Lock()
+	data := encodeGame(a.game)
+	a.mu.Unlock()
+	for _, target := range a.targets {
+		a.conn.WriteTo(data, target)
+	}
+}
+
+// Update changes how many players joined the announced game.
+func (a *Announcer) Update(joined int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.game.Joined = joined
+}
+
+func (a *Announcer) Close() error {
+	close(a.done)
+	return a.conn.Close()
+}
+
+// Browser listens for announcements and keeps the games it heard of.
+type Browser struct {
+	conn  net.PacketConn
+	mu    sync.Mutex
+	games map[string]Game
+}
+
+// Browse listens for announcements on addr. A multicast addr joins the group,
+// which also gets the broadcasts to its port, and with no addr it's
+// DefaultGroup. If the group can't be joined it listens for broadcasts only.
+func Browse(addr string) (*Browser, error) {
+	if addr == "" {
+		addr = DefaultGroup
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp4", addr)
+	if err != nil {
+		return nil, err
+	}
+	var conn net.PacketConn
+	if udpAddr.IP.IsMulticast() {
+		conn, err = net.ListenMulticastUDP("udp4", nil, udpAddr)
+		if err != nil {
+			log.Println("discovery: can't join the multicast group, listening for broadcasts:", err)
+			conn, err = net.ListenPacket("udp4", fmt.Sprintf(":%d", udpAddr.Port))
+		}
+	} else {
+		conn, err = net.ListenPacket("udp4", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b := &Browser{conn: conn, games: map[string]Game{}}
+	go b.read()
+	return b, nil
+}
+
+func (b *Browser) read() {
+	buf := make([]byte, MAX_PACKET_SIZE)
+	for {
+		n, from, err := b.conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Println("discovery:", err)
+			}
+			return
+		}
+		g, err := decodeGame(buf[:n])
+		if err != nil {
+			continue
+		}
+		host, _, err := net.SplitHostPort(from.String())
+		if err != nil {
+			continue
+		}
+		g.Addr = net.JoinHostPort(host, fmt.Sprint(g.port))
+		g.seen = time.Now()
+		b.mu.Lock()
+		b.games[g.Addr] = g
+		b.mu.Unlock()
+	}
+}
+
+// Addr is the local address the browser listens on.
+func (b *Browser) Addr() net.Addr {
+	return b.conn.LocalAddr()
+}
+
+// Games returns the games heard of in the last EXPIRY, sorted by name.
+func (b *Browser) Games() []Game {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	games := []Game{}
+	for addr, g := range b.games {
+		if time.Since(g.seen) > EXPIRY {
+			delete(b.games, addr)
+			continue
+		}
+		games = append(games, g)
+	}
+	slices.SortFunc(games, func(a, b Game) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Addr, b.Addr)
+	})
+	return games
+}
+
+func (b *Browser) Close() error {
+	return b.conn.Close()
+}
@@ -0,0 +1,83 @@
+package discovery
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// waitFor polls the browser until it has a game that passes check.
+func waitFor(t *testing.T, b *Browser, check func(g Game) bool) Game {
+	t.Helper()
+	deadline := time.Now().Add(3 * ANNOUNCE_INTERVAL)
+	for time.Now().Before(deadline) {
+		for _, g := range b.Games() {
+			if check(g) {
+				return g
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("the browser didn't hear of the game, it has %v", b.Games())
+	return Game{}
+}
+
+func TestAnnounceAndBrowse(t *testing.T) {
+	b, err := Browse("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	a, err := Announce(Game{Name: "loopback", Mode: sim.ModeDuel, Joined: 1, Players: 2, Version: 7}, 4321, b.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	g := waitFor(t, b, func(g Game) bool { return g.Name == "loopback" })
+	want := Game{Name: "loopback", Mode: sim.ModeDuel, Joined: 1, Players: 2, Version: 7, Addr: "127.0.0.1:4321"}
+	if g.Name != want.Name || g.Mode != want.Mode || g.Joined != want.Joined || g.Players != want.Players || g.Version != want.Version || g.Addr != want.Addr {
+		t.Errorf("the browser heard of %+v, want %+v", g, want)
+	}
+
+	a.Update(2)
+	waitFor(t, b, func(g Game) bool { return g.Name == "loopback" && g.Joined == 2 })
+	if games := b.Games(); len(games) != 1 {
+		t.Errorf("the browser has %d games, want the one announced again", len(games))
+	}
+}
+
+func TestGamesExpire(t *testing.T) {
+	b, err := Browse("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	now := time.Now()
+	b.mu.Lock()
+	for i, seen := range []time.Time{now, now.Add(-EXPIRY / 2), now.Add(-EXPIRY - time.Second)} {
+		addr := fmt.Sprintf("127.0.0.1:%d", 5000+i)
+		b.games[addr] = Game{Name: fmt.Sprint(i), Addr: addr, seen: seen}
+	}
+	b.mu.Unlock()
+
+	games := b.Games()
+	if len(games) != 2 || games[0].Name != "0" || games[1].Name != "1" {
+		t.Errorf("Games() = %+v, want the two heard of in the last %v", games, EXPIRY)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.games["127.0.0.1:5002"]; ok {
+		t.Error("the expired game is still kept")
+	}
+}
+
+func TestDecodeIgnoresOtherPackets(t *testing.T) {
+	for _, data := range [][]byte{nil, {msgAnnounce}, []byte("hello there"), {2, 'A', 'S', 'T', 'L'}} {
+		if _, err := decodeGame(data); err == nil {
+			t.Errorf("decodeGame(%q) took it for an announcement", data)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/rodolfato/asteroids/render"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+func (g *GameState) drawRoundScreen() {
+	w := g.world
+	title := fmt.Sprintf("Round %d", w.Round)
+	if winner := w.MatchWinner(); winner != nil {
+		title = fmt.Sprintf("Player %d wins", slices.Index(w.Players, winner)+1)
+	}
+	score := fmt.Sprintf("%d - %d", w.Players[0].Rounds, w.Players[1].Rounds)
+	next := "Press Enter for the next round"
+	if g.scene == SCENE_GAME_OVER {
+		next = "Press Enter to play again"
+	}
+	for i, line := range []string{title, score, next} {
+		size := float32(80 - 25*i)
+		render.TextCentered(screen, line, sim.NewVector2(SCREEN_SIZE_X/2, SCREEN_SIZE_Y/2-100+100*float32(i)), size, 1, render.WHITE)
+	}
+}
@@ -0,0 +1,229 @@
+// Package gym wraps the simulation in the interface reinforcement learning
+// environments usually have: Reset starts an episode from a seed, and Step
+// plays an action for a few ticks and returns what the agent sees, the
+// reward and whether the episode is over. It's the same game the window
+// shows, with nothing drawn, so agents can be trained on it as fast as the
+// machine goes.
+//
+// The agent flies one ship. In games with more than one player the other
+// ships are flown by bot pilots.
+package gym
+
+import (
+	"fmt"
+
+	"github.com/rodolfato/asteroids/bot"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+const (
+	DefaultFrameSkip    = 4
+	DefaultDeathPenalty = 500
+	DefaultRoundReward  = 1000
+)
+
+// Action is the controls held down during a step, the bits of sim.Input
+// without start: any number from 0 to NUM_ACTIONS-1 is a valid one. Fire is
+// pressed rather than held, like the fire key: it shoots once, on the first
+// tick of the step.
+type Action int
+
+const NUM_ACTIONS = int(sim.InputFire) << 1
+
+func (a Action) Input() sim.Input {
+	return sim.Input(a) & (sim.InputLeft | sim.InputRight | sim.InputThrust | sim.InputReverse | sim.InputFire)
+}
+
+// Config is everything about the environment that stays the same from one
+// episode to the next.
+type Config struct {
+	// Rules are the game played. Players is at least 1.
+	Rules sim.Rules
+	// Player is the ship the agent flies.
+	Player int
+	// FrameSkip is how many ticks every action is held for.
+	FrameSkip int
+	// Encoding is how observations describe the world, with Rays rays or up
+	// to Entities entities.
+	Encoding Encoding
+	Rays     int
+	Entities int
+	// MaxSteps cuts episodes short after that many steps. 0 lets them go on
+	// until the game is over.
+	MaxSteps int
+	// DeathPenalty is taken from the reward every time the ship is
+	// destroyed, and RoundReward given for every round won in a duel. The
+	// rest of the reward is the score.
+	DeathPenalty float32
+	RoundReward  float32
+	// Opponents is how well the bots flying the other ships play.
+	Opponents bot.Difficulty
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Rules:        sim.Rules{Mode: sim.ModeCoop, Players: 1},
+		FrameSkip:    DefaultFrameSkip,
+		Encoding:     Rays,
+		Rays:         DefaultRays,
+		Entities:     DefaultEntities,
+		DeathPenalty: DefaultDeathPenalty,
+		RoundReward:  DefaultRoundReward,
+		Opponents:    bot.Normal,
+	}
+}
+
+func (c Config) check() error {
+	switch {
+	case c.Rules.Players < 1 || c.Rules.Players > sim.MAX_SHIPS:
+		return fmt.Errorf("a game is for 1 to %d players, not %d", sim.MAX_SHIPS, c.Rules.Players)
+	case c.Rules.Mode == sim.ModeDuel && c.Rules.Players != 2:
+		return fmt.Errorf("a duel is for 2 players, not %d", c.Rules.Players)
+	case c.Player < 0 || c.Player >= c.Rules.Players:
+		return fmt.Errorf("there's no player %d in a game for %d", c.Player, c.Rules.Players)
+	case c.FrameSkip < 1:
+		return fmt.Errorf("the frame skip is at least 1, not %d", c.FrameSkip)
+	case c.Encoding == Rays && c.Rays < 1:
+		return fmt.Errorf("observations need at least 1 ray, not %d", c.Rays)
+	case c.Encoding == Entities && c.Entities < 1:
+		return fmt.Errorf("observations need at least 1 entity, not %d", c.Entities)
+	case c.Encoding != Rays && c.Encoding != Entities:
+		return fmt.Errorf("unknown encoding %v", c.Encoding)
+	}
+	return nil
+}
+
+// Info is what happened during a step, beyond the reward.
+type Info struct {
+	Tick   int
+	Score  int
+	Lives  int
+	Wave   int
+	Rounds int
+	// Kills counts the asteroids the ship broke during the step, by class.
+	Kills []int
+	// Deaths is how many times the ship was destroyed during the step.
+	Deaths int
+	// Truncated is set when the episode ended because of MaxSteps rather
+	// than a game over.
+	Truncated bool
+}
+
+// Env is one environment. It isn't safe to use from several goroutines at
+// once, but any number of them can run side by side.
+type Env struct {
+	config Config
+	world  *sim.World
+	others []*bot.Pilot
+	steps  int
+	done   bool
+}
+
+func New(config Config) (*Env, error) {
+	if err := config.check(); err != nil {
+		return nil, err
+	}
+	return &Env{config: config}, nil
+}
+
+func (e *Env) Config() Config {
+	return e.config
+}
+
+// World is the game being played, to look at and not to change.
+func (e *Env) World() *sim.World {
+	return e.world
+}
+
+// Reset starts a new episode. Everything random in it, the bots included,
+// comes from the seed, so an episode can be played again.
+func (e *Env) Reset(seed uint64) Observation {
+	e.world = sim.NewWorld(seed, e.config.Rules)
+	e.others = nil
+	for i := range e.config.Rules.Players {
+		if i != e.config.Player {
+			e.others = append(e.others, bot.New(i, e.config.Opponents, seed+uint64(i)+1))
+		}
+	}
+	e.steps = 0
+	e.done = false
+	return e.observe()
+}
+
+// Step holds the action down for FrameSkip ticks, or until the episode is
+// over, firing on the first one only. Stepping an episode that's over, or
+// one that was never reset, does nothing and returns done.
+func (e *Env) Step(action Action) (Observation, float32, bool, Info) {
+	info := Info{Kills: make([]int, len(sim.SPLIT_RULES))}
+	if e.world == nil || e.done {
+		return e.observe(), 0, true, e.fill(info)
+	}
+	player := e.world.Players[e.config.Player]
+	score, rounds := player.Score, player.Rounds
+	inputs := make([]sim.Input, e.config.Rules.Players)
+	for tick := range e.config.FrameSkip {
+		for _, other := range e.others {
+			inputs[other.Player] = other.Input(e.world)
+		}
+		inputs[e.config.Player] = action.Input()
+		if tick > 0 {
+			//Como la tecla de disparo, mantenerlo apretado dispara una sola vez
+			inputs[e.config.Player] &^= sim.InputFire
+		}
+		if e.world.Phase == sim.PhaseRoundOver {
+			//Entre rondas de un duelo se sigue sin esperar
+			inputs[e.config.Player] |= sim.InputStart
+		}
+		e.world.Step(inputs)
+		for _, event := range e.world.Events {
+			if event.Player != e.config.Player {
+				continue
+			}
+			switch event.Kind {
+			case sim.EventAsteroidHit:
+				info.Kills[event.Class] += 1
+			case sim.EventShipDestroyed:
+				info.Deaths += 1
+			}
+		}
+		if e.over() {
+			e.done = true
+			break
+		}
+	}
+	e.steps += 1
+	if !e.done && e.config.MaxSteps > 0 && e.steps >= e.config.MaxSteps {
+		e.done = true
+		info.Truncated = true
+	}
+	reward := float32(player.Score-score) +
+		float32(player.Rounds-rounds)*e.config.RoundReward -
+		float32(info.Deaths)*e.config.DeathPenalty
+	return e.observe(), reward, e.done, e.fill(info)
+}
+
+// over is true once the game is over, or the agent's ship is out of lives
+// while the others play on.
+func (e *Env) over() bool {
+	if e.world.Phase == sim.PhaseGameOver {
+		return true
+	}
+	return e.world.Rules.Mode == sim.ModeCoop && e.world.Players[e.config.Player].Lives < 1
+}
+
+func (e *Env) fill(info Info) Info {
+	if e.world == nil {
+		return info
+	}
+	player := e.world.Players[e.config.Player]
+	info.Tick = e.world.Tick
+	info.Score = player.Score
+	info.Lives = player.Lives
+	info.Wave = e.world.Wave
+	info.Rounds = player.Rounds
+	return info
+}
+
+func (e *Env) observe() Observation {
+	return e.config.Observe(e.world)
+}
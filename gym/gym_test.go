@@ -0,0 +1,61 @@
+package gym
+
+import (
+	"testing"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+func TestFireShootsOncePerStep(t *testing.T) {
+	env, err := New(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Reset(1)
+	fire := Action(sim.InputFire)
+	for step := 1; step <= 5; step++ {
+		tick := env.World().Tick
+		env.Step(fire)
+		shots := 0
+		for _, s := range env.World().Ships {
+			shots += len(s.Projectiles)
+		}
+		if shots > step {
+			t.Fatalf("after %d steps firing there are %d shots, want at most one a step", step, shots)
+		}
+		if env.World().Tick != tick+DefaultFrameSkip {
+			t.Fatalf("a step played %d ticks, want %d", env.World().Tick-tick, DefaultFrameSkip)
+		}
+	}
+}
+
+func TestResetPlaysTheSameEpisode(t *testing.T) {
+	config := DefaultConfig()
+	config.Rules = sim.Rules{Mode: sim.ModeCoop, Players: 2}
+	env, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	play := func() ([]float32, uint32) {
+		env.Reset(3)
+		rewards := []float32{}
+		for i := range 200 {
+			_, reward, done, _ := env.Step(Action(i % NUM_ACTIONS))
+			rewards = append(rewards, reward)
+			if done {
+				break
+			}
+		}
+		return rewards, env.World().Checksum()
+	}
+	a, sumA := play()
+	b, sumB := play()
+	if sumA != sumB || len(a) != len(b) {
+		t.Fatalf("the same seed played two episodes, of %d and %d steps", len(a), len(b))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("the rewards of step %d are %v and %v", i, a[i], b[i])
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package gym
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/rodolfato/asteroids/sensor"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+const (
+	DefaultRays     = sensor.DefaultRays
+	DefaultEntities = 16
+	// RAY_RANGE is how far rays see.
+	RAY_RANGE = sensor.DefaultRange
+	// MAX_RELATIVE_SPEED scales velocities: two ships flying at full speed
+	// against each other, moving twice their velocity every tick.
+	MAX_RELATIVE_SPEED = 4 * sim.MAX_SPEED
+)
+
+// Encoding is how an observation describes what's around the ship.
+type Encoding int
+
+const (
+	// Rays are distance sensors spread evenly around the ship, starting
+	// straight ahead, read with the sensor package.
+	Rays Encoding = iota
+	// Entities are the nearest things to the ship, nearest first.
+	Entities
+)
+
+var ENCODING_NAMES = []string{"rays", "entities"}
+
+func (e Encoding) String() string {
+	if e < 0 || int(e) >= len(ENCODING_NAMES) {
+		return fmt.Sprintf("Encoding(%d)", int(e))
+	}
+	return ENCODING_NAMES[e]
+}
+
+func ParseEncoding(name string) (Encoding, error) {
+	for i, n := range ENCODING_NAMES {
+		if n == name {
+			return Encoding(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown encoding %q, it can be rays or entities", name)
+}
+
+// Entity is something near the ship. Pos and Vel are relative to the ship,
+// in its frame.
+type Entity struct {
+	Kind   sensor.Kind
+	Pos    sim.Vector2
+	Vel    sim.Vector2
+	Radius float32
+	Class  int
+}
+
+// Observation is what the agent sees after a step. Everything is in the
+// frame of its ship: X points where the nose does and Y to its right, so
+// the same situation looks the same whichever way the ship is facing.
+type Observation struct {
+	// Alive is false while the ship is in pieces, or out of the game. The
+	// rest of the observation is empty then.
+	Alive bool
+	// Vel is the ship's velocity.
+	Vel sim.Vector2
+	// Heading is where the ship points on the screen, in radians.
+	Heading  float32
+	Rays     []sensor.Reading
+	Entities []Entity
+}
+
+// Observe describes the world as the ship of c.Player sees it, the way the
+// environment does after every step. Pilots trained on the environment use
+// it to fly in any game.
+func (c Config) Observe(w *sim.World) Observation {
+	o := Observation{}
+	var ship *sim.PlayerShip
+	if w != nil {
+		for _, s := range w.Ships {
+			if s.Player == c.Player {
+				ship = s
+			}
+		}
+	}
+	if c.Encoding == Rays {
+		o.Rays = sensor.Even(c.Rays, RAY_RANGE).Read(w, ship)
+	}
+	if ship == nil || ship.Collision {
+		return o
+	}
+	o.Alive = true
+	o.Heading = ship.Orientation
+	o.Vel = sim.Vector2Rotate(ship.Vel, -ship.Orientation)
+
+	if c.Encoding == Entities {
+		near := entities(w, ship)
+		slices.SortFunc(near, func(a, b Entity) int {
+			return cmp.Compare(sim.Vector2Length(a.Pos)-a.Radius, sim.Vector2Length(b.Pos)-b.Radius)
+		})
+		o.Entities = near[:min(len(near), c.Entities)]
+	}
+	return o
+}
+
+// entities lists everything the ship can run into, the short way around the
+// edges and in the frame of the ship. Velocities are how far things move in
+// a tick, which for ships is twice their velocity: once when steered and
+// once more with the rest.
+func entities(w *sim.World, ship *sim.PlayerShip) []Entity {
+	entities := []Entity{}
+	shipVel := sim.Vector2Scale(ship.Vel, 2)
+	add := func(kind sensor.Kind, pos, vel sim.Vector2, radius float32, class int) {
+		entities = append(entities, Entity{
+			Kind:   kind,
+			Pos:    sim.Vector2Rotate(sim.WrappedDelta(ship.Pos, pos), -ship.Orientation),
+			Vel:    sim.Vector2Rotate(sim.Vector2Subtract(vel, shipVel), -ship.Orientation),
+			Radius: radius,
+			Class:  class,
+		})
+	}
+	for _, a := range w.Asteroids {
+		add(sensor.KindAsteroid, a.Pos, a.Vel, a.Radius(), a.Class)
+	}
+	duel := w.Rules.Mode == sim.ModeDuel
+	for _, other := range w.Ships {
+		if other == ship {
+			continue
+		}
+		if !other.Collision {
+			add(sensor.KindShip, other.Pos, sim.Vector2Scale(other.Vel, 2), other.Size, 0)
+		}
+		if duel || w.Rules.FriendlyFire {
+			for _, p := range other.Projectiles {
+				add(sensor.KindShot, p.Pos, p.Vel, p.Size, 0)
+			}
+		}
+	}
+	if duel {
+		add(sensor.KindStar, sim.STAR_POS, sim.Vector2Zero(), sim.STAR_RADIUS, 0)
+	}
+	return entities
+}
+
+// Size is how many numbers Vector makes out of an observation with this
+// config.
+func (c Config) Size() int {
+	if c.Encoding == Entities {
+		return SHIP_VALUES + c.Entities*ENTITY_VALUES
+	}
+	return SHIP_VALUES + c.Rays*RAY_VALUES
+}
+
+const (
+	// SHIP_VALUES are alive, the velocity, and the sine and cosine of the
+	// heading.
+	SHIP_VALUES = 5
+	// RAY_VALUES are the distance and a one-hot kind, none left out.
+	RAY_VALUES = 1 + int(sensor.NUM_KINDS) - 1
+	// ENTITY_VALUES are present, the position, the velocity, the radius, the
+	// class and a one-hot kind, none left out.
+	ENTITY_VALUES = 7 + int(sensor.NUM_KINDS) - 1
+)
+
+// Vector flattens the observation into numbers mostly between -1 and 1, for
+// a network to take in. Missing rays and entities are all zeros, except for
+// the distance of a ray, which is 1 when it sees nothing.
+func (o Observation) Vector(c Config) []float32 {
+	v := make([]float32, 0, c.Size())
+	heading := sim.GetDirection(o.Heading)
+	v = append(v, bit(o.Alive), o.Vel.X/sim.MAX_SPEED, o.Vel.Y/sim.MAX_SPEED, heading.Y, heading.X)
+	if c.Encoding == Entities {
+		for i := range c.Entities {
+			if i >= len(o.Entities) {
+				v = append(v, make([]float32, ENTITY_VALUES)...)
+				continue
+			}
+			entity := o.Entities[i]
+			v = append(v,
+				1,
+				entity.Pos.X/(sim.SCREEN_SIZE_X/2),
+				entity.Pos.Y/(sim.SCREEN_SIZE_Y/2),
+				entity.Vel.X/MAX_RELATIVE_SPEED,
+				entity.Vel.Y/MAX_RELATIVE_SPEED,
+				entity.Radius/sim.ASTEROID_SIZE,
+				float32(entity.Class)/float32(len(sim.SPLIT_RULES)),
+			)
+			v = appendKind(v, entity.Kind)
+		}
+		return v
+	}
+	for i := range c.Rays {
+		if i >= len(o.Rays) {
+			v = append(v, 1)
+			v = appendKind(v, sensor.KindNone)
+			continue
+		}
+		v = append(v, o.Rays[i].Distance/RAY_RANGE)
+		v = appendKind(v, o.Rays[i].Kind)
+	}
+	return v
+}
+
+func appendKind(v []float32, kind sensor.Kind) []float32 {
+	for k := sensor.KindNone + 1; k < sensor.NUM_KINDS; k++ {
+		v = append(v, bit(kind == k))
+	}
+	return v
+}
+
+func bit(b bool) float32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,50 @@
+package main
+
+import "github.com/rodolfato/asteroids/sim"
+
+const (
+	HEARTBEAT_SLOWEST = 60
+	HEARTBEAT_FASTEST = 14
+	HEARTBEAT_SPEEDUP = 0.5
+)
+
+// Heartbeat plays the two alternating low tones under the game. It counts
+// frames until the next beat, and the wait gets shorter as the wave goes on.
+type Heartbeat struct {
+	frames int
+	high   bool
+}
+
+// heartbeatInterval returns the frames between beats given the fraction of
+// the wave's rock mass still flying and the seconds since the wave started.
+func heartbeatInterval(remaining float32, elapsed float64) int {
+	remaining = max(0, min(1, remaining))
+	interval := HEARTBEAT_FASTEST + (HEARTBEAT_SLOWEST-HEARTBEAT_FASTEST)*float64(remaining)
+	interval -= elapsed * HEARTBEAT_SPEEDUP
+	return int(max(HEARTBEAT_FASTEST, interval))
+}
+
+func (h *Heartbeat) update(g *GameState) {
+	//Se detiene mientras la nave reaparece, en el game over y en los duelos
+	w := g.world
+	if g.scene != SCENE_PLAYING || w.Respawning() || w.Rules.Mode == sim.ModeDuel {
+		h.frames = 0
+		h.high = false
+		return
+	}
+	if h.frames > 0 {
+		h.frames -= 1
+		return
+	}
+	if h.high {
+		g.audio.play("beat_high")
+	} else {
+		g.audio.play("beat_low")
+	}
+	h.high = !h.high
+	remaining := float32(0)
+	if w.WaveMass > 0 {
+		remaining = w.AsteroidsMass() / w.WaveMass
+	}
+	h.frames = heartbeatInterval(remaining, float64(w.Tick-w.WaveStartTick)/sim.TICK_RATE)
+}
@@ -1,527 +1,275 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
-	"math"
-	"math/rand/v2"
+	"os"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/rodolfato/asteroids/bot"
+	"github.com/rodolfato/asteroids/discovery"
+	"github.com/rodolfato/asteroids/netplay"
+	"github.com/rodolfato/asteroids/neuro"
+	"github.com/rodolfato/asteroids/render"
+	"github.com/rodolfato/asteroids/sensor"
+	"github.com/rodolfato/asteroids/server"
+	"github.com/rodolfato/asteroids/sim"
+	"github.com/rodolfato/asteroids/spectate"
+	"github.com/rodolfato/asteroids/synth"
 )
 
 const (
-	PLAYER_SHIP_SIZE                = 20
-	PLAYER_SHIP_THICKNESS           = 1.5
-	PLAYER_SHIP_INITIAL_ORIENTATION = math.Pi + (math.Pi * 0.5)
-	PLAYER_SHIP_TURN_SPEED          = 0.02 * math.Pi
-	PLAYER_SHIP_SPEED               = 0.3
-	SCREEN_SIZE_X                   = 1024
-	SCREEN_SIZE_Y                   = 768
-	PROJECTILE_SPEED                = PLAYER_SHIP_SPEED + 15
-	TTL_PRJECTILE                   = 45
-	PROJECTILE_SIZE                 = 2.5
-	MAX_SPEED                       = 5
-	MAX_ASTEROIDS                   = 12
-	ASTEROID_SPEED                  = 1
-	ASTEROID_SIZE                   = 50.0
-	ASTEROID_POINTS                 = 11
-	SHIP_TIME_IN_PIECES             = 5
-	LIVES                           = 3
+	PLAYER_SHIP_THICKNESS = 1.5
+	SCREEN_SIZE_X         = sim.SCREEN_SIZE_X
+	SCREEN_SIZE_Y         = sim.SCREEN_SIZE_Y
 )
 
-type GameState struct {
-	playerShip    *PlayerShip
-	asteroids     *[]Asteroid
-	debug         bool
-	collision     bool
-	lives         int
-	gameTime      float64
-	destroyedTime float64
-}
-
-type PlayerShip struct {
-	pos         rl.Vector2
-	orientation float32
-	size        float32
-	speed       float32
-	vel         rl.Vector2
-	projectiles *[]Projectile
-}
-
-type Projectile struct {
-	pos         rl.Vector2
-	speed       float32
-	vel         rl.Vector2
-	ttl         int
-	orientation float32
-	size        float32
-}
-
-type Asteroid struct {
-	pos         rl.Vector2
-	speed       float32
-	vel         rl.Vector2
-	orientation float32
-	size        float32
-	sizes       []float32
-}
-
-func generateAsteroids() *[]Asteroid {
-
-	asteroids := []Asteroid{}
-	positions := make(map[rl.Vector2]bool)
+type Scene int
 
-	for range MAX_ASTEROIDS {
-		//slices.Sort(angles)
-		points := []float32{}
-		for range ASTEROID_POINTS {
-			points = append(points, (rand.Float32()*0.6)+0.6)
-
-		}
+const (
+	SCENE_TITLE Scene = iota
+	SCENE_PLAYING
+	SCENE_GAME_OVER
+	SCENE_OPTIONS
+	SCENE_ROUND_OVER
+	SCENE_LOBBY
+	SCENE_BROWSE
+)
 
-		cdX := rand.Float32() * SCREEN_SIZE_X
-		cdY := rand.Float32() * SCREEN_SIZE_Y
-		orientation := rand.Float32() * (math.Pi * 2)
-		directionX := float32(math.Cos(float64(orientation)))
-		directionY := float32(math.Sin(float64(orientation)))
-		speed := rand.Float32() * ASTEROID_SPEED
-		_, ok := positions[rl.NewVector2(cdX, cdY)]
-		for ok {
-			cdX := rand.Float32() * SCREEN_SIZE_X
-			cdY := rand.Float32() * SCREEN_SIZE_Y
-			_, ok = positions[rl.NewVector2(cdX, cdY)]
-		}
-		positions[rl.NewVector2(cdX, cdY)] = true
-		asteroid := Asteroid{
-			pos:         rl.NewVector2(cdX, cdY),
-			speed:       speed,
-			vel:         rl.Vector2Scale(rl.NewVector2(directionX, directionY), speed),
-			size:        ASTEROID_SIZE,
-			orientation: orientation,
-			sizes:       points,
-		}
-		asteroids = append(asteroids, asteroid)
+// GameState is everything around the simulation: the window, the sound, the
+// menus and the effects that don't change how the game plays.
+type GameState struct {
+	world      *sim.World
+	debug      bool
+	gameTime   float64
+	particles  *ParticleSystem
+	audio      *Audio
+	heartbeat  Heartbeat
+	scene      Scene
+	settings   Settings
+	options    OptionsMenu
+	turnBanner int
+	net        *netplay.Session
+	netOptions netplay.Options
+	announcer  *discovery.Announcer
+	browser    *discovery.Browser
+	remote     *server.Client
+	spectators *spectate.Hub
+	attract    *bot.Pilot
+	autopilot  pilot
+	// autopilotPlayer is the player the autopilot is flying for.
+	autopilotPlayer int
+	// autopilotDifficulty is how well the autopilot flies when F2 turns it on,
+	// unless genome, read from genomePath, flies instead.
+	autopilotDifficulty bot.Difficulty
+	genome              *neuro.Genome
+	genomePath          string
+	message             string
+}
+
+// TITLE_OPTIONS are the keys listed under the title.
+var TITLE_OPTIONS = []string{
+	"Press 2 for two players",
+	"Press C for co-op",
+	"Press V for a versus duel",
+	"Press O for options",
+	"Press L for games on the local network",
+}
+
+func drawTitleScreen() {
+	center := sim.NewVector2(SCREEN_SIZE_X/2, SCREEN_SIZE_Y/2)
+	render.TextCentered(screen, "Asteroids", center, 100, 1, render.WHITE)
+	render.TextCentered(screen, "Press Enter to start", sim.Vector2Add(center, sim.NewVector2(0, 100)), 50, 1, render.WHITE)
+	for i, line := range TITLE_OPTIONS {
+		render.TextCentered(screen, line, sim.Vector2Add(center, sim.NewVector2(0, 160+40*float32(i))), 30, 1, render.GRAY)
 	}
-	return &asteroids
-}
-
-func generateMidAsteroid(pos rl.Vector2, size float32, speedMult float32) Asteroid {
-	points := []float32{}
-	for range ASTEROID_POINTS {
-		points = append(points, (rand.Float32()*0.6)+0.6)
-	}
-	posX := pos.X
-	posY := pos.Y
-	orientation := rand.Float32() * (math.Pi * 2)
-	directionX := float32(math.Cos(float64(orientation)))
-	directionY := float32(math.Sin(float64(orientation)))
-	speed := rand.Float32() * ASTEROID_SPEED * speedMult
-	asteroid := Asteroid{
-		pos:         rl.NewVector2(posX, posY),
-		speed:       speed,
-		vel:         rl.Vector2Scale(rl.NewVector2(directionX, directionY), speed),
-		size:        ASTEROID_SIZE / size,
-		orientation: orientation,
-		sizes:       points,
-	}
-	return asteroid
 }
 
 func drawGameOverScreen() {
-	rl.DrawTextPro(rl.GetFontDefault(), "Game Over", rl.Vector2{
-		X: SCREEN_SIZE_X / 2,
-		Y: SCREEN_SIZE_Y / 2,
-	}, rl.Vector2Scale(rl.MeasureTextEx(rl.GetFontDefault(), "Game Over", 100.0, 1.0), 0.5), 0.0, 100.0, 1.0, rl.White)
-	rl.DrawTextPro(rl.GetFontDefault(), "Press Enter to try again", rl.Vector2{
-		X: SCREEN_SIZE_X / 2,
-		Y: SCREEN_SIZE_Y/2 + 100,
-	}, rl.Vector2Scale(rl.MeasureTextEx(rl.GetFontDefault(), "Press Enter to try again", 50.0, 1.0), 0.5), 0.0, 50.0, 1.0, rl.White)
-
-}
-
-func (s *PlayerShip) shoot() {
-	circleX := s.pos.X + (s.size+10)*float32(math.Cos(float64(s.orientation)))
-	circleY := s.pos.Y + (s.size+10)*float32(math.Sin(float64(s.orientation)))
-	initialPosVector := rl.NewVector2(circleX, circleY)
-
-	//Sentido y orientacion de la nave
-	directionX := float32(math.Cos(float64(s.orientation)))
-	directionY := float32(math.Sin(float64(s.orientation)))
-	//Este vector es el sentido y orientacion de la nave
-	newVector := rl.NewVector2(directionX, directionY)
-	//Con el sentido y orientación de la nave se puede escalar con la rapidez para obtener la velocidad
-	projectileVelocity := rl.Vector2Add(
-		s.vel,
-		rl.Vector2Scale(newVector, PROJECTILE_SPEED+PLAYER_SHIP_SPEED),
-	)
-
-	projectile := Projectile{
-		pos:         initialPosVector,
-		speed:       PROJECTILE_SPEED,
-		vel:         projectileVelocity,
-		ttl:         TTL_PRJECTILE,
-		orientation: s.orientation,
-		size:        PROJECTILE_SIZE,
-	}
-	*s.projectiles = append(*s.projectiles, projectile)
-
-}
-
-func (p *Projectile) drawProjectile() {
-	rl.DrawCircleV(p.pos, PROJECTILE_SIZE, rl.White)
-}
-
-func (s *PlayerShip) drawProjectiles() {
-	for _, p := range *s.projectiles {
-		p.drawProjectile()
-	}
-}
-
-func (s *PlayerShip) moveProjectiles() {
-	for i := range *s.projectiles {
-		(*s.projectiles)[i].pos = rl.Vector2Add((*s.projectiles)[i].pos, (*s.projectiles)[i].vel)
-		resetPosition(&(*s.projectiles)[i].pos)
-		(*s.projectiles)[i].ttl -= 1
-	}
-}
-
-func (s *PlayerShip) removeProjectiles() {
-	for i, p := range *s.projectiles {
-		if p.ttl < 1 {
-			removeItem(s.projectiles, i)
-		}
-	}
-}
-
-func removeItem[T any](slice *[]T, index int) {
-	if index < 0 || index >= len(*slice) {
-		return
-	}
-	copy((*slice)[index:], (*slice)[index+1:])
-	*slice = (*slice)[:len(*slice)-1]
+	center := sim.NewVector2(SCREEN_SIZE_X/2, SCREEN_SIZE_Y/2)
+	render.TextCentered(screen, "Game Over", center, 100, 1, render.WHITE)
+	render.TextCentered(screen, "Press Enter to try again", sim.Vector2Add(center, sim.NewVector2(0, 100)), 50, 1, render.WHITE)
 }
 
 func getDirection(orientation float32) rl.Vector2 {
-	circleX := float32(math.Cos(float64(orientation)))
-	circleY := float32(math.Sin(float64(orientation)))
-
-	newVector := rl.NewVector2(circleX, circleY)
-	return newVector
-
-}
-
-func (s *PlayerShip) drawShipExplosion() {
-
-	verticalDirection := rl.Vector2Add(rl.Vector2Scale(getDirection(s.orientation), s.size), s.pos)
-	horizontalDirection := rl.Vector2Add(rl.Vector2Scale(getDirection(s.orientation+math.Pi*0.7), s.size), s.pos)
-	opVerticalDirection := rl.Vector2Add(rl.Vector2Scale(getDirection(s.orientation+math.Pi*0.25), s.size), s.pos)
-	opHorizontalDirection := rl.Vector2Add(rl.Vector2Scale(getDirection(math.Pi+s.orientation+math.Pi*0.1), s.size), s.pos)
-
-	rl.DrawLineV(rl.Vector2AddValue(s.pos, 25), verticalDirection, rl.White)
-	rl.DrawLineV(rl.Vector2AddValue(s.pos, 50), horizontalDirection, rl.White)
-	rl.DrawLineV(rl.Vector2AddValue(s.pos, -40), opVerticalDirection, rl.White)
-	rl.DrawLineV(rl.Vector2AddValue(s.pos, 35), opHorizontalDirection, rl.White)
-}
-
-func (s *PlayerShip) drawShip() {
-	verticalDirection := rl.Vector2Scale(getDirection(s.orientation), s.size)
-	horizontalDirection := rl.Vector2Scale(getDirection(s.orientation+math.Pi*0.5), s.size)
-
-	points := []rl.Vector2{
-		rl.Vector2Add(s.pos, verticalDirection),
-		rl.Vector2Subtract(rl.Vector2Subtract(s.pos, verticalDirection), horizontalDirection),
-		s.pos,
-		rl.Vector2Add(rl.Vector2Subtract(s.pos, verticalDirection), horizontalDirection),
-		rl.Vector2Add(s.pos, verticalDirection),
-	}
-
-	for i := range points {
-		rl.DrawLineV(
-			points[i],
-			points[(i+1)%len(points)],
-			rl.White,
-		)
-	}
-}
-
-func drawLife(pos rl.Vector2, size float32, orientation float32) {
-	verticalDirection := rl.Vector2Scale(getDirection(orientation), size)
-	horizontalDirection := rl.Vector2Scale(getDirection(orientation+math.Pi*0.5), size)
-
-	points := []rl.Vector2{
-		rl.Vector2Add(pos, verticalDirection),
-		rl.Vector2Subtract(rl.Vector2Subtract(pos, verticalDirection), horizontalDirection),
-		pos,
-		rl.Vector2Add(rl.Vector2Subtract(pos, verticalDirection), horizontalDirection),
-		rl.Vector2Add(pos, verticalDirection),
-	}
-
-	for i := range points {
-		rl.DrawLineV(
-			points[i],
-			points[(i+1)%len(points)],
-			rl.White,
-		)
+	return rl.Vector2(sim.GetDirection(orientation))
+}
+
+// DEBUG_SENSOR is the sensor the debug overlay shows for the first ship.
+var DEBUG_SENSOR = sensor.Even(sensor.DefaultRays, sensor.DefaultRange)
+
+// SENSOR_COLORS are indexed by sensor.Kind.
+var SENSOR_COLORS = []rl.Color{rl.Fade(rl.Gray, 0.3), rl.Lime, rl.SkyBlue, rl.Red, rl.Gold}
+
+// drawSensor draws every ray up to what it hit. A ray that goes past an edge
+// is drawn again from the other side, the way it sees.
+func drawSensor(w *sim.World, s *sim.PlayerShip) {
+	pos := rl.Vector2(s.Pos)
+	for _, r := range DEBUG_SENSOR.Read(w, s) {
+		end := rl.Vector2Add(pos, rl.Vector2Scale(getDirection(s.Orientation+r.Angle), r.Distance))
+		for _, dx := range []float32{0, -SCREEN_SIZE_X, SCREEN_SIZE_X} {
+			for _, dy := range []float32{0, -SCREEN_SIZE_Y, SCREEN_SIZE_Y} {
+				offset := rl.NewVector2(dx, dy)
+				rl.DrawLineV(rl.Vector2Add(pos, offset), rl.Vector2Add(end, offset), SENSOR_COLORS[r.Kind])
+				if r.Kind != sensor.KindNone {
+					rl.DrawCircleV(rl.Vector2Add(end, offset), 3, SENSOR_COLORS[r.Kind])
+				}
+			}
+		}
 	}
-
 }
 
-func (s *PlayerShip) getShipPoints() []rl.Vector2 {
-	verticalDirection := rl.Vector2Scale(getDirection(s.orientation), s.size)
-	horizontalDirection := rl.Vector2Scale(getDirection(s.orientation+math.Pi*0.5), s.size)
-
-	points := []rl.Vector2{
-		rl.Vector2Add(s.pos, verticalDirection),
-		rl.Vector2Subtract(rl.Vector2Subtract(s.pos, verticalDirection), horizontalDirection),
-		s.pos,
-		rl.Vector2Add(rl.Vector2Subtract(s.pos, verticalDirection), horizontalDirection),
-		rl.Vector2Add(s.pos, verticalDirection),
+func (g *GameState) drawAsteroids() {
+	for _, a := range g.world.Asteroids {
+		render.Asteroid(screen, &a)
 	}
-	return points
 }
 
-func (g *GameState) getAsteroidsPoints() [][]rl.Vector2 {
-	asteroidsPoints := [][]rl.Vector2{}
-	for _, a := range *g.asteroids {
-		asteroidPoints := []rl.Vector2{
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation), a.size*a.sizes[0]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+(math.Pi*2)), a.size*a.sizes[1]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.1*(math.Pi*2)), a.size*a.sizes[2]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.2*(math.Pi*2)), a.size*a.sizes[3]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.3*(math.Pi*2)), a.size*a.sizes[4]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.4*(math.Pi*2)), a.size*a.sizes[5]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.5*(math.Pi*2)), a.size*a.sizes[6]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.6*(math.Pi*2)), a.size*a.sizes[7]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.7*(math.Pi*2)), a.size*a.sizes[8]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.8*(math.Pi*2)), a.size*a.sizes[9]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.9*(math.Pi*2)), a.size*a.sizes[10]), a.pos),
+// drawAttract draws the game the pilot plays behind the title screen and the
+// menus.
+func (g *GameState) drawAttract() {
+	for _, s := range g.world.Ships {
+		if s.Collision {
+			render.ShipExplosion(screen, s, float32(s.DestroyedTime/sim.SHIP_TIME_IN_PIECES))
+		} else {
+			render.Ship(screen, s)
 		}
-		asteroidsPoints = append(asteroidsPoints, asteroidPoints)
+		render.Projectiles(screen, s)
 	}
-	return asteroidsPoints
+	g.drawAsteroids()
 }
 
-func (g *GameState) checkColissions() bool {
-	shipPoints := g.playerShip.getShipPoints()
-	asteroidsPoints := g.getAsteroidsPoints()
-
-	for i := range shipPoints {
-		for j := range *g.asteroids {
-			collisionPoint := rl.Vector2{}
-			if rl.CheckCollisionLines(shipPoints[i], shipPoints[(i+1)%len(shipPoints)], asteroidsPoints[j][0], asteroidsPoints[j][1], &collisionPoint) ||
-				rl.CheckCollisionLines(shipPoints[i], shipPoints[(i+1)%len(shipPoints)], asteroidsPoints[j][1], asteroidsPoints[j][2], &collisionPoint) ||
-				rl.CheckCollisionLines(shipPoints[i], shipPoints[(i+1)%len(shipPoints)], asteroidsPoints[j][2], asteroidsPoints[j][3], &collisionPoint) ||
-				rl.CheckCollisionLines(shipPoints[i], shipPoints[(i+1)%len(shipPoints)], asteroidsPoints[j][3], asteroidsPoints[j][4], &collisionPoint) ||
-				rl.CheckCollisionLines(shipPoints[i], shipPoints[(i+1)%len(shipPoints)], asteroidsPoints[j][4], asteroidsPoints[j][5], &collisionPoint) ||
-				rl.CheckCollisionLines(shipPoints[i], shipPoints[(i+1)%len(shipPoints)], asteroidsPoints[j][5], asteroidsPoints[j][6], &collisionPoint) ||
-				rl.CheckCollisionLines(shipPoints[i], shipPoints[(i+1)%len(shipPoints)], asteroidsPoints[j][6], asteroidsPoints[j][7], &collisionPoint) ||
-				rl.CheckCollisionLines(shipPoints[i], shipPoints[(i+1)%len(shipPoints)], asteroidsPoints[j][7], asteroidsPoints[j][8], &collisionPoint) ||
-				rl.CheckCollisionLines(shipPoints[i], shipPoints[(i+1)%len(shipPoints)], asteroidsPoints[j][8], asteroidsPoints[j][9], &collisionPoint) ||
-				rl.CheckCollisionLines(shipPoints[i], shipPoints[(i+1)%len(shipPoints)], asteroidsPoints[j][9], asteroidsPoints[j][10], &collisionPoint) {
-				return true
-			}
+func (g *GameState) update() {
+	defer g.publish()
+	g.gameTime = rl.GetTime()
+	if g.scene == SCENE_OPTIONS || g.scene == SCENE_LOBBY || g.scene == SCENE_BROWSE {
+		g.audio.setMusic(SCENE_TITLE)
+	} else {
+		g.audio.setMusic(g.scene)
+	}
+	if g.scene == SCENE_TITLE || g.scene == SCENE_OPTIONS || g.scene == SCENE_LOBBY || g.scene == SCENE_BROWSE {
+		if g.scene == SCENE_LOBBY {
+			g.updateLobby()
+		} else if g.scene == SCENE_BROWSE {
+			g.updateBrowser()
+		} else if g.scene == SCENE_OPTIONS {
+			g.updateOptions()
+		} else if rl.IsKeyPressed(rl.KeyEnter) || rl.IsKeyPressed(rl.KeyOne) {
+			g.startGame(sim.ModeTurns, 1)
+		} else if rl.IsKeyPressed(rl.KeyTwo) {
+			g.startGame(sim.ModeTurns, 2)
+		} else if rl.IsKeyPressed(rl.KeyC) {
+			g.startGame(sim.ModeCoop, g.settings.CoopShips)
+		} else if rl.IsKeyPressed(rl.KeyV) {
+			g.startGame(sim.ModeDuel, 2)
+		} else if rl.IsKeyPressed(rl.KeyO) {
+			g.openOptions()
+		} else if rl.IsKeyPressed(rl.KeyL) {
+			g.openBrowser()
 		}
-	}
-	return false
-}
-
-func (g *GameState) checkProjectileCollisions() {
-
-	for i, p := range *g.playerShip.projectiles {
-		for j, a := range *g.asteroids {
-			asteroidsPoints := g.getAsteroidsPoints()
-			if j < len(*g.asteroids) {
-				if rl.CheckCollisionPointLine(p.pos, asteroidsPoints[j][0], asteroidsPoints[j][1], 20) ||
-					rl.CheckCollisionPointLine(p.pos, asteroidsPoints[j][1], asteroidsPoints[j][2], 20) ||
-					rl.CheckCollisionPointLine(p.pos, asteroidsPoints[j][2], asteroidsPoints[j][3], 20) ||
-					rl.CheckCollisionPointLine(p.pos, asteroidsPoints[j][3], asteroidsPoints[j][4], 20) ||
-					rl.CheckCollisionPointLine(p.pos, asteroidsPoints[j][4], asteroidsPoints[j][5], 20) ||
-					rl.CheckCollisionPointLine(p.pos, asteroidsPoints[j][5], asteroidsPoints[j][6], 20) ||
-					rl.CheckCollisionPointLine(p.pos, asteroidsPoints[j][6], asteroidsPoints[j][7], 20) ||
-					rl.CheckCollisionPointLine(p.pos, asteroidsPoints[j][7], asteroidsPoints[j][8], 20) ||
-					rl.CheckCollisionPointLine(p.pos, asteroidsPoints[j][8], asteroidsPoints[j][9], 20) ||
-					rl.CheckCollisionPointLine(p.pos, asteroidsPoints[j][9], asteroidsPoints[j][10], 20) {
-					removeItem(g.asteroids, j)
-					removeItem(g.playerShip.projectiles, i)
-					if a.size == ASTEROID_SIZE {
-						*g.asteroids = append(*g.asteroids, generateMidAsteroid(a.pos, 2.0, 3.0))
-						*g.asteroids = append(*g.asteroids, generateMidAsteroid(a.pos, 2.0, 3.0))
-					}
-					if a.size == ASTEROID_SIZE/2 {
-						*g.asteroids = append(*g.asteroids, generateMidAsteroid(a.pos, 4.0, 6.0))
-						*g.asteroids = append(*g.asteroids, generateMidAsteroid(a.pos, 4.0, 6.0))
-					}
-
-				}
-
-			}
-
+		if g.scene != SCENE_PLAYING {
+			g.world.Step(g.attractInputs())
 		}
-
+		g.particles.update()
+		g.audio.update()
+		return
+	}
+	if g.turnBanner > 0 {
+		g.turnBanner -= 1
 	}
-}
-
-func (g *GameState) input() {
 	if rl.IsKeyPressed(rl.KeyF1) {
 		g.debug = !g.debug
 	}
-	if rl.IsKeyDown(rl.KeyD) {
-		newOrientation := g.playerShip.orientation + PLAYER_SHIP_TURN_SPEED
-		if newOrientation >= 2*math.Pi {
-			g.playerShip.orientation = 0.0
-		} else if newOrientation <= -2*math.Pi {
-			g.playerShip.orientation = 0.0
-		} else {
-			g.playerShip.orientation = newOrientation
-		}
-
-	}
-	if rl.IsKeyDown(rl.KeyA) {
-		newOrientation := g.playerShip.orientation - PLAYER_SHIP_TURN_SPEED
-		if newOrientation >= 2*math.Pi {
-			g.playerShip.orientation = 0.0
-		} else if newOrientation <= -2*math.Pi {
-			g.playerShip.orientation = 0.0
-		} else {
-			g.playerShip.orientation = newOrientation
+	if rl.IsKeyPressed(rl.KeyF2) {
+		g.toggleAutopilot()
+	}
+	stepped := true
+	if g.net != nil {
+		stepped = g.advanceNetwork()
+	} else if g.remote != nil {
+		stepped = g.advanceRemote()
+	} else {
+		g.world.Step(g.localInputs())
+	}
+	if stepped {
+		g.handleEvents()
+	}
+	if g.net != nil || g.remote != nil || stepped {
+		g.scene = sceneFor(g.world.Phase)
+	}
+
+	thrusting := false
+	for _, s := range g.world.Ships {
+		thrusting = thrusting || s.Thrusting
+	}
+	g.audio.setThrust(thrusting)
+	g.particles.update()
+	g.heartbeat.update(g)
+	if g.scene == SCENE_PLAYING {
+		g.audio.setIntensity(g.intensity())
+	}
+	g.audio.update()
+
+}
+
+// handleEvents turns what happened in the last step into particles, sounds
+// and banners.
+func (g *GameState) handleEvents() {
+	for _, e := range g.world.Events {
+		pos := rl.Vector2(e.Pos)
+		vel := rl.Vector2(e.Vel)
+		switch e.Kind {
+		case sim.EventGameStart:
+			g.heartbeat = Heartbeat{}
+			g.particles.clear()
+		case sim.EventTurn:
+			g.turnBanner = TURN_BANNER_TIME
+		case sim.EventThrust:
+			g.particles.emit(EXHAUST_EMITTER, pos, vel, e.Angle)
+		case sim.EventFire:
+			g.audio.playAt("fire", pos, g.listener())
+		case sim.EventAsteroidHit:
+			g.particles.emit(IMPACT_EMITTER, pos, vel, e.Angle)
+			g.particles.emit(SPARKS_EMITTER, pos, vel, 0)
+			g.audio.playAt(explosionSound(e.Class), pos, g.listener())
+		case sim.EventShipDestroyed:
+			g.audio.playAt("explosion_large", pos, g.listener())
+			g.particles.emit(DEBRIS_EMITTER, pos, vel, 0)
+		case sim.EventExtraLife:
+			g.audio.play("extra_life")
 		}
 	}
-
-	//Sentido y orientacion de la nave
-	directionX := float32(math.Cos(float64(g.playerShip.orientation)))
-	directionY := float32(math.Sin(float64(g.playerShip.orientation)))
-
-	//Este vector es el sentido y orientacion de la nave
-	newVector := rl.NewVector2(directionX, directionY)
-
-	if rl.IsKeyDown(rl.KeyW) {
-		//Agregarle la rapidez
-		g.playerShip.vel = rl.Vector2Add(
-			g.playerShip.vel,
-			rl.Vector2Scale(newVector, g.playerShip.speed),
-		)
-	}
-
-	if rl.IsKeyDown(rl.KeyS) {
-		//Agregarle la rapidez
-		g.playerShip.vel = rl.Vector2Subtract(
-			g.playerShip.vel,
-			rl.Vector2Scale(newVector, g.playerShip.speed),
-		)
-	}
-
-	if rl.IsKeyPressed(rl.KeySpace) {
-		g.playerShip.shoot()
-	}
-
-	g.playerShip.pos = rl.Vector2Add(g.playerShip.pos, g.playerShip.vel)
-
-}
-
-func (a *Asteroid) drawAsteroid() {
-	types := [][]rl.Vector2{
-		{
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation), a.size*a.sizes[0]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+(math.Pi*2)), a.size*a.sizes[1]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.1*(math.Pi*2)), a.size*a.sizes[2]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.2*(math.Pi*2)), a.size*a.sizes[3]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.3*(math.Pi*2)), a.size*a.sizes[4]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.4*(math.Pi*2)), a.size*a.sizes[5]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.5*(math.Pi*2)), a.size*a.sizes[6]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.6*(math.Pi*2)), a.size*a.sizes[7]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.7*(math.Pi*2)), a.size*a.sizes[8]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.8*(math.Pi*2)), a.size*a.sizes[9]), a.pos),
-			rl.Vector2Add(rl.Vector2Scale(getDirection(a.orientation+1.9*(math.Pi*2)), a.size*a.sizes[10]), a.pos),
-		},
-	}
-	points := types[0]
-
-	for i := range points {
-		rl.DrawLineV(
-			//a.pos,
-			points[i],
-			points[(i+1)%len(points)],
-			rl.White,
-		)
-	}
-}
-
-func (g *GameState) drawAsteroids() {
-	for _, p := range *g.asteroids {
-		p.drawAsteroid()
-	}
 }
 
-func (g *GameState) moveAsteroids() {
-	for i := range *g.asteroids {
-		(*g.asteroids)[i].pos = rl.Vector2Add((*g.asteroids)[i].pos, (*g.asteroids)[i].vel)
-		resetPosition(&(*g.asteroids)[i].pos)
+func sceneFor(phase sim.Phase) Scene {
+	switch phase {
+	case sim.PhaseRoundOver:
+		return SCENE_ROUND_OVER
+	case sim.PhaseGameOver:
+		return SCENE_GAME_OVER
+	default:
+		return SCENE_PLAYING
 	}
 }
 
-func resetPosition(position *rl.Vector2) *rl.Vector2 {
-
-	position.X = float32(
-		math.Mod(
-			float64(position.X), float64(SCREEN_SIZE_X)))
-
-	position.Y = float32(
-		math.Mod(
-			float64(position.Y), float64(SCREEN_SIZE_Y)))
-
-	if position.X <= 0 {
-		position.X = SCREEN_SIZE_X
+// intensity goes from 0 at the start of the game to 1 as the rocks are
+// cleared and the waves go by.
+func (g *GameState) intensity() float32 {
+	cleared := float32(0)
+	if g.world.WaveMass > 0 {
+		cleared = 1 - g.world.AsteroidsMass()/g.world.WaveMass
 	}
-	if position.Y <= 0 {
-		position.Y = SCREEN_SIZE_Y
-	}
-	return position
+	return min(1, cleared+0.15*float32(g.world.Wave-1))
 }
 
-func (g *GameState) update() {
-	g.gameTime = rl.GetTime()
-	if !g.collision && g.lives > 0 {
-		g.input()
-	}
-	if g.lives > 0 {
-		g.playerShip.pos = *resetPosition(&g.playerShip.pos)
-		g.playerShip.moveProjectiles()
-		g.playerShip.removeProjectiles()
-		g.checkProjectileCollisions()
-
-		g.playerShip.pos = rl.Vector2Add(g.playerShip.pos, g.playerShip.vel)
-		if g.playerShip.vel.X > MAX_SPEED {
-			g.playerShip.vel.X = MAX_SPEED
-		}
-		if g.playerShip.vel.Y > MAX_SPEED {
-			g.playerShip.vel.Y = MAX_SPEED
-		}
-		if g.playerShip.vel.X < -MAX_SPEED {
-			g.playerShip.vel.X = -MAX_SPEED
-		}
-		if g.playerShip.vel.Y < -MAX_SPEED {
-			g.playerShip.vel.Y = -MAX_SPEED
-		}
-
-		if g.collision {
-			g.destroyedTime -= 0.1
-			if g.destroyedTime < 0 {
-				g.restartGame()
-			}
-		} else {
-			if g.checkColissions() {
-				g.collision = true
-			}
-		}
+// listener is where sounds are heard from: the ship, or the middle of the
+// screen when several ships share it.
+func (g *GameState) listener() rl.Vector2 {
+	if len(g.world.Ships) == 1 {
+		return rl.Vector2(g.world.Ships[0].Pos)
 	}
-	if g.lives < 1 {
-		if rl.IsKeyPressed(rl.KeyEnter) {
-			g.reInitGame()
-			log.Println("Enter pressed")
-		}
-	}
-	g.moveAsteroids()
-
+	return rl.NewVector2(SCREEN_SIZE_X/2, SCREEN_SIZE_Y/2)
 }
 
 func (g *GameState) render() {
@@ -533,36 +281,61 @@ func (g *GameState) render() {
 
 func (g *GameState) draw() {
 	rl.ClearBackground(rl.Black)
-	if g.debug {
-		rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Ship position: (%f, %f)", g.playerShip.pos.X, g.playerShip.pos.Y), rl.Vector2{
+	if g.scene == SCENE_TITLE {
+		g.drawAttract()
+		drawTitleScreen()
+		if g.message != "" {
+			render.TextCentered(screen, g.message, sim.NewVector2(SCREEN_SIZE_X/2, SCREEN_SIZE_Y-40), 20, 1, rl.Red)
+		}
+		return
+	}
+	if g.scene == SCENE_LOBBY {
+		g.drawAttract()
+		g.drawLobby()
+		return
+	}
+	if g.scene == SCENE_OPTIONS {
+		g.drawAttract()
+		g.drawOptions()
+		return
+	}
+	if g.scene == SCENE_BROWSE {
+		g.drawAttract()
+		g.drawBrowser()
+		return
+	}
+	if g.debug && len(g.world.Ships) > 0 {
+		ship := g.world.Ships[0]
+		drawSensor(g.world, ship)
+		rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Ship position: (%f, %f)", ship.Pos.X, ship.Pos.Y), rl.Vector2{
 			X: 10,
 			Y: 10,
 		}, 10.0, 1.0, rl.White)
-		rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Velocity: (%f, %f)", g.playerShip.vel.X, g.playerShip.vel.Y), rl.Vector2{
+		rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Velocity: (%f, %f)", ship.Vel.X, ship.Vel.Y), rl.Vector2{
 			X: 10,
 			Y: 30,
 		}, 10.0, 1.0, rl.White)
 
-		for i, p := range *g.playerShip.projectiles {
-			rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("P(%f, %f)", p.pos.X, p.pos.Y), rl.Vector2{
+		for i, p := range ship.Projectiles {
+			rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("P(%f, %f)", p.Pos.X, p.Pos.Y), rl.Vector2{
 				X: 150,
 				Y: 50 + 10*float32(i),
 			}, 10.0, 1.0, rl.White)
 		}
 
-		for i, a := range *g.asteroids {
-			rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("A(%f, %f)", a.pos.X, a.pos.Y), rl.Vector2{
+		for i, a := range g.world.Asteroids {
+			rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("A(%f, %f)", a.Pos.X, a.Pos.Y), rl.Vector2{
 				X: 10,
 				Y: 50 + 10*float32(i),
 			}, 10.0, 1.0, rl.White)
 		}
-		if g.collision {
-			rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Collision: %v", g.collision), rl.Vector2{
+		if ship.Collision {
+			rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Collision: %v", ship.Collision), rl.Vector2{
 				X: 210,
 				Y: 10,
 			}, 10.0, 1.0, rl.Red)
 		} else {
-			rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Collision: %v", g.collision), rl.Vector2{
+			rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Collision: %v", ship.Collision), rl.Vector2{
 				X: 210,
 				Y: 10,
 			}, 10.0, 1.0, rl.White)
@@ -571,100 +344,160 @@ func (g *GameState) draw() {
 			X: 300,
 			Y: 10,
 		}, 10.0, 1.0, rl.White)
+		if g.autopilot != nil {
+			rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Autopilot (%s)", g.autopilotName()), rl.Vector2{
+				X: 450,
+				Y: 10,
+			}, 10.0, 1.0, rl.Yellow)
+		}
 
 	}
-	if g.collision {
-		g.playerShip.drawShipExplosion()
-
-	} else if g.lives > 0 {
-		g.playerShip.drawShip()
+	if g.world.Rules.Mode == sim.ModeDuel && g.scene == SCENE_PLAYING {
+		render.Star(screen, g.world.Tick)
+	}
+	if g.scene == SCENE_PLAYING {
+		for _, s := range g.world.Ships {
+			if s.Collision {
+				render.ShipExplosion(screen, s, float32(s.DestroyedTime/sim.SHIP_TIME_IN_PIECES))
+			} else {
+				render.Ship(screen, s)
+			}
+		}
 	}
 
-	g.playerShip.drawProjectiles()
-	g.drawAsteroids()
-	for i := range g.lives {
-		drawLife(rl.Vector2{
-			X: 25 + 45*float32(i),
-			Y: SCREEN_SIZE_Y - 25,
-		}, 20, math.Pi+math.Pi*0.5)
+	g.particles.draw()
+	for _, s := range g.world.Ships {
+		render.Projectiles(screen, s)
 	}
-	if g.lives <= 0 {
+	g.drawAsteroids()
+	render.Lives(screen, g.world)
+	g.drawScores()
+	if g.world.Rules.Mode == sim.ModeDuel && (g.scene == SCENE_ROUND_OVER || g.scene == SCENE_GAME_OVER) {
+		g.drawRoundScreen()
+	} else if g.scene == SCENE_GAME_OVER {
 		drawGameOverScreen()
 	}
+	if g.net != nil {
+		g.drawNetworkStatus()
+	}
 }
 
 func initGame() *GameState {
 	gState := GameState{
-		playerShip: &PlayerShip{
-			pos: rl.Vector2{
-				X: SCREEN_SIZE_X / 2,
-				Y: SCREEN_SIZE_Y / 2,
-			},
-			size:        PLAYER_SHIP_SIZE,
-			orientation: PLAYER_SHIP_INITIAL_ORIENTATION,
-			speed:       PLAYER_SHIP_SPEED,
-			vel: rl.Vector2{
-				X: 0,
-				Y: 0,
-			},
-			projectiles: &[]Projectile{},
-		},
-		asteroids:     generateAsteroids(),
-		debug:         true,
-		collision:     false,
-		lives:         LIVES,
-		gameTime:      0,
-		destroyedTime: SHIP_TIME_IN_PIECES,
-	}
+		debug:               true,
+		gameTime:            0,
+		scene:               SCENE_TITLE,
+		settings:            loadSettings(),
+		particles:           newParticleSystem(MAX_PARTICLES),
+		autopilotDifficulty: bot.Normal,
+	}
+	gState.newTitleWorld()
 	return &gState
 }
 
-func (g *GameState) restartGame() {
-	g.playerShip.pos = rl.Vector2{
-		X: SCREEN_SIZE_X / 2,
-		Y: SCREEN_SIZE_Y / 2,
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "server":
+			runServer(os.Args[2:])
+			return
+		case "agent":
+			runAgent(os.Args[2:])
+			return
+		case "train":
+			runTrain(os.Args[2:])
+			return
+		case "simulate":
+			runSimulate(os.Args[2:])
+			return
+		case "sweep":
+			runSweep(os.Args[2:])
+			return
+		case "screenshot":
+			runScreenshot(os.Args[2:])
+			return
+		}
 	}
-	g.playerShip.orientation = -math.Pi / 2
-	g.playerShip.vel = rl.Vector2{
-		X: 0,
-		Y: 0,
+	exportSounds := flag.String("export-sounds", "", "write every sound effect as a WAV file to this directory and exit")
+	host := flag.String("host", "", "host a networked game, listening on this address, like :7777")
+	join := flag.String("join", "", "join the networked game hosted at this address, like 192.168.0.10:7777")
+	players := flag.Int("players", 2, "how many players the hosted game, or the match created on a server, is for")
+	connect := flag.String("connect", "", "play a match on the server at this address, like 192.168.0.10:7778")
+	match := flag.String("match", "asteroids", "the name of the match to play on the server, created with -mode and -players if it doesn't exist")
+	tcp := flag.Bool("tcp", false, "connect to the server over TCP instead of UDP")
+	spectateAddr := flag.String("spectate", "", "let browsers watch the game at this address, like localhost:8080")
+	autopilot := flag.String("autopilot", "", "fly the first ship with the autopilot from the start, and set how well it flies when F2 turns it on: easy, normal or hard")
+	genome := flag.String("pilot", "", "let a genome made by the train command, like training/best.json, be the autopilot")
+	mode := flag.String("mode", "coop", "the mode of the hosted game, or the match created on a server: coop or versus")
+	options := netplay.DefaultOptions()
+	flag.IntVar(&options.InputDelay, "input-delay", options.InputDelay, "ticks every input waits before it's used, to hide the network latency")
+	flag.IntVar(&options.Rollback, "rollback", options.Rollback, "ticks the game can guess ahead of the other players' inputs, 0 to always wait for them")
+	flag.DurationVar(&options.Conditions.Latency, "net-latency", 0, "simulated latency added to every packet sent")
+	flag.DurationVar(&options.Conditions.Jitter, "net-jitter", 0, "simulated random extra latency, up to this much, added to every packet sent")
+	flag.Float64Var(&options.Conditions.Loss, "net-loss", 0, "simulated fraction of the packets sent that get lost, from 0 to 1")
+	flag.Parse()
+	if *exportSounds != "" {
+		if err := synth.ExportPresets(*exportSounds); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
-	g.collision = false
-	g.lives = g.lives - 1
-	g.destroyedTime = SHIP_TIME_IN_PIECES
-
-}
 
-func (g *GameState) reInitGame() {
-
-	g.playerShip = &PlayerShip{
-		pos: rl.Vector2{
-			X: SCREEN_SIZE_X / 2,
-			Y: SCREEN_SIZE_Y / 2,
-		},
-		size:        PLAYER_SHIP_SIZE,
-		orientation: PLAYER_SHIP_INITIAL_ORIENTATION,
-		speed:       PLAYER_SHIP_SPEED,
-		vel: rl.Vector2{
-			X: 0,
-			Y: 0,
-		},
-		projectiles: &[]Projectile{},
-	}
-	g.asteroids = generateAsteroids()
-	g.debug = true
-	g.collision = false
-	g.lives = LIVES
-	g.gameTime = 0
-	g.destroyedTime = SHIP_TIME_IN_PIECES
-
-}
-
-func main() {
 	rl.InitWindow(SCREEN_SIZE_X, SCREEN_SIZE_Y, "Rokas espasiales")
 
 	defer rl.CloseWindow()
 	gState := initGame()
+	gState.netOptions = options
+	if *genome != "" {
+		g, err := neuro.LoadGenome(*genome)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gState.genome = g
+		gState.genomePath = *genome
+	}
+	if *autopilot != "" {
+		d, err := bot.ParseDifficulty(*autopilot)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gState.autopilotDifficulty = d
+		gState.toggleAutopilot()
+	}
+	if *spectateAddr != "" {
+		gState.spectators = spectate.NewHub()
+		go func() {
+			log.Println("Spectators:", gState.spectators.ListenAndServe(*spectateAddr))
+		}()
+	}
+	if *host != "" {
+		m, err := parseMode(*mode)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := gState.hostGame(*host, m, *players, options); err != nil {
+			log.Fatal(err)
+		}
+	} else if *join != "" {
+		if err := gState.joinGame(*join, options); err != nil {
+			log.Fatal(err)
+		}
+	} else if *connect != "" {
+		m, err := parseMode(*mode)
+		if err != nil {
+			log.Fatal(err)
+		}
+		network := "udp"
+		if *tcp {
+			network = "tcp"
+		}
+		if err := gState.connectServer(network, *connect, *match, m, *players); err != nil {
+			log.Fatal(err)
+		}
+	}
+	gState.audio = newAudio()
+	gState.audio.applySettings(gState.settings)
+	defer gState.audio.close()
 	rl.SetTargetFPS(60)
 
 	for !rl.WindowShouldClose() {
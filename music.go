@@ -0,0 +1,95 @@
+package main
+
+import (
+	"github.com/rodolfato/asteroids/synth"
+)
+
+var TITLE_MUSIC = synth.MustParsePattern(`
+tempo 110
+track bass triangle 0.3
+A2 - - - . . A2 . F2 - - - . . F2 .
+C3 - - - . . C3 . G2 - - - . . E2 .
+track arp square 0.07
+A4 C5 E5 C5 A4 C5 E5 C5 F4 A4 C5 A4 F4 A4 C5 A4
+C5 E5 G5 E5 C5 E5 G5 E5 G4 B4 D5 B4 E4 G#4 B4 G#4
+`)
+
+// GAME_MUSIC is layered by intensity: the bass is always there, the hats
+// and the lead fade in as the wave gets more intense.
+var GAME_MUSIC = synth.MustParsePattern(`
+tempo 120
+track bass triangle 0.2
+A1 . . . A1 . . . A1 . . . G1 . . .
+track hats noise 0.04
+C8 . C8 . C8 . C8 . C8 . C8 . C8 . C8 C8
+track lead square 0.05
+A4 - . . C5 - . . E5 - . . D5 - C5 .
+`)
+
+var GAME_MUSIC_LAYERS = []string{"bass", "hats", "lead"}
+
+var GAME_OVER_MUSIC = synth.MustParsePattern(`
+tempo 80
+track bass triangle 0.3
+A2 - - - E2 - - - A1 - - - - - - -
+track lead square 0.08
+E5 - D5 - C5 - B4 - A4 - - - - - - -
+`)
+
+// Music holds every scene's music already rendered. Each scene is a list of
+// layers that play in sync.
+type Music struct {
+	scenes  map[Scene][][]float32
+	loops   map[Scene]bool
+	scene   Scene
+	playing []*synth.Voice
+}
+
+func newMusic() *Music {
+	m := &Music{
+		scenes: map[Scene][][]float32{
+			SCENE_TITLE:     {TITLE_MUSIC.Render(synth.SampleRate, true)},
+			SCENE_GAME_OVER: {GAME_OVER_MUSIC.Render(synth.SampleRate, false)},
+		},
+		loops: map[Scene]bool{
+			SCENE_TITLE:     true,
+			SCENE_PLAYING:   true,
+			SCENE_GAME_OVER: false,
+		},
+		scene: -1,
+	}
+	for _, layer := range GAME_MUSIC_LAYERS {
+		m.scenes[SCENE_PLAYING] = append(m.scenes[SCENE_PLAYING], GAME_MUSIC.RenderTracks(synth.SampleRate, true, []string{layer}))
+	}
+	return m
+}
+
+// setMusic switches to the music of the scene, if it isn't already playing.
+func (a *Audio) setMusic(scene Scene) {
+	if a == nil || a.music.scene == scene {
+		return
+	}
+	for _, v := range a.music.playing {
+		v.Stop()
+	}
+	a.music.playing = nil
+	a.music.scene = scene
+	for _, layer := range a.music.scenes[scene] {
+		a.music.playing = append(a.music.playing, a.mixer.Play(synth.BusMusic, layer, 1, a.music.loops[scene]))
+	}
+}
+
+// setIntensity fades the music layers in one after the other as intensity
+// goes from 0 to 1. The first layer always plays.
+func (a *Audio) setIntensity(intensity float32) {
+	if a == nil {
+		return
+	}
+	layers := len(a.music.playing)
+	for i, v := range a.music.playing {
+		if i == 0 {
+			continue
+		}
+		v.SetVolume(max(0, min(1, intensity*float32(layers-1)-float32(i-1))))
+	}
+}
@@ -0,0 +1,50 @@
+package netplay
+
+import (
+	"math/rand/v2"
+	"net"
+	"time"
+)
+
+// Conditions describe a bad network to play over without having one. Every
+// packet sent waits Latency plus a random extra of up to Jitter, which can
+// reorder them, and a Loss fraction of them is never sent at all.
+type Conditions struct {
+	Latency time.Duration
+	Jitter  time.Duration
+	Loss    float64
+}
+
+func (c Conditions) enabled() bool {
+	return c.Latency > 0 || c.Jitter > 0 || c.Loss > 0
+}
+
+// simulatedConn sends through a real connection under the given conditions.
+// Packets are only affected on the way out, so with both sides simulating the
+// round trip gets twice the latency.
+type simulatedConn struct {
+	net.PacketConn
+	conditions Conditions
+}
+
+func simulate(conn net.PacketConn, c Conditions) net.PacketConn {
+	if !c.enabled() {
+		return conn
+	}
+	return &simulatedConn{PacketConn: conn, conditions: c}
+}
+
+func (c *simulatedConn) WriteTo(data []byte, addr net.Addr) (int, error) {
+	if rand.Float64() < c.conditions.Loss {
+		return len(data), nil
+	}
+	delay := c.conditions.Latency
+	if c.conditions.Jitter > 0 {
+		delay += rand.N(c.conditions.Jitter)
+	}
+	data = append([]byte(nil), data...)
+	time.AfterFunc(delay, func() {
+		c.PacketConn.WriteTo(data, addr)
+	})
+	return len(data), nil
+}
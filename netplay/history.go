@@ -0,0 +1,46 @@
+package netplay
+
+import "github.com/rodolfato/asteroids/sim"
+
+// history is the inputs of a player from the oldest tick anything could
+// still need on. The ones before start were forgotten: every machine had
+// them and the world can't go back that far anymore.
+type history struct {
+	start  int
+	inputs []sim.Input
+}
+
+// end is the first tick whose input isn't known yet.
+func (h *history) end() int {
+	return h.start + len(h.inputs)
+}
+
+func (h *history) at(tick int) sim.Input {
+	return h.inputs[tick-h.start]
+}
+
+func (h *history) last() sim.Input {
+	return h.inputs[len(h.inputs)-1]
+}
+
+func (h *history) add(in sim.Input) {
+	h.inputs = append(h.inputs, in)
+}
+
+// between returns the known inputs from start up to, but not including, end.
+func (h *history) between(start, end int) []sim.Input {
+	return h.inputs[start-h.start : end-h.start]
+}
+
+// forget drops the inputs before the tick. The last one is always kept, the
+// guesses for the ticks after it are made from it.
+func (h *history) forget(tick int) {
+	tick = min(tick, h.end()-1)
+	if tick <= h.start {
+		return
+	}
+	//Se corren al principio para que el arreglo no crezca sin fin
+	n := copy(h.inputs, h.inputs[tick-h.start:])
+	h.inputs = h.inputs[:n]
+	h.start = tick
+}
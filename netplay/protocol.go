@@ -0,0 +1,156 @@
+package netplay
+
+import (
+	"errors"
+
+	"github.com/rodolfato/asteroids/sim"
+	"github.com/rodolfato/asteroids/wire"
+)
+
+// Version has to match between the host and everyone joining. It goes up
+// whenever the messages or the simulation change.
+const Version = 3
+
+const magic = "AST"
+
+type messageKind uint8
+
+const (
+	msgJoin messageKind = iota + 1
+	msgReject
+	msgWelcome
+	msgInputs
+)
+
+// welcome tells a client which player it is and, once everyone is in, the
+// seed and rules of the game.
+type welcome struct {
+	player  int
+	players int
+	joined  int
+	started bool
+	seed    uint64
+	delay   int
+	rules   sim.Rules
+}
+
+// inputs carries what the sender knows that the receiver may not: the inputs
+// of every player from where the receiver's acks say it's missing them, and
+// the sender's latest checksums.
+type inputs struct {
+	acks      [sim.MAX_SHIPS]int
+	blocks    []inputBlock
+	checksums []checksum
+}
+
+type inputBlock struct {
+	player int
+	start  int
+	inputs []sim.Input
+}
+
+type checksum struct {
+	tick int
+	sum  uint32
+}
+
+func encodeJoin() []byte {
+	w := wire.NewWriter(uint8(msgJoin))
+	w.Bytes([]byte(magic))
+	w.U32(Version)
+	return w.Data
+}
+
+func decodeJoin(r *wire.Reader) (uint32, error) {
+	if string(r.Bytes(len(magic))) != magic {
+		return 0, errors.New("not an asteroids game")
+	}
+	version := r.U32()
+	return version, r.Err
+}
+
+func encodeReject(reason string) []byte {
+	w := wire.NewWriter(uint8(msgReject))
+	w.String(reason)
+	return w.Data
+}
+
+func encodeWelcome(m welcome) []byte {
+	w := wire.NewWriter(uint8(msgWelcome))
+	w.U8(m.player)
+	w.U8(m.players)
+	w.U8(m.joined)
+	w.Bool(m.started)
+	w.U64(m.seed)
+	w.U8(m.delay)
+	w.U8(int(m.rules.Mode))
+	w.Bool(m.rules.FriendlyFire)
+	w.U8(m.rules.DuelRounds)
+	w.Bool(m.rules.DuelAsteroids)
+	return w.Data
+}
+
+func decodeWelcome(r *wire.Reader) (welcome, error) {
+	m := welcome{
+		player:  r.U8(),
+		players: r.U8(),
+		joined:  r.U8(),
+		started: r.Bool(),
+		seed:    r.U64(),
+		delay:   r.U8(),
+	}
+	m.rules = sim.Rules{
+		Mode:          sim.Mode(r.U8()),
+		Players:       m.players,
+		FriendlyFire:  r.Bool(),
+		DuelRounds:    r.U8(),
+		DuelAsteroids: r.Bool(),
+	}
+	if m.players < 1 || m.players > sim.MAX_SHIPS || m.player >= m.players {
+		return m, errors.New("bad welcome")
+	}
+	return m, r.Err
+}
+
+func encodeInputs(m inputs) []byte {
+	w := wire.NewWriter(uint8(msgInputs))
+	for _, ack := range m.acks {
+		w.U32(uint32(ack))
+	}
+	w.U8(len(m.blocks))
+	for _, b := range m.blocks {
+		w.U8(b.player)
+		w.U32(uint32(b.start))
+		w.U8(len(b.inputs))
+		for _, in := range b.inputs {
+			w.U8(int(in))
+		}
+	}
+	w.U8(len(m.checksums))
+	for _, c := range m.checksums {
+		w.U32(uint32(c.tick))
+		w.U32(c.sum)
+	}
+	return w.Data
+}
+
+func decodeInputs(r *wire.Reader) (inputs, error) {
+	m := inputs{}
+	for i := range m.acks {
+		m.acks[i] = int(r.U32())
+	}
+	for range r.U8() {
+		b := inputBlock{player: r.U8(), start: int(r.U32())}
+		for range r.U8() {
+			b.inputs = append(b.inputs, sim.Input(r.U8()))
+		}
+		if b.player >= sim.MAX_SHIPS {
+			return m, errors.New("bad player")
+		}
+		m.blocks = append(m.blocks, b)
+	}
+	for range r.U8() {
+		m.checksums = append(m.checksums, checksum{tick: int(r.U32()), sum: r.U32()})
+	}
+	return m, r.Err
+}
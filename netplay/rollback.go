@@ -0,0 +1,92 @@
+package netplay
+
+import (
+	"slices"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// confirmed returns the first tick for which some player's input is still
+// missing. Every tick before it can be played for real.
+func (s *Session) confirmed() int {
+	confirmed := s.inputs[0].end()
+	for i := range s.inputs {
+		confirmed = min(confirmed, s.inputs[i].end())
+	}
+	return confirmed
+}
+
+// guess returns the inputs for a tick, using the real ones where they're
+// known. A missing input is guessed to be the last one known from that
+// player, since players mostly keep holding the same keys, but without the
+// presses that only last a tick like firing.
+func (s *Session) guess(tick int) []sim.Input {
+	inputs := make([]sim.Input, s.players)
+	for i := range s.inputs {
+		known := &s.inputs[i]
+		switch {
+		case tick < known.end():
+			inputs[i] = known.at(tick)
+		case len(known.inputs) > 0:
+			inputs[i] = known.last() &^ (sim.InputFire | sim.InputStart)
+		}
+	}
+	return inputs
+}
+
+// advanceRollback checks the ticks played with guessed inputs against the
+// real ones that arrived, plays them again from the first wrong guess, and
+// then plays one new tick unless that would get more than the rollback
+// window ahead of the real inputs.
+func (s *Session) advanceRollback(w *sim.World) bool {
+	confirmed := min(s.confirmed(), w.Tick)
+	for tick := s.verified; tick < confirmed; tick++ {
+		if !slices.Equal(s.played[tick], s.ready(tick)) {
+			s.resimulate(w, tick)
+			break
+		}
+	}
+
+	//Los ticks confirmados ya no pueden cambiar, se comparan con los demas
+	for tick := s.verified + 1; tick <= confirmed; tick++ {
+		state := w
+		if tick < w.Tick {
+			state = s.snapshots[tick]
+		}
+		s.tick = tick
+		s.record(s.checksums, s.remote, tick, state.Checksum())
+	}
+	for tick := s.verified; tick < confirmed; tick++ {
+		delete(s.snapshots, tick)
+		delete(s.played, tick)
+	}
+	s.verified = max(s.verified, confirmed)
+
+	if w.Tick-confirmed >= s.window {
+		w.Events = w.Events[:0]
+		return false
+	}
+	s.step(w)
+	return true
+}
+
+// step plays a tick with the best inputs known, keeping a snapshot of the
+// world before it in case it has to be played again.
+func (s *Session) step(w *sim.World) {
+	inputs := s.guess(w.Tick)
+	s.snapshots[w.Tick] = w.Clone()
+	s.played[w.Tick] = inputs
+	w.Step(inputs)
+}
+
+// resimulate goes back to the snapshot of the tick and plays again up to
+// where the world was. The events of those ticks were already seen and
+// heard the first time, so they're dropped.
+func (s *Session) resimulate(w *sim.World, tick int) {
+	target := w.Tick
+	w.Restore(s.snapshots[tick])
+	for w.Tick < target {
+		s.step(w)
+	}
+	w.Events = w.Events[:0]
+}
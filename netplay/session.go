@@ -0,0 +1,519 @@
+// Package netplay plays a game between two to four machines over UDP using
+// lockstep. Every machine runs the whole simulation and only the players'
+// inputs go over the network: a tick is simulated once the inputs of every
+// player for it have arrived, and each local input is scheduled a few ticks
+// ahead of when it's used, the input delay, to hide the time it takes to get
+// there. Checksums of the world after every tick are exchanged to catch a
+// desync as soon as it happens.
+//
+// With a rollback window, a machine doesn't wait for the inputs of the others:
+// it guesses them and keeps playing, and when the real ones arrive and turn
+// out different it goes back to the snapshot of that tick and plays it again.
+//
+// The host relays the inputs between the clients, so every client only talks
+// to the host.
+package netplay
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/rodolfato/asteroids/sim"
+	"github.com/rodolfato/asteroids/wire"
+)
+
+const (
+	DefaultInputDelay = 3
+	DefaultRollback   = 8
+	// MAX_INPUTS_PER_BLOCK limits how many inputs of a player go in a single
+	// packet. Anything missing is sent again in the next one.
+	MAX_INPUTS_PER_BLOCK = 64
+	// CHECKSUMS_PER_PACKET is how many of the latest checksums every packet
+	// repeats, so a lost packet doesn't lose them.
+	CHECKSUMS_PER_PACKET = 8
+	CHECKSUM_HISTORY     = 600
+	HANDSHAKE_INTERVAL   = 200 * time.Millisecond
+	TIMEOUT              = 5 * time.Second
+	MAX_PACKET_SIZE      = 1500
+)
+
+type packet struct {
+	addr net.Addr
+	data []byte
+}
+
+// peer is a machine on the other side of the connection: the host for a
+// client, or one of the clients for the host.
+type peer struct {
+	addr   net.Addr
+	player int
+	acks   [sim.MAX_SHIPS]int
+	ready  bool
+	heard  time.Time
+}
+
+// Session is one machine's side of a networked game. It's driven from the
+// game loop: Poll every frame to handle what arrived, and once Started,
+// Advance to step the world.
+type Session struct {
+	conn      net.PacketConn
+	host      bool
+	peers     []*peer
+	packets   chan packet
+	local     int
+	players   int
+	joined    int
+	seed      uint64
+	delay     int
+	rules     sim.Rules
+	window    int
+	started   bool
+	inputs    []history
+	checksums map[int]uint32
+	remote    map[int]uint32
+	tick      int
+	desync    int
+	verified  int
+	snapshots map[int]*sim.World
+	played    map[int][]sim.Input
+	lastSent  time.Time
+	err       error
+}
+
+// Options tune how a session deals with the network.
+type Options struct {
+	// InputDelay is how many ticks every input waits before it's used. It's
+	// chosen by the host for everyone.
+	InputDelay int
+	// Rollback is how many ticks a machine can play ahead of the inputs it
+	// has, guessing the rest. With 0 it waits for them like plain lockstep.
+	Rollback int
+	// Conditions make the network worse on purpose, for testing.
+	Conditions Conditions
+}
+
+func DefaultOptions() Options {
+	return Options{
+		InputDelay: DefaultInputDelay,
+		Rollback:   DefaultRollback,
+	}
+}
+
+// Host listens on addr for players to join a game with the given rules.
+// The game starts as soon as rules.Players machines, counting this one, are
+// in.
+func Host(addr string, rules sim.Rules, seed uint64, options Options) (*Session, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	rules.Players = max(1, min(sim.MAX_SHIPS, rules.Players))
+	s := newSession(conn, options)
+	s.host = true
+	s.players = rules.Players
+	s.joined = 1
+	s.seed = seed
+	s.delay = max(0, options.InputDelay)
+	s.rules = rules
+	if s.joined == s.players {
+		s.start()
+	}
+	return s, nil
+}
+
+// Join asks the host at addr for a place in its game. The rules come from
+// the host once everyone has joined.
+func Join(addr string, options Options) (*Session, error) {
+	hostAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		return nil, err
+	}
+	s := newSession(conn, options)
+	s.peers = []*peer{{addr: hostAddr, player: 0, heard: time.Now()}}
+	return s, nil
+}
+
+func newSession(conn net.PacketConn, options Options) *Session {
+	s := &Session{
+		conn:      simulate(conn, options.Conditions),
+		packets:   make(chan packet, 256),
+		window:    max(0, options.Rollback),
+		checksums: map[int]uint32{},
+		remote:    map[int]uint32{},
+		desync:    -1,
+		snapshots: map[int]*sim.World{},
+		played:    map[int][]sim.Input{},
+	}
+	go s.read()
+	return s
+}
+
+// read runs on its own goroutine and hands every packet to Poll. Packets that
+// don't fit in the queue are dropped like the network would.
+func (s *Session) read() {
+	buf := make([]byte, MAX_PACKET_SIZE)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Println("netplay:", err)
+			}
+			close(s.packets)
+			return
+		}
+		select {
+		case s.packets <- packet{addr: addr, data: append([]byte(nil), buf[:n]...)}:
+		default:
+		}
+	}
+}
+
+func (s *Session) Close() error {
+	return s.conn.Close()
+}
+
+// Addr is the local address the session listens on.
+func (s *Session) Addr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+func (s *Session) IsHost() bool {
+	return s.host
+}
+
+// Players returns how many players have joined out of how many the game is
+// for. A client doesn't know until the host welcomes it.
+func (s *Session) Players() (int, int) {
+	return s.joined, s.players
+}
+
+func (s *Session) Started() bool {
+	return s.started
+}
+
+// Local is the player this machine controls.
+func (s *Session) Local() int {
+	return s.local
+}
+
+// NewWorld creates the world every machine in the game simulates. It can only
+// be called once the session has started.
+func (s *Session) NewWorld() *sim.World {
+	return sim.NewWorld(s.seed, s.rules)
+}
+
+// Err returns why the session can't go on, if it can't.
+func (s *Session) Err() error {
+	return s.err
+}
+
+// Desync returns the first tick whose checksum didn't match another machine's.
+func (s *Session) Desync() (int, bool) {
+	return s.desync, s.desync >= 0
+}
+
+func (s *Session) start() {
+	s.started = true
+	s.inputs = make([]history, s.players)
+	for i := range s.inputs {
+		//Las primeras entradas no llegan nunca por el retraso, son vacias para todos
+		s.inputs[i].inputs = make([]sim.Input, s.delay)
+	}
+	for _, p := range s.peers {
+		p.heard = time.Now()
+	}
+}
+
+// Poll handles every packet that arrived since the last call and keeps the
+// handshake going. It returns the session's error, if any.
+func (s *Session) Poll() error {
+	if s.err != nil {
+		return s.err
+	}
+	for drained := false; !drained; {
+		select {
+		case p, ok := <-s.packets:
+			if !ok {
+				s.err = errors.New("connection closed")
+				return s.err
+			}
+			if err := s.handle(p); err != nil {
+				log.Printf("netplay: bad packet from %s: %v", p.addr, err)
+			}
+			if s.err != nil {
+				return s.err
+			}
+		default:
+			drained = true
+		}
+	}
+
+	now := time.Now()
+	if s.started {
+		for _, p := range s.peers {
+			if now.Sub(p.heard) > TIMEOUT {
+				s.err = fmt.Errorf("lost connection to player %d", p.player+1)
+				return s.err
+			}
+		}
+	} else if !s.host && now.Sub(s.peers[0].heard) > TIMEOUT {
+		s.err = fmt.Errorf("no answer from %s", s.peers[0].addr)
+		return s.err
+	}
+	if now.Sub(s.lastSent) >= HANDSHAKE_INTERVAL {
+		s.lastSent = now
+		s.handshake()
+	}
+	return nil
+}
+
+// handshake sends the join request until the host answers, and the welcome
+// until every client is playing.
+func (s *Session) handshake() {
+	if !s.host {
+		if !s.started {
+			s.send(s.peers[0], encodeJoin())
+		}
+		return
+	}
+	for _, p := range s.peers {
+		if !p.ready {
+			s.send(p, encodeWelcome(s.welcome(p)))
+		}
+	}
+}
+
+func (s *Session) welcome(p *peer) welcome {
+	return welcome{
+		player:  p.player,
+		players: s.players,
+		joined:  s.joined,
+		started: s.started,
+		seed:    s.seed,
+		delay:   s.delay,
+		rules:   s.rules,
+	}
+}
+
+func (s *Session) peerAt(addr net.Addr) *peer {
+	for _, p := range s.peers {
+		if p.addr.String() == addr.String() {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *Session) handle(pk packet) error {
+	if len(pk.data) == 0 {
+		return wire.ErrShortMessage
+	}
+	r := wire.NewReader(pk.data[1:])
+	p := s.peerAt(pk.addr)
+	if p != nil {
+		p.heard = time.Now()
+	}
+	switch messageKind(pk.data[0]) {
+	case msgJoin:
+		version, err := decodeJoin(r)
+		if err != nil || !s.host {
+			return err
+		}
+		s.join(pk.addr, p, version)
+	case msgReject:
+		if p == nil || s.host {
+			return nil
+		}
+		s.err = fmt.Errorf("the host won't let us join: %s", r.String())
+	case msgWelcome:
+		m, err := decodeWelcome(r)
+		if err != nil || p == nil || s.host {
+			return err
+		}
+		s.local = m.player
+		s.players = m.players
+		s.joined = m.joined
+		if m.started && !s.started {
+			s.seed = m.seed
+			s.delay = m.delay
+			s.rules = m.rules
+			s.start()
+		}
+	case msgInputs:
+		m, err := decodeInputs(r)
+		if err != nil || p == nil || !s.started {
+			return err
+		}
+		p.ready = true
+		//Un paquete viejo que llega tarde no deshace lo que ya se sabe que tiene
+		for i := range p.acks {
+			p.acks[i] = max(p.acks[i], m.acks[i])
+		}
+		for _, b := range m.blocks {
+			s.receive(p, b)
+		}
+		for _, c := range m.checksums {
+			s.record(s.remote, s.checksums, c.tick, c.sum)
+		}
+	default:
+		return fmt.Errorf("unknown message %d", pk.data[0])
+	}
+	return nil
+}
+
+func (s *Session) join(addr net.Addr, p *peer, version uint32) {
+	if p != nil {
+		//Ya esta adentro, se le vuelve a dar la bienvenida por si se perdio
+		s.send(p, encodeWelcome(s.welcome(p)))
+		return
+	}
+	reason := ""
+	if version != Version {
+		reason = fmt.Sprintf("the host plays version %d and you %d", Version, version)
+	} else if s.started {
+		reason = "the game already started"
+	} else if s.joined >= s.players {
+		reason = "the game is full"
+	}
+	if reason != "" {
+		s.conn.WriteTo(encodeReject(reason), addr)
+		return
+	}
+	p = &peer{addr: addr, player: s.joined, heard: time.Now()}
+	s.peers = append(s.peers, p)
+	s.joined += 1
+	log.Printf("netplay: player %d joined from %s", p.player+1, addr)
+	if s.joined == s.players {
+		s.start()
+	}
+	for _, other := range s.peers {
+		s.send(other, encodeWelcome(s.welcome(other)))
+	}
+}
+
+// receive adds the inputs of a block that follow the ones already known.
+// Blocks that leave a gap are ignored, the missing inputs come again later.
+// The host only takes a client's own inputs from it, so nobody can play for
+// someone else; clients take everyone's from the host, which relays them.
+func (s *Session) receive(from *peer, b inputBlock) {
+	if b.player >= len(s.inputs) || b.player == s.local {
+		return
+	}
+	if s.host && b.player != from.player {
+		return
+	}
+	known := s.inputs[b.player].end()
+	if b.start > known {
+		return
+	}
+	for i, in := range b.inputs {
+		if b.start+i >= known {
+			s.inputs[b.player].add(in)
+		}
+	}
+}
+
+// record keeps a checksum, ours or another machine's, and compares it with
+// the one from the other side for the same tick if that's already known.
+func (s *Session) record(sums map[int]uint32, others map[int]uint32, tick int, sum uint32) {
+	if other, ok := others[tick]; ok && other != sum && s.desync < 0 {
+		s.desync = tick
+		log.Printf("netplay: desync at tick %d", tick)
+	}
+	sums[tick] = sum
+	delete(sums, tick-CHECKSUM_HISTORY)
+	delete(others, tick-CHECKSUM_HISTORY)
+}
+
+// Advance schedules the local input and steps the world, returning whether
+// it did. In lockstep it steps once the inputs of every player for the next
+// tick are in; with rollback it may first play the last ticks again, and
+// only the events of the new tick are left in the world. The inputs known by
+// this machine are then sent to the others.
+func (s *Session) Advance(w *sim.World, local sim.Input) bool {
+	if !s.started || s.err != nil {
+		return false
+	}
+	if s.inputs[s.local].end() <= w.Tick+s.delay {
+		s.inputs[s.local].add(local)
+	}
+	stepped := false
+	if s.window > 0 {
+		stepped = s.advanceRollback(w)
+	} else if ready := s.ready(w.Tick); ready != nil {
+		w.Step(ready)
+		s.tick = w.Tick
+		s.record(s.checksums, s.remote, w.Tick, w.Checksum())
+		stepped = true
+	}
+	for _, p := range s.peers {
+		s.send(p, encodeInputs(s.outgoing(p)))
+	}
+	s.forget(w.Tick)
+	return stepped
+}
+
+// forget drops the inputs nothing can need anymore: the ones before the
+// oldest tick the world may still play, or play again, that every peer
+// already has.
+func (s *Session) forget(tick int) {
+	if s.window > 0 {
+		tick = min(tick, s.verified)
+	}
+	for i := range s.inputs {
+		oldest := tick
+		for _, p := range s.peers {
+			if i != p.player {
+				oldest = min(oldest, p.acks[i])
+			}
+		}
+		s.inputs[i].forget(oldest)
+	}
+}
+
+// ready returns the inputs for the tick if all of them are known.
+func (s *Session) ready(tick int) []sim.Input {
+	inputs := make([]sim.Input, s.players)
+	for i := range inputs {
+		if s.inputs[i].end() <= tick {
+			return nil
+		}
+		inputs[i] = s.inputs[i].at(tick)
+	}
+	return inputs
+}
+
+// outgoing builds the packet for a peer with everything it's missing.
+func (s *Session) outgoing(p *peer) inputs {
+	m := inputs{}
+	for i := range s.inputs {
+		m.acks[i] = s.inputs[i].end()
+		if i == p.player {
+			continue
+		}
+		start := min(p.acks[i], s.inputs[i].end())
+		end := min(s.inputs[i].end(), start+MAX_INPUTS_PER_BLOCK)
+		if start < end {
+			m.blocks = append(m.blocks, inputBlock{player: i, start: start, inputs: s.inputs[i].between(start, end)})
+		}
+	}
+	for tick := s.tick - CHECKSUMS_PER_PACKET + 1; tick <= s.tick; tick++ {
+		if sum, ok := s.checksums[tick]; ok {
+			m.checksums = append(m.checksums, checksum{tick: tick, sum: sum})
+		}
+	}
+	return m
+}
+
+func (s *Session) send(p *peer, data []byte) {
+	if _, err := s.conn.WriteTo(data, p.addr); err != nil {
+		log.Println("netplay:", err)
+	}
+}
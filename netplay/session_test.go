@@ -0,0 +1,119 @@
+package netplay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+const TEST_TICKS = 300
+
+// play hosts a game for the rules on localhost with a client joining for
+// every other player, and plays them all until every machine confirmed tick
+// TEST_TICKS, failing on any error or desync.
+func play(t *testing.T, rules sim.Rules, options Options) {
+	t.Helper()
+	host, err := Host("127.0.0.1:0", rules, 42, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := []*Session{host}
+	for range rules.Players - 1 {
+		client, err := Join(host.Addr().String(), options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions = append(sessions, client)
+	}
+	defer func() {
+		for _, s := range sessions {
+			s.Close()
+		}
+	}()
+
+	worlds := make([]*sim.World, len(sessions))
+	deadline := time.Now().Add(20 * time.Second)
+	for {
+		done := true
+		for i, s := range sessions {
+			if err := s.Poll(); err != nil {
+				t.Fatalf("player %d: %v", i+1, err)
+			}
+			if tick, ok := s.Desync(); ok {
+				t.Fatalf("player %d: desync at tick %d", i+1, tick)
+			}
+			if !s.Started() {
+				done = false
+				continue
+			}
+			if worlds[i] == nil {
+				worlds[i] = s.NewWorld()
+			}
+			w := worlds[i]
+			//Cada jugador hace algo distinto para que las entradas importen
+			in := sim.InputThrust | sim.Input(1+s.Local()%2)
+			if (w.Tick/7+s.Local())%3 == 0 {
+				in |= sim.InputFire
+			}
+			s.Advance(w, in)
+			if _, ok := s.checksums[TEST_TICKS]; !ok {
+				done = false
+			}
+		}
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the game didn't get to tick %d in time", TEST_TICKS)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	//Los ultimos checksums pueden no haberse cruzado todavia, se comparan aca
+	for i, s := range sessions {
+		if s.checksums[TEST_TICKS] != host.checksums[TEST_TICKS] {
+			t.Errorf("player %d played tick %d differently than the host", i+1, TEST_TICKS)
+		}
+		//Las entradas que ya nadie necesita se olvidan en vez de juntarse para siempre
+		for j := range s.inputs {
+			if kept := len(s.inputs[j].inputs); kept > TEST_TICKS/2 {
+				t.Errorf("player %d still keeps %d inputs of player %d", i+1, kept, j+1)
+			}
+		}
+	}
+}
+
+func TestLockstepOnLoopback(t *testing.T) {
+	options := DefaultOptions()
+	options.Rollback = 0
+	play(t, sim.Rules{Mode: sim.ModeCoop, Players: 2}, options)
+}
+
+func TestRollbackOnLoopback(t *testing.T) {
+	play(t, sim.Rules{Mode: sim.ModeCoop, Players: 3, FriendlyFire: true}, DefaultOptions())
+}
+
+func TestRollbackTakingTurns(t *testing.T) {
+	play(t, sim.Rules{Mode: sim.ModeTurns, Players: 3}, DefaultOptions())
+}
+
+func TestRollbackOverABadNetwork(t *testing.T) {
+	options := DefaultOptions()
+	options.Conditions = Conditions{Latency: 20 * time.Millisecond, Jitter: 10 * time.Millisecond, Loss: 0.1}
+	play(t, sim.Rules{Mode: sim.ModeDuel, Players: 2, DuelRounds: 3}, options)
+}
+
+func TestHostTakesOnlyTheSendersInputs(t *testing.T) {
+	s := &Session{host: true, players: 3}
+	s.start()
+	client := &peer{player: 1}
+	s.receive(client, inputBlock{player: 2, start: 0, inputs: []sim.Input{sim.InputFire}})
+	if s.inputs[2].end() != 0 {
+		t.Error("the host took inputs for player 3 from player 2")
+	}
+	s.receive(client, inputBlock{player: 1, start: 0, inputs: []sim.Input{sim.InputFire}})
+	if s.inputs[1].end() != 1 {
+		t.Errorf("the host has %d inputs of player 2, want 1", s.inputs[1].end())
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net"
+	"os"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/rodolfato/asteroids/discovery"
+	"github.com/rodolfato/asteroids/netplay"
+	"github.com/rodolfato/asteroids/server"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// parseMode reads the name of a mode that can be played over the network.
+func parseMode(name string) (sim.Mode, error) {
+	switch name {
+	case "coop", "co-op":
+		return sim.ModeCoop, nil
+	case "versus", "duel":
+		return sim.ModeDuel, nil
+	}
+	return 0, fmt.Errorf("unknown mode %q, it can be coop or versus", name)
+}
+
+// networkRules are the rules from the settings for a game over the network.
+func (g *GameState) networkRules(mode sim.Mode, players int) sim.Rules {
+	if mode == sim.ModeDuel {
+		players = 2
+	}
+	return sim.Rules{
+		Mode:          mode,
+		Players:       players,
+		FriendlyFire:  g.settings.FriendlyFire,
+		DuelRounds:    g.settings.DuelRounds,
+		DuelAsteroids: g.settings.DuelAsteroids,
+	}
+}
+
+// hostGame waits in the lobby for other machines to join a game with the
+// rules from the settings.
+func (g *GameState) hostGame(addr string, mode sim.Mode, players int, options netplay.Options) error {
+	rules := g.networkRules(mode, players)
+	session, err := netplay.Host(addr, rules, rand.Uint64(), options)
+	if err != nil {
+		return err
+	}
+	g.net = session
+	g.scene = SCENE_LOBBY
+	if session.Started() {
+		return nil
+	}
+	//Se anuncia en la red local para que se pueda unir sin escribir la direccion
+	name, _ := os.Hostname()
+	announcer, err := discovery.Announce(discovery.Game{
+		Name:    name,
+		Mode:    rules.Mode,
+		Joined:  1,
+		Players: rules.Players,
+		Version: netplay.Version,
+	}, session.Addr().(*net.UDPAddr).Port)
+	if err != nil {
+		log.Println("Can't announce the game on the local network:", err)
+		return nil
+	}
+	g.announcer = announcer
+	return nil
+}
+
+func (g *GameState) joinGame(addr string, options netplay.Options) error {
+	session, err := netplay.Join(addr, options)
+	if err != nil {
+		return err
+	}
+	g.net = session
+	g.scene = SCENE_LOBBY
+	return nil
+}
+
+// connectServer joins the named match on a server, which creates it with the
+// rules from the settings if nobody did yet.
+func (g *GameState) connectServer(network, addr, match string, mode sim.Mode, players int) error {
+	client, err := server.Connect(network, addr, match, g.networkRules(mode, players))
+	if err != nil {
+		return err
+	}
+	g.remote = client
+	g.scene = SCENE_LOBBY
+	return nil
+}
+
+// leaveNetwork ends the networked game and goes back to the title screen,
+// showing why.
+func (g *GameState) leaveNetwork(err error) {
+	log.Println("Network game over:", err)
+	g.message = err.Error()
+	g.stopAnnouncing()
+	if g.net != nil {
+		g.net.Close()
+		g.net = nil
+	}
+	if g.remote != nil {
+		g.remote.Close()
+		g.remote = nil
+	}
+	g.newTitleWorld()
+	g.scene = SCENE_TITLE
+}
+
+func (g *GameState) updateLobby() {
+	if rl.IsKeyPressed(rl.KeyBackspace) {
+		g.leaveNetwork(errors.New("left the lobby"))
+		return
+	}
+	if g.remote != nil {
+		if err := g.remote.Poll(); err != nil {
+			g.leaveNetwork(err)
+			return
+		}
+		if snapshot, fresh := g.remote.Latest(); fresh {
+			g.world = snapshot.World(g.remote.Rules())
+			g.scene = SCENE_PLAYING
+			g.handleEvents()
+		}
+		return
+	}
+	if err := g.net.Poll(); err != nil {
+		g.leaveNetwork(err)
+		return
+	}
+	if g.announcer != nil {
+		joined, _ := g.net.Players()
+		g.announcer.Update(joined)
+	}
+	if g.net.Started() {
+		g.stopAnnouncing()
+		g.world = g.net.NewWorld()
+		g.scene = SCENE_PLAYING
+		g.handleEvents()
+	}
+}
+
+func (g *GameState) stopAnnouncing() {
+	if g.announcer != nil {
+		g.announcer.Close()
+		g.announcer = nil
+	}
+}
+
+// advanceNetwork steps the world when the inputs of every player are in, or
+// when they can be guessed with rollback. It returns whether it did.
+func (g *GameState) advanceNetwork() bool {
+	if err := g.net.Poll(); err != nil {
+		g.leaveNetwork(err)
+		return false
+	}
+	return g.net.Advance(g.world, g.pilotInput(g.net.Local()))
+}
+
+// advanceRemote sends the local controls to the server and shows the latest
+// snapshot of the world it sent back. It returns whether there was a new one.
+func (g *GameState) advanceRemote() bool {
+	if err := g.remote.Poll(); err != nil {
+		g.leaveNetwork(err)
+		return false
+	}
+	g.remote.Send(g.pilotInput(g.remote.Local()))
+	snapshot, fresh := g.remote.Latest()
+	if !fresh {
+		return false
+	}
+	g.world = snapshot.World(g.remote.Rules())
+	return true
+}
+
+// publish streams the world to the browsers watching, if any.
+func (g *GameState) publish() {
+	if g.spectators != nil {
+		g.spectators.Publish("game", g.world)
+	}
+}
+
+func (g *GameState) drawLobby() {
+	title, status := "Joining the game", "Waiting for the host"
+	joined, players := 0, 0
+	if g.remote != nil {
+		title, status = "Joining the match on the server", "Waiting for the server"
+		joined, players = g.remote.Players()
+	} else {
+		if g.net.IsHost() {
+			title = fmt.Sprintf("Hosting on %s", g.net.Addr())
+		}
+		joined, players = g.net.Players()
+	}
+	if players > 0 {
+		status = fmt.Sprintf("Waiting for players %d/%d", joined, players)
+	}
+	rl.DrawTextPro(rl.GetFontDefault(), title, rl.Vector2{
+		X: SCREEN_SIZE_X / 2,
+		Y: SCREEN_SIZE_Y / 2,
+	}, rl.Vector2Scale(rl.MeasureTextEx(rl.GetFontDefault(), title, 50.0, 1.0), 0.5), 0.0, 50.0, 1.0, rl.White)
+	rl.DrawTextPro(rl.GetFontDefault(), status, rl.Vector2{
+		X: SCREEN_SIZE_X / 2,
+		Y: SCREEN_SIZE_Y/2 + 80,
+	}, rl.Vector2Scale(rl.MeasureTextEx(rl.GetFontDefault(), status, 30.0, 1.0), 0.5), 0.0, 30.0, 1.0, rl.Gray)
+	rl.DrawTextPro(rl.GetFontDefault(), "Backspace to leave", rl.Vector2{
+		X: SCREEN_SIZE_X / 2,
+		Y: SCREEN_SIZE_Y - 60,
+	}, rl.Vector2Scale(rl.MeasureTextEx(rl.GetFontDefault(), "Backspace to leave", 15.0, 1.0), 0.5), 0.0, 15.0, 1.0, rl.Gray)
+}
+
+// drawNetworkStatus warns when the machines stopped simulating the same game.
+func (g *GameState) drawNetworkStatus() {
+	if tick, ok := g.net.Desync(); ok {
+		rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Out of sync since tick %d", tick), rl.Vector2{
+			X: 10,
+			Y: SCREEN_SIZE_Y - 80,
+		}, 20.0, 1.0, rl.Red)
+	}
+}
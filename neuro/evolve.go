@@ -0,0 +1,244 @@
+package neuro
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/rodolfato/asteroids/gym"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+const (
+	DefaultPopulation   = 64
+	DefaultGames        = 4
+	DefaultMaxSteps     = 2000
+	DefaultMutationRate = 0.1
+	DefaultMutationSize = 0.3
+	// DefaultSurvival is the fitness every second alive is worth, next to
+	// the score: about a small asteroid every few seconds.
+	DefaultSurvival = 20
+	// TOURNAMENT is how many genomes compete to be a parent.
+	TOURNAMENT = 3
+)
+
+// Options are how a population evolves.
+type Options struct {
+	// Env is the game genomes play and how they see it. Its Player is the
+	// ship they fly.
+	Env gym.Config
+	// Hidden are the sizes of the hidden layers of new networks.
+	Hidden     []int
+	Population int
+	// Games is how many games every genome plays per generation, all of them
+	// with the same seeds so genomes are compared on the same games.
+	Games int
+	// Workers is how many games are played at the same time.
+	Workers int
+	// Elite is how many of the best genomes go on to the next generation
+	// unchanged.
+	Elite int
+	// MutationRate is the chance of every weight of a child changing, and
+	// MutationSize the standard deviation of the change.
+	MutationRate float64
+	MutationSize float64
+	// Survival is the fitness every second alive is worth. The rest of the
+	// fitness is the score.
+	Survival float64
+	// Seed makes the whole run the same every time. Generation n plays the
+	// same games and breeds the same way whenever it's reached, so a run can
+	// be picked up from a checkpoint.
+	Seed uint64
+}
+
+func DefaultOptions() Options {
+	env := gym.DefaultConfig()
+	env.MaxSteps = DefaultMaxSteps
+	return Options{
+		Env:          env,
+		Hidden:       []int{16},
+		Population:   DefaultPopulation,
+		Games:        DefaultGames,
+		Workers:      runtime.NumCPU(),
+		Elite:        DefaultPopulation / 10,
+		MutationRate: DefaultMutationRate,
+		MutationSize: DefaultMutationSize,
+		Survival:     DefaultSurvival,
+	}
+}
+
+// Population is a generation of genomes, sorted best first once evaluated.
+// Best is the best genome seen in any generation so far.
+type Population struct {
+	Generation int       `json:"generation"`
+	Genomes    []*Genome `json:"genomes"`
+	Best       *Genome   `json:"best,omitempty"`
+}
+
+// NewPopulation makes the first generation, with random networks.
+func NewPopulation(options Options) *Population {
+	rng := options.rng(0, false)
+	layers := append([]int{options.Env.Size()}, options.Hidden...)
+	layers = append(layers, len(CONTROLS))
+	p := &Population{}
+	for range options.Population {
+		p.Genomes = append(p.Genomes, options.genome(NewNetwork(layers, rng), 0))
+	}
+	return p
+}
+
+func (o Options) genome(n Network, generation int) *Genome {
+	return &Genome{
+		Network:    n,
+		Encoding:   o.Env.Encoding.String(),
+		Rays:       o.Env.Rays,
+		Entities:   o.Env.Entities,
+		FrameSkip:  o.Env.FrameSkip,
+		Generation: generation,
+	}
+}
+
+// rng is the generator for a generation, the same every time it's made.
+// Breeding and the seeds of the games come from two different ones.
+func (o Options) rng(generation int, games bool) *rand.Rand {
+	stream := uint64(generation) << 1
+	if games {
+		stream |= 1
+	}
+	return rand.New(rand.NewPCG(o.Seed, stream))
+}
+
+// LoadPopulation reads a checkpoint, checking it can go on with the options.
+func LoadPopulation(path string, options Options) (*Population, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Population{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(p.Genomes) == 0 {
+		return nil, fmt.Errorf("%s: the population is empty", path)
+	}
+	for _, g := range p.Genomes {
+		if err := g.check(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if g.Layers[0] != options.Env.Size() || g.Encoding != options.Env.Encoding.String() {
+			return nil, fmt.Errorf("%s: the genomes see the world differently than the options say", path)
+		}
+	}
+	return p, nil
+}
+
+func (p *Population) Save(path string) error {
+	return writeJSON(path, p)
+}
+
+// Evaluate plays the games of the generation with every genome, sets their
+// fitness and sorts them, best first.
+func (p *Population) Evaluate(options Options) error {
+	if _, err := gym.New(options.Env); err != nil {
+		return err
+	}
+	rng := options.rng(p.Generation, true)
+	seeds := make([]uint64, options.Games)
+	for i := range seeds {
+		seeds[i] = rng.Uint64()
+	}
+
+	jobs := make(chan *Genome)
+	var wg sync.WaitGroup
+	for range max(1, options.Workers) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			env, _ := gym.New(options.Env)
+			for g := range jobs {
+				g.Fitness = options.fitness(env, g, seeds)
+			}
+		}()
+	}
+	for _, g := range p.Genomes {
+		jobs <- g
+	}
+	close(jobs)
+	wg.Wait()
+
+	slices.SortStableFunc(p.Genomes, func(a, b *Genome) int {
+		return cmp.Compare(b.Fitness, a.Fitness)
+	})
+	if p.Best == nil || p.Genomes[0].Fitness > p.Best.Fitness {
+		best := *p.Genomes[0]
+		best.Weights = slices.Clone(best.Weights)
+		p.Best = &best
+	}
+	return nil
+}
+
+// fitness is the mean over the games of the score and the time alive.
+func (o Options) fitness(env *gym.Env, g *Genome, seeds []uint64) float64 {
+	total := 0.0
+	for _, seed := range seeds {
+		observation := env.Reset(seed)
+		alive := 0
+		for {
+			var done bool
+			var info gym.Info
+			if observation.Alive {
+				alive += 1
+			}
+			observation, _, done, info = env.Step(gym.Action(g.Act(observation, o.Env)))
+			if done {
+				seconds := float64(alive*o.Env.FrameSkip) / sim.TICK_RATE
+				total += float64(info.Score) + o.Survival*seconds
+				break
+			}
+		}
+	}
+	return total / float64(len(seeds))
+}
+
+// Next breeds the next generation out of an evaluated one: the elite go on
+// as they are, and the rest are children of parents picked by tournament,
+// every weight taken from either parent and then maybe mutated.
+func (p *Population) Next(options Options) *Population {
+	rng := options.rng(p.Generation+1, false)
+	next := &Population{Generation: p.Generation + 1, Best: p.Best}
+	for _, g := range p.Genomes[:min(options.Elite, len(p.Genomes))] {
+		next.Genomes = append(next.Genomes, g)
+	}
+	for len(next.Genomes) < options.Population {
+		a, b := p.pick(rng), p.pick(rng)
+		child := Network{Layers: a.Layers, Weights: make([]float32, len(a.Weights))}
+		for i := range child.Weights {
+			child.Weights[i] = a.Weights[i]
+			if rng.IntN(2) == 0 {
+				child.Weights[i] = b.Weights[i]
+			}
+			if rng.Float64() < options.MutationRate {
+				child.Weights[i] += float32(rng.NormFloat64() * options.MutationSize)
+			}
+		}
+		next.Genomes = append(next.Genomes, options.genome(child, next.Generation))
+	}
+	return next
+}
+
+// pick runs a tournament: the best of a few genomes taken at random.
+func (p *Population) pick(rng *rand.Rand) *Genome {
+	best := p.Genomes[rng.IntN(len(p.Genomes))]
+	for range TOURNAMENT - 1 {
+		g := p.Genomes[rng.IntN(len(p.Genomes))]
+		if g.Fitness > best.Fitness {
+			best = g
+		}
+	}
+	return best
+}
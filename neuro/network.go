@@ -0,0 +1,67 @@
+// Package neuro evolves small neural networks that fly a ship. A genome is
+// the weights of a feed-forward network that takes in what the ship sees,
+// as the gym package describes it, and puts out which controls to hold.
+// Populations of genomes get better by playing seeded games in parallel,
+// keeping the ones that score and survive the most, and breeding and
+// mutating them into the next generation.
+//
+// Everything is plain Go, with no outside libraries: the networks are a few
+// thousand weights and running them costs less than the game does.
+package neuro
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// Network is a feed-forward network. Layers are the sizes of every layer,
+// from the inputs to the outputs, and Weights go layer by layer, each output
+// of a layer taking a weight for every input and then its bias. Every layer
+// goes through tanh.
+type Network struct {
+	Layers  []int     `json:"layers"`
+	Weights []float32 `json:"weights"`
+}
+
+// weightCount is how many weights a network with the given layers has.
+func weightCount(layers []int) int {
+	n := 0
+	for i := 1; i < len(layers); i++ {
+		n += (layers[i-1] + 1) * layers[i]
+	}
+	return n
+}
+
+// NewNetwork makes a network with random weights, small enough that the
+// signal neither dies out nor saturates through the layers.
+func NewNetwork(layers []int, rng *rand.Rand) Network {
+	n := Network{Layers: layers, Weights: make([]float32, 0, weightCount(layers))}
+	for i := 1; i < len(layers); i++ {
+		scale := 1 / math.Sqrt(float64(layers[i-1]+1))
+		for range (layers[i-1] + 1) * layers[i] {
+			n.Weights = append(n.Weights, float32(rng.NormFloat64()*scale))
+		}
+	}
+	return n
+}
+
+// Forward runs the network on the inputs.
+func (n Network) Forward(inputs []float32) []float32 {
+	values := inputs
+	w := 0
+	for i := 1; i < len(n.Layers); i++ {
+		outputs := make([]float32, n.Layers[i])
+		for j := range outputs {
+			sum := float32(0)
+			for _, v := range values {
+				sum += n.Weights[w] * v
+				w += 1
+			}
+			sum += n.Weights[w]
+			w += 1
+			outputs[j] = float32(math.Tanh(float64(sum)))
+		}
+		values = outputs
+	}
+	return values
+}
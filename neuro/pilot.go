@@ -0,0 +1,148 @@
+package neuro
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rodolfato/asteroids/gym"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// CONTROLS are what the outputs of a network hold down, in order, when
+// they're above zero.
+var CONTROLS = []sim.Input{sim.InputLeft, sim.InputRight, sim.InputThrust, sim.InputReverse, sim.InputFire}
+
+// Genome is a network together with how it sees the world, which has to be
+// the same as when it was trained.
+type Genome struct {
+	Network
+	Encoding   string  `json:"encoding"`
+	Rays       int     `json:"rays,omitempty"`
+	Entities   int     `json:"entities,omitempty"`
+	FrameSkip  int     `json:"frame_skip"`
+	Fitness    float64 `json:"fitness"`
+	Generation int     `json:"generation"`
+}
+
+// config is the part of an environment's config the genome is about.
+func (g *Genome) config(player int) (gym.Config, error) {
+	config := gym.DefaultConfig()
+	encoding, err := gym.ParseEncoding(g.Encoding)
+	if err != nil {
+		return config, err
+	}
+	config.Player = player
+	config.Encoding = encoding
+	config.Rays = g.Rays
+	config.Entities = g.Entities
+	config.FrameSkip = max(1, g.FrameSkip)
+	return config, nil
+}
+
+// check makes sure the network fits what the genome sees and the controls.
+func (g *Genome) check() error {
+	config, err := g.config(0)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(g.Layers) < 2:
+		return fmt.Errorf("a network needs at least 2 layers, not %d", len(g.Layers))
+	case g.Layers[0] != config.Size():
+		return fmt.Errorf("the network takes %d inputs but the observations have %d", g.Layers[0], config.Size())
+	case g.Layers[len(g.Layers)-1] != len(CONTROLS):
+		return fmt.Errorf("the network has %d outputs but there are %d controls", g.Layers[len(g.Layers)-1], len(CONTROLS))
+	case len(g.Weights) != weightCount(g.Layers):
+		return fmt.Errorf("the network needs %d weights, not %d", weightCount(g.Layers), len(g.Weights))
+	}
+	return nil
+}
+
+// Act decides what to hold down after seeing the observation.
+func (g *Genome) Act(o gym.Observation, config gym.Config) sim.Input {
+	in := sim.Input(0)
+	for i, out := range g.Forward(o.Vector(config)) {
+		if out > 0 {
+			in |= CONTROLS[i]
+		}
+	}
+	return in
+}
+
+func LoadGenome(path string) (*Genome, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	g := &Genome{}
+	if err := json.Unmarshal(data, g); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := g.check(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return g, nil
+}
+
+func (g *Genome) Save(path string) error {
+	return writeJSON(path, g)
+}
+
+// writeJSON writes the file whole or not at all, so a checkpoint being
+// written when the trainer is stopped doesn't replace a good one.
+func writeJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	temporary := path + ".tmp"
+	if err := os.WriteFile(temporary, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temporary, path)
+}
+
+// Pilot flies the ship of a player with a genome, deciding again every
+// FrameSkip ticks like it did when it was trained. Like in the gym it fires
+// only when it decides to, not on every tick it holds fire down.
+type Pilot struct {
+	// Restart presses start after a round or a game over.
+	Restart bool
+	genome  *Genome
+	config  gym.Config
+	held    sim.Input
+	ticks   int
+}
+
+func NewPilot(player int, genome *Genome) (*Pilot, error) {
+	if err := genome.check(); err != nil {
+		return nil, err
+	}
+	config, err := genome.config(player)
+	if err != nil {
+		return nil, err
+	}
+	return &Pilot{genome: genome, config: config}, nil
+}
+
+// Input is what the pilot does this tick, looking at the world after the
+// last one.
+func (p *Pilot) Input(w *sim.World) sim.Input {
+	if w.Phase != sim.PhasePlaying {
+		p.ticks = 0
+		if p.Restart && w.Tick%sim.TICK_RATE == 0 {
+			return sim.InputStart
+		}
+		return 0
+	}
+	decided := p.ticks%p.config.FrameSkip == 0
+	if decided {
+		p.held = p.genome.Act(p.config.Observe(w), p.config)
+	}
+	p.ticks += 1
+	if !decided {
+		return p.held &^ sim.InputFire
+	}
+	return p.held
+}
@@ -0,0 +1,44 @@
+package neuro
+
+import (
+	"testing"
+
+	"github.com/rodolfato/asteroids/gym"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// firing is a genome whose network holds fire down whatever it sees.
+func firing() *Genome {
+	config := gym.DefaultConfig()
+	layers := []int{config.Size(), len(CONTROLS)}
+	n := Network{Layers: layers, Weights: make([]float32, weightCount(layers))}
+	//El ultimo peso de cada salida es su sesgo
+	n.Weights[(config.Size()+1)*len(CONTROLS)-1] = 1
+	return &Genome{
+		Network:   n,
+		Encoding:  config.Encoding.String(),
+		Rays:      config.Rays,
+		Entities:  config.Entities,
+		FrameSkip: config.FrameSkip,
+	}
+}
+
+func TestPilotFiresOncePerDecision(t *testing.T) {
+	g := firing()
+	p, err := NewPilot(0, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := sim.NewWorld(1, sim.Rules{Mode: sim.ModeCoop, Players: 1})
+	shots := 0
+	for range 5 * g.FrameSkip {
+		in := p.Input(w)
+		if in.Has(sim.InputFire) {
+			shots += 1
+		}
+		w.Step([]sim.Input{in})
+	}
+	if shots != 5 {
+		t.Errorf("the pilot fired on %d ticks out of %d, want once every %d", shots, 5*g.FrameSkip, g.FrameSkip)
+	}
+}
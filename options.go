@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+const (
+	OPTION_MASTER_VOLUME = iota
+	OPTION_SFX_VOLUME
+	OPTION_MUSIC_VOLUME
+	OPTION_MUTE
+	OPTION_COOP_SHIPS
+	OPTION_FRIENDLY_FIRE
+	OPTION_DUEL_ROUNDS
+	OPTION_DUEL_ASTEROIDS
+	OPTION_BACK
+	OPTION_COUNT
+)
+
+const (
+	VOLUME_STEP     = 0.1
+	MAX_DUEL_ROUNDS = 9
+)
+
+// OptionsMenu is the screen where the volumes and the co-op game are set up.
+// It goes back to the scene it was opened from.
+type OptionsMenu struct {
+	selected int
+	previous Scene
+}
+
+func (g *GameState) openOptions() {
+	g.options = OptionsMenu{previous: g.scene}
+	g.scene = SCENE_OPTIONS
+}
+
+func (g *GameState) closeOptions() {
+	if err := g.settings.save(); err != nil {
+		log.Println("Can't save the settings:", err)
+	}
+	g.scene = g.options.previous
+}
+
+func (g *GameState) updateOptions() {
+	menu := &g.options
+	if rl.IsKeyPressed(rl.KeyUp) || rl.IsKeyPressed(rl.KeyW) {
+		menu.selected = (menu.selected + OPTION_COUNT - 1) % OPTION_COUNT
+	}
+	if rl.IsKeyPressed(rl.KeyDown) || rl.IsKeyPressed(rl.KeyS) {
+		menu.selected = (menu.selected + 1) % OPTION_COUNT
+	}
+
+	step := float32(0)
+	if rl.IsKeyPressed(rl.KeyLeft) || rl.IsKeyPressed(rl.KeyA) {
+		step = -VOLUME_STEP
+	}
+	if rl.IsKeyPressed(rl.KeyRight) || rl.IsKeyPressed(rl.KeyD) {
+		step = VOLUME_STEP
+	}
+	switch menu.selected {
+	case OPTION_MASTER_VOLUME:
+		g.settings.MasterVolume = clampVolume(g.settings.MasterVolume + step)
+	case OPTION_SFX_VOLUME:
+		g.settings.SFXVolume = clampVolume(g.settings.SFXVolume + step)
+	case OPTION_MUSIC_VOLUME:
+		g.settings.MusicVolume = clampVolume(g.settings.MusicVolume + step)
+	case OPTION_MUTE:
+		if step != 0 || rl.IsKeyPressed(rl.KeyEnter) {
+			g.settings.Muted = !g.settings.Muted
+		}
+	case OPTION_COOP_SHIPS:
+		g.settings.CoopShips = max(2, min(sim.MAX_SHIPS, g.settings.CoopShips+int(step/VOLUME_STEP)))
+	case OPTION_FRIENDLY_FIRE:
+		if step != 0 || rl.IsKeyPressed(rl.KeyEnter) {
+			g.settings.FriendlyFire = !g.settings.FriendlyFire
+		}
+	case OPTION_DUEL_ROUNDS:
+		g.settings.DuelRounds = max(1, min(MAX_DUEL_ROUNDS, g.settings.DuelRounds+2*int(step/VOLUME_STEP)))
+	case OPTION_DUEL_ASTEROIDS:
+		if step != 0 || rl.IsKeyPressed(rl.KeyEnter) {
+			g.settings.DuelAsteroids = !g.settings.DuelAsteroids
+		}
+	case OPTION_BACK:
+		if rl.IsKeyPressed(rl.KeyEnter) {
+			g.closeOptions()
+		}
+	}
+	if rl.IsKeyPressed(rl.KeyBackspace) {
+		g.closeOptions()
+	}
+	g.audio.applySettings(g.settings)
+}
+
+// clampVolume keeps the volume between 0 and 1, rounded to the menu steps.
+func clampVolume(volume float32) float32 {
+	steps := float32(int(volume/VOLUME_STEP + 0.5))
+	return max(0, min(1, steps*VOLUME_STEP))
+}
+
+func (g *GameState) drawOptions() {
+	labels := []string{
+		fmt.Sprintf("Master volume  < %3.0f%% >", g.settings.MasterVolume*100),
+		fmt.Sprintf("Effects volume < %3.0f%% >", g.settings.SFXVolume*100),
+		fmt.Sprintf("Music volume   < %3.0f%% >", g.settings.MusicVolume*100),
+		fmt.Sprintf("Mute           < %v >", g.settings.Muted),
+		fmt.Sprintf("Co-op ships    < %d >", g.settings.CoopShips),
+		fmt.Sprintf("Friendly fire  < %v >", g.settings.FriendlyFire),
+		fmt.Sprintf("Duel rounds    < best of %d >", g.settings.DuelRounds),
+		fmt.Sprintf("Duel asteroids < %v >", g.settings.DuelAsteroids),
+		"Back",
+	}
+	rl.DrawTextPro(rl.GetFontDefault(), "Options", rl.Vector2{
+		X: SCREEN_SIZE_X / 2,
+		Y: 150,
+	}, rl.Vector2Scale(rl.MeasureTextEx(rl.GetFontDefault(), "Options", 80.0, 1.0), 0.5), 0.0, 80.0, 1.0, rl.White)
+	for i, label := range labels {
+		color := rl.Gray
+		if i == g.options.selected {
+			color = rl.White
+		}
+		rl.DrawTextEx(rl.GetFontDefault(), label, rl.Vector2{
+			X: SCREEN_SIZE_X/2 - 200,
+			Y: 240 + 45*float32(i),
+		}, 30.0, 2.0, color)
+	}
+	rl.DrawTextEx(rl.GetFontDefault(), "Up/Down to choose, Left/Right to change, Backspace to go back", rl.Vector2{
+		X: SCREEN_SIZE_X/2 - 300,
+		Y: SCREEN_SIZE_Y - 60,
+	}, 15.0, 1.0, rl.Gray)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+const (
+	MAX_PARTICLES = 1024
+)
+
+type Particle struct {
+	pos   rl.Vector2
+	vel   rl.Vector2
+	ttl   int
+	life  int
+	drag  float32
+	color rl.Color
+	alive bool
+}
+
+// Emitter describes a kind of burst of particles. Every particle leaves in a
+// random direction inside a cone of the given spread around the emit angle.
+type Emitter struct {
+	count    int
+	spread   float32
+	speedMin float32
+	speedMax float32
+	ttlMin   int
+	ttlMax   int
+	drag     float32
+	color    rl.Color
+}
+
+var (
+	SPARKS_EMITTER = Emitter{
+		count:    24,
+		spread:   math.Pi * 2,
+		speedMin: 0.5,
+		speedMax: 3.5,
+		ttlMin:   20,
+		ttlMax:   45,
+		drag:     0.97,
+		color:    rl.White,
+	}
+	IMPACT_EMITTER = Emitter{
+		count:    6,
+		spread:   math.Pi * 0.6,
+		speedMin: 1.0,
+		speedMax: 4.0,
+		ttlMin:   6,
+		ttlMax:   14,
+		drag:     0.9,
+		color:    rl.White,
+	}
+	DEBRIS_EMITTER = Emitter{
+		count:    40,
+		spread:   math.Pi * 2,
+		speedMin: 0.2,
+		speedMax: 2.5,
+		ttlMin:   30,
+		ttlMax:   sim.SHIP_TIME_IN_PIECES * 10,
+		drag:     0.99,
+		color:    rl.White,
+	}
+	EXHAUST_EMITTER = Emitter{
+		count:    2,
+		spread:   math.Pi * 0.25,
+		speedMin: 1.5,
+		speedMax: 3.0,
+		ttlMin:   6,
+		ttlMax:   12,
+		drag:     0.92,
+		color:    rl.Orange,
+	}
+)
+
+// ParticleSystem keeps every particle in a fixed pool. When the pool is full
+// new particles replace the oldest ones.
+type ParticleSystem struct {
+	particles []Particle
+	next      int
+}
+
+func newParticleSystem(size int) *ParticleSystem {
+	return &ParticleSystem{
+		particles: make([]Particle, size),
+	}
+}
+
+func (ps *ParticleSystem) emit(e Emitter, pos rl.Vector2, vel rl.Vector2, angle float32) {
+	for range e.count {
+		direction := getDirection(angle + e.spread*(rand.Float32()-0.5))
+		speed := e.speedMin + rand.Float32()*(e.speedMax-e.speedMin)
+		ttl := e.ttlMin + rand.IntN(e.ttlMax-e.ttlMin+1)
+
+		ps.particles[ps.next] = Particle{
+			pos:   pos,
+			vel:   rl.Vector2Add(vel, rl.Vector2Scale(direction, speed)),
+			ttl:   ttl,
+			life:  ttl,
+			drag:  e.drag,
+			color: e.color,
+			alive: true,
+		}
+		ps.next = (ps.next + 1) % len(ps.particles)
+	}
+}
+
+func (ps *ParticleSystem) update() {
+	for i := range ps.particles {
+		p := &ps.particles[i]
+		if !p.alive {
+			continue
+		}
+		p.pos = rl.Vector2Add(p.pos, p.vel)
+		sim.ResetPosition((*sim.Vector2)(&p.pos))
+		p.vel = rl.Vector2Scale(p.vel, p.drag)
+		p.ttl -= 1
+		if p.ttl < 1 {
+			p.alive = false
+		}
+	}
+}
+
+func (ps *ParticleSystem) draw() {
+	for _, p := range ps.particles {
+		if !p.alive {
+			continue
+		}
+		color := rl.Fade(p.color, float32(p.ttl)/float32(p.life))
+		//Las particulas rapidas se dibujan como una estela
+		if rl.Vector2Length(p.vel) > 1 {
+			screen.Line(sim.Vector2(p.pos), sim.Vector2(rl.Vector2Subtract(p.pos, p.vel)), color)
+		} else {
+			screen.Pixel(sim.Vector2(p.pos), color)
+		}
+	}
+}
+
+func (ps *ParticleSystem) clear() {
+	for i := range ps.particles {
+		ps.particles[i].alive = false
+	}
+}
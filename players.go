@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/rodolfato/asteroids/render"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+const (
+	TURN_BANNER_TIME = 120
+)
+
+// Controls are the keys that fly one ship.
+type Controls struct {
+	left    int32
+	right   int32
+	thrust  int32
+	reverse int32
+	fire    int32
+}
+
+// CONTROLS has a set of keys for each of the ships of a co-op game. When
+// players take turns they all use the first one.
+var CONTROLS = []Controls{
+	{left: rl.KeyA, right: rl.KeyD, thrust: rl.KeyW, reverse: rl.KeyS, fire: rl.KeySpace},
+	{left: rl.KeyLeft, right: rl.KeyRight, thrust: rl.KeyUp, reverse: rl.KeyDown, fire: rl.KeyRightControl},
+	{left: rl.KeyJ, right: rl.KeyL, thrust: rl.KeyI, reverse: rl.KeyK, fire: rl.KeyH},
+	{left: rl.KeyKp4, right: rl.KeyKp6, thrust: rl.KeyKp8, reverse: rl.KeyKp5, fire: rl.KeyKp0},
+}
+
+// read turns the keys held this frame into the player's input. Enter, to go
+// on after a round or a game over, is shared by everyone.
+func (c Controls) read() sim.Input {
+	in := sim.Input(0)
+	if rl.IsKeyDown(c.left) {
+		in |= sim.InputLeft
+	}
+	if rl.IsKeyDown(c.right) {
+		in |= sim.InputRight
+	}
+	if rl.IsKeyDown(c.thrust) {
+		in |= sim.InputThrust
+	}
+	if rl.IsKeyDown(c.reverse) {
+		in |= sim.InputReverse
+	}
+	if rl.IsKeyPressed(c.fire) {
+		in |= sim.InputFire
+	}
+	if rl.IsKeyPressed(rl.KeyEnter) {
+		in |= sim.InputStart
+	}
+	return in
+}
+
+// startGame starts a new game played on this machine, with the rules taken
+// from the settings.
+func (g *GameState) startGame(mode sim.Mode, players int) {
+	g.world = sim.NewWorld(rand.Uint64(), sim.Rules{
+		Mode:          mode,
+		Players:       players,
+		FriendlyFire:  g.settings.FriendlyFire,
+		DuelRounds:    g.settings.DuelRounds,
+		DuelAsteroids: g.settings.DuelAsteroids,
+	})
+	g.scene = SCENE_PLAYING
+	g.handleEvents()
+}
+
+// localInputs reads the keyboard for every player. When players take turns
+// they share the first set of keys, which the autopilot can fly.
+func (g *GameState) localInputs() []sim.Input {
+	first := 0
+	if g.world.Rules.Mode == sim.ModeTurns {
+		first = g.world.Current
+	}
+	shared := g.pilotInput(first)
+	inputs := make([]sim.Input, len(g.world.Players))
+	for i := range inputs {
+		if i == 0 || g.world.Rules.Mode == sim.ModeTurns {
+			inputs[i] = shared
+		} else {
+			inputs[i] = CONTROLS[i].read()
+		}
+	}
+	return inputs
+}
+
+// drawScores draws the scores, and whose turn it is when it's just been
+// passed.
+func (g *GameState) drawScores() {
+	render.Scores(screen, g.world)
+	if g.turnBanner > 0 {
+		text := fmt.Sprintf("Player %d", g.world.Current+1)
+		render.TextCentered(screen, text, sim.Vector2{
+			X: SCREEN_SIZE_X / 2,
+			Y: SCREEN_SIZE_Y/2 - 120,
+		}, 50.0, 1.0, render.WHITE)
+	}
+}
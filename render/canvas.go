@@ -0,0 +1,161 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// Canvas is a Renderer that draws into an image, anti-aliased, with nothing
+// but Go. The same drawing always gives the same pixels, so frames can be
+// compared with ones saved before.
+type Canvas struct {
+	Image *image.RGBA
+}
+
+// NewCanvas makes a black canvas the size of the screen.
+func NewCanvas() *Canvas {
+	c := &Canvas{Image: image.NewRGBA(image.Rect(0, 0, sim.SCREEN_SIZE_X, sim.SCREEN_SIZE_Y))}
+	c.Clear(BLACK)
+	return c
+}
+
+func (c *Canvas) Clear(col color.RGBA) {
+	for i := 0; i < len(c.Image.Pix); i += 4 {
+		c.Image.Pix[i] = col.R
+		c.Image.Pix[i+1] = col.G
+		c.Image.Pix[i+2] = col.B
+		c.Image.Pix[i+3] = col.A
+	}
+}
+
+// blend paints the pixel at x, y with the color covering a share of it,
+// over what's there.
+func (c *Canvas) blend(x, y int, col color.RGBA, coverage float32) {
+	if coverage <= 0 || !(image.Point{x, y}.In(c.Image.Rect)) {
+		return
+	}
+	alpha := float32(col.A) / 255 * min(coverage, 1)
+	i := c.Image.PixOffset(x, y)
+	pix := c.Image.Pix[i : i+4 : i+4]
+	for k, v := range []uint8{col.R, col.G, col.B, 255} {
+		pix[k] = uint8(float32(v)*alpha + float32(pix[k])*(1-alpha) + 0.5)
+	}
+}
+
+func (c *Canvas) Pixel(pos sim.Vector2, col color.RGBA) {
+	c.blend(int(math.Floor(float64(pos.X))), int(math.Floor(float64(pos.Y))), col, 1)
+}
+
+// Line covers every pixel by how close its center is to the segment, a
+// pixel away being nothing. It walks the segment along its longest side,
+// covering a few pixels across at every step.
+func (c *Canvas) Line(a, b sim.Vector2, col color.RGBA) {
+	steep := math.Abs(float64(b.Y-a.Y)) > math.Abs(float64(b.X-a.X))
+	//Se recorre en x, cambiando los ejes si la linea es mas alta que ancha
+	if steep {
+		a.X, a.Y = a.Y, a.X
+		b.X, b.Y = b.Y, b.X
+	}
+	if a.X > b.X {
+		a, b = b, a
+	}
+	slope := float32(0)
+	if b.X != a.X {
+		slope = (b.Y - a.Y) / (b.X - a.X)
+	}
+	for x := int(math.Floor(float64(a.X))) - 1; x <= int(math.Floor(float64(b.X)))+1; x++ {
+		center := float32(x) + 0.5
+		y := a.Y + (min(max(center, a.X), b.X)-a.X)*slope
+		for row := int(math.Floor(float64(y))) - 1; row <= int(math.Floor(float64(y)))+1; row++ {
+			p := sim.NewVector2(center, float32(row)+0.5)
+			coverage := 1 - segmentDistance(p, a, b)
+			if steep {
+				c.blend(row, x, col, coverage)
+			} else {
+				c.blend(x, row, col, coverage)
+			}
+		}
+	}
+}
+
+// segmentDistance is how far p is from the segment from a to b.
+func segmentDistance(p, a, b sim.Vector2) float32 {
+	ab := sim.Vector2Subtract(b, a)
+	t := float32(0)
+	if length := sim.Vector2DotProduct(ab, ab); length > 0 {
+		t = max(0, min(1, sim.Vector2DotProduct(sim.Vector2Subtract(p, a), ab)/length))
+	}
+	return sim.Vector2Distance(p, sim.Vector2Add(a, sim.Vector2Scale(ab, t)))
+}
+
+// Circle fills the pixels inside the circle, and the ones on its edge by how
+// much of them is inside.
+func (c *Canvas) Circle(center sim.Vector2, radius float32, col color.RGBA) {
+	x0, x1 := int(math.Floor(float64(center.X-radius-1))), int(math.Ceil(float64(center.X+radius+1)))
+	y0, y1 := int(math.Floor(float64(center.Y-radius-1))), int(math.Ceil(float64(center.Y+radius+1)))
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			d := sim.Vector2Distance(center, sim.NewVector2(float32(x)+0.5, float32(y)+0.5))
+			c.blend(x, y, col, radius+0.5-d)
+		}
+	}
+}
+
+// rect fills a rectangle, covering the pixels on its edges by how much of
+// them is inside.
+func (c *Canvas) rect(x0, y0, x1, y1 float32, col color.RGBA) {
+	for y := int(math.Floor(float64(y0))); float32(y) < y1; y++ {
+		across := min(float32(y+1), y1) - max(float32(y), y0)
+		for x := int(math.Floor(float64(x0))); float32(x) < x1; x++ {
+			along := min(float32(x+1), x1) - max(float32(x), x0)
+			c.blend(x, y, col, across*along)
+		}
+	}
+}
+
+// Text draws with the FONT, scaled from its size to the size asked for.
+func (c *Canvas) Text(text string, pos sim.Vector2, size, spacing float32, col color.RGBA) {
+	scale := size / FONT_SIZE
+	x := pos.X
+	for _, r := range text {
+		columns := glyph(r)
+		for i, column := range columns {
+			for row := range FONT_ROWS {
+				if column&(1<<row) == 0 {
+					continue
+				}
+				left := x + float32(i)*scale
+				top := pos.Y + float32(FONT_TOP+row)*scale
+				c.rect(left, top, left+scale, top+scale, col)
+			}
+		}
+		x += FONT_ADVANCE*scale + spacing
+	}
+}
+
+func (c *Canvas) MeasureText(text string, size, spacing float32) sim.Vector2 {
+	return measureText(text, size, spacing)
+}
+
+// WritePNG encodes what's been drawn as a PNG.
+func (c *Canvas) WritePNG(w io.Writer) error {
+	return png.Encode(w, c.Image)
+}
+
+func (c *Canvas) SavePNG(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := c.WritePNG(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,185 @@
+// Package render draws the game: ships, their shots and wrecks, asteroids,
+// the lives and the scores. It draws through a Renderer, so the same
+// drawing goes to the window, where raylib does it, or into an image with
+// Canvas, which needs nothing but Go and can take screenshots of any frame
+// of a simulated game on a machine with no display.
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand/v2"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// Renderer draws the shapes everything is made of, in screen coordinates.
+// Lines are a pixel wide, circles are filled, and text is drawn from its top
+// left corner with the default font.
+type Renderer interface {
+	Line(a, b sim.Vector2, c color.RGBA)
+	Circle(center sim.Vector2, radius float32, c color.RGBA)
+	Pixel(pos sim.Vector2, c color.RGBA)
+	Text(text string, pos sim.Vector2, size, spacing float32, c color.RGBA)
+	MeasureText(text string, size, spacing float32) sim.Vector2
+}
+
+// The colors are raylib's.
+var (
+	WHITE    = color.RGBA{255, 255, 255, 255}
+	GRAY     = color.RGBA{130, 130, 130, 255}
+	SKY_BLUE = color.RGBA{102, 191, 255, 255}
+	GOLD     = color.RGBA{255, 203, 0, 255}
+	LIME     = color.RGBA{0, 158, 47, 255}
+	BLACK    = color.RGBA{0, 0, 0, 255}
+)
+
+// SHIP_COLORS are indexed by player.
+var SHIP_COLORS = []color.RGBA{WHITE, SKY_BLUE, GOLD, LIME}
+
+const (
+	// LIFE_SIZE is the size of the ships in the row of lives.
+	LIFE_SIZE = 20
+	STAR_RAYS = 12
+)
+
+// Fade returns the color with its alpha scaled by alpha, from 0 to 1.
+func Fade(c color.RGBA, alpha float32) color.RGBA {
+	alpha = max(0, min(1, alpha))
+	return color.RGBA{c.R, c.G, c.B, uint8(float32(c.A) * alpha)}
+}
+
+// TextCentered draws text centered on pos.
+func TextCentered(r Renderer, text string, pos sim.Vector2, size, spacing float32, c color.RGBA) {
+	half := sim.Vector2Scale(r.MeasureText(text, size, spacing), 0.5)
+	r.Text(text, sim.Vector2Subtract(pos, half), size, spacing, c)
+}
+
+func Projectile(r Renderer, p sim.Projectile, c color.RGBA) {
+	r.Circle(p.Pos, sim.PROJECTILE_SIZE, c)
+}
+
+func Projectiles(r Renderer, s *sim.PlayerShip) {
+	for _, p := range s.Projectiles {
+		Projectile(r, p, SHIP_COLORS[s.Player])
+	}
+}
+
+// ShipExplosion draws the tumbling hull segments, fading them out as the
+// time in pieces runs out.
+func ShipExplosion(r Renderer, s *sim.PlayerShip, alpha float32) {
+	c := Fade(SHIP_COLORS[s.Player], alpha)
+	for _, segment := range s.Wreck {
+		half := sim.Vector2Rotate(segment.Half, segment.Angle)
+		r.Line(sim.Vector2Subtract(segment.Pos, half), sim.Vector2Add(segment.Pos, half), c)
+	}
+}
+
+func Ship(r Renderer, s *sim.PlayerShip) {
+	Life(r, s.Pos, s.Size, s.Orientation, SHIP_COLORS[s.Player])
+}
+
+// Life draws the outline of a ship: nose, back corners and the middle.
+func Life(r Renderer, pos sim.Vector2, size float32, orientation float32, c color.RGBA) {
+	verticalDirection := sim.Vector2Scale(sim.GetDirection(orientation), size)
+	horizontalDirection := sim.Vector2Scale(sim.GetDirection(orientation+math.Pi*0.5), size)
+
+	points := []sim.Vector2{
+		sim.Vector2Add(pos, verticalDirection),
+		sim.Vector2Subtract(sim.Vector2Subtract(pos, verticalDirection), horizontalDirection),
+		pos,
+		sim.Vector2Add(sim.Vector2Subtract(pos, verticalDirection), horizontalDirection),
+	}
+
+	for i := range points {
+		r.Line(points[i], points[(i+1)%len(points)], c)
+	}
+}
+
+func Asteroid(r Renderer, a *sim.Asteroid) {
+	points := a.Points()
+
+	for i := range points {
+		r.Line(points[i], points[(i+1)%len(points)], WHITE)
+	}
+}
+
+// Lives draws a row of ships for the lives of each player in play.
+func Lives(r Renderer, w *sim.World) {
+	if w.Rules.Mode == sim.ModeDuel {
+		return
+	}
+	for i, p := range w.Players {
+		if w.Rules.Mode == sim.ModeTurns && i != w.Current {
+			continue
+		}
+		row := float32(i)
+		if w.Rules.Mode == sim.ModeTurns {
+			row = 0
+		}
+		for j := range p.Lives {
+			Life(r, sim.Vector2{
+				X: 25 + 45*float32(j) + 250*row,
+				Y: sim.SCREEN_SIZE_Y - 25,
+			}, LIFE_SIZE, math.Pi+math.Pi*0.5, SHIP_COLORS[i])
+		}
+	}
+}
+
+// Scores draws the score of every player in the top right corner, or the
+// rounds they've won in a duel. Taking turns, the players waiting are gray.
+func Scores(r Renderer, w *sim.World) {
+	for i, p := range w.Players {
+		c := SHIP_COLORS[i]
+		if w.Rules.Mode == sim.ModeTurns && i != w.Current {
+			c = GRAY
+		}
+		score := p.Score
+		if w.Rules.Mode == sim.ModeDuel {
+			score = p.Rounds
+		}
+		text := fmt.Sprintf("%02d", score)
+		if len(w.Players) > 1 {
+			text = fmt.Sprintf("P%d %02d", i+1, score)
+		}
+		r.Text(text, sim.Vector2{
+			X: sim.SCREEN_SIZE_X - 200,
+			Y: 10 + 35*float32(i),
+		}, 30.0, 2.0, c)
+	}
+}
+
+// Star draws the star of a duel as rays that flicker from tick to tick. The
+// flicker comes from the tick, so a frame comes out the same every time.
+func Star(r Renderer, tick int) {
+	rng := rand.New(rand.NewPCG(uint64(tick), 0))
+	for i := range STAR_RAYS {
+		angle := float32(i)*(math.Pi*2)/STAR_RAYS + rng.Float32()*0.3
+		length := sim.STAR_RADIUS * (0.5 + rng.Float32()*0.7)
+		r.Line(sim.STAR_POS, sim.Vector2Add(sim.STAR_POS, sim.Vector2Scale(sim.GetDirection(angle), length)), WHITE)
+	}
+}
+
+// Frame draws the world the way the game shows it being played, without
+// the particles, which aren't part of the simulation.
+func Frame(r Renderer, w *sim.World) {
+	if w.Rules.Mode == sim.ModeDuel {
+		Star(r, w.Tick)
+	}
+	for _, s := range w.Ships {
+		if s.Collision {
+			ShipExplosion(r, s, float32(s.DestroyedTime/sim.SHIP_TIME_IN_PIECES))
+		} else {
+			Ship(r, s)
+		}
+	}
+	for _, s := range w.Ships {
+		Projectiles(r, s)
+	}
+	for _, a := range w.Asteroids {
+		Asteroid(r, &a)
+	}
+	Lives(r, w)
+	Scores(r, w)
+}
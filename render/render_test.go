@@ -0,0 +1,131 @@
+package render
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+var update = flag.Bool("update", false, "write the golden images again from what's drawn now")
+
+// GOLDEN_TOLERANCE is how far a channel of a pixel can be from the golden
+// image, and GOLDEN_MISSES how many pixels can be further, since floating
+// point can round a little differently on other machines.
+const (
+	GOLDEN_TOLERANCE = 8
+	GOLDEN_MISSES    = 50
+)
+
+// goldenFrames are the worlds drawn and compared with the images in testdata.
+var goldenFrames = []struct {
+	name  string
+	rules sim.Rules
+	seed  uint64
+	ticks int
+}{
+	{"coop", sim.Rules{Mode: sim.ModeCoop, Players: 2}, 7, 150},
+	{"turns", sim.Rules{Mode: sim.ModeTurns, Players: 2}, 3, 90},
+	{"duel", sim.Rules{Mode: sim.ModeDuel, Players: 2, DuelRounds: 3}, 5, 120},
+}
+
+// play steps a world with inputs that turn, thrust and fire in turns, the
+// same every time.
+func play(rules sim.Rules, seed uint64, ticks int) *sim.World {
+	w := sim.NewWorld(seed, rules)
+	inputs := make([]sim.Input, rules.Players)
+	for tick := range ticks {
+		for i := range inputs {
+			inputs[i] = sim.InputThrust
+			if (tick/20+i)%2 == 0 {
+				inputs[i] |= sim.InputLeft
+			}
+			if (tick+3*i)%15 == 0 {
+				inputs[i] |= sim.InputFire
+			}
+		}
+		w.Step(inputs)
+	}
+	return w
+}
+
+func TestFrameMatchesGolden(t *testing.T) {
+	for _, frame := range goldenFrames {
+		t.Run(frame.name, func(t *testing.T) {
+			c := NewCanvas()
+			Frame(c, play(frame.rules, frame.seed, frame.ticks))
+			path := filepath.Join("testdata", frame.name+".png")
+			if *update {
+				if err := c.SavePNG(path); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			golden, err := loadPNG(path)
+			if err != nil {
+				t.Fatalf("%v, run the test with -update to make it", err)
+			}
+			if golden.Bounds() != c.Image.Bounds() {
+				t.Fatalf("the golden image is %v and the frame %v", golden.Bounds(), c.Image.Bounds())
+			}
+			misses := 0
+			for y := golden.Bounds().Min.Y; y < golden.Bounds().Max.Y; y++ {
+				for x := golden.Bounds().Min.X; x < golden.Bounds().Max.X; x++ {
+					if !near(golden.At(x, y), c.Image.At(x, y)) {
+						misses += 1
+					}
+				}
+			}
+			if misses > GOLDEN_MISSES {
+				got := filepath.Join(os.TempDir(), "asteroids-"+frame.name+".png")
+				c.SavePNG(got)
+				t.Errorf("%d pixels differ from %s, the frame drawn is in %s", misses, path, got)
+			}
+		})
+	}
+}
+
+func TestFrameIsDeterministic(t *testing.T) {
+	frame := goldenFrames[0]
+	a, b := NewCanvas(), NewCanvas()
+	Frame(a, play(frame.rules, frame.seed, frame.ticks))
+	Frame(b, play(frame.rules, frame.seed, frame.ticks))
+	for i := range a.Image.Pix {
+		if a.Image.Pix[i] != b.Image.Pix[i] {
+			t.Fatalf("the same frame drawn twice differs at byte %d", i)
+		}
+	}
+}
+
+func loadPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return img, nil
+}
+
+// near is true if every channel of the colors is within GOLDEN_TOLERANCE.
+func near(a, b color.Color) bool {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	for _, pair := range [][2]uint32{{ar, br}, {ag, bg}, {ab, bb}, {aa, ba}} {
+		diff := int(pair[0]>>8) - int(pair[1]>>8)
+		if diff < -GOLDEN_TOLERANCE || diff > GOLDEN_TOLERANCE {
+			return false
+		}
+	}
+	return true
+}
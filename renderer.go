@@ -0,0 +1,35 @@
+package main
+
+import (
+	"image/color"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/rodolfato/asteroids/render"
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// raylibRenderer draws to the window.
+type raylibRenderer struct{}
+
+// screen is what the game is drawn with.
+var screen render.Renderer = raylibRenderer{}
+
+func (raylibRenderer) Line(a, b sim.Vector2, c color.RGBA) {
+	rl.DrawLineV(rl.Vector2(a), rl.Vector2(b), c)
+}
+
+func (raylibRenderer) Circle(center sim.Vector2, radius float32, c color.RGBA) {
+	rl.DrawCircleV(rl.Vector2(center), radius, c)
+}
+
+func (raylibRenderer) Pixel(pos sim.Vector2, c color.RGBA) {
+	rl.DrawPixelV(rl.Vector2(pos), c)
+}
+
+func (raylibRenderer) Text(text string, pos sim.Vector2, size, spacing float32, c color.RGBA) {
+	rl.DrawTextEx(rl.GetFontDefault(), text, rl.Vector2(pos), size, spacing, c)
+}
+
+func (raylibRenderer) MeasureText(text string, size, spacing float32) sim.Vector2 {
+	return sim.Vector2(rl.MeasureTextEx(rl.GetFontDefault(), text, size, spacing))
+}
@@ -0,0 +1,213 @@
+// Package sensor gives a ship distance sensors: rays cast from the ship at
+// angles relative to where it points, each reporting how far the first
+// thing in its way is and what it is. Rays follow the screen around its
+// edges, so something just past the left edge is seen by a ray going left
+// from the right side. Bots and learning agents see the world through them
+// the same way, and the debug overlay draws them.
+//
+// Rays hit the outlines of asteroids and ships as they really are, and shots
+// and the star as circles. The game has no saucers, so there's no kind for
+// them; the shots of other ships are the bullets to look out for.
+package sensor
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+const (
+	DefaultRays = 16
+	// DefaultRange is half the height of the screen, so a ray never sees all
+	// the way around and back.
+	DefaultRange = sim.SCREEN_SIZE_Y / 2
+)
+
+// Kind is what a ray hit.
+type Kind int
+
+const (
+	KindNone Kind = iota
+	KindAsteroid
+	KindShip
+	// KindShot is a projectile that can hit the ship: another ship's, in a
+	// duel or with friendly fire. A ship's own shots aren't seen.
+	KindShot
+	KindStar
+	NUM_KINDS
+)
+
+var KIND_NAMES = []string{"none", "asteroid", "ship", "shot", "star"}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(KIND_NAMES) {
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+	return KIND_NAMES[k]
+}
+
+// Reading is what a ray sees. Distance is the Range of the sensor when it
+// sees nothing.
+type Reading struct {
+	Angle    float32
+	Distance float32
+	Kind     Kind
+}
+
+// Sensor is a set of rays. Angles are in radians from where the ship points,
+// clockwise on the screen like the ship's orientation.
+type Sensor struct {
+	Angles []float32
+	Range  float32
+}
+
+// Even spreads n rays evenly around the ship, the first one straight ahead.
+func Even(n int, reach float32) Sensor {
+	s := Sensor{Angles: make([]float32, n), Range: reach}
+	for i := range s.Angles {
+		s.Angles[i] = 2 * math.Pi * float32(i) / float32(n)
+	}
+	return s
+}
+
+// obstacle is something rays can hit, around the ship: an outline, or a
+// circle when it has no outline.
+type obstacle struct {
+	kind    Kind
+	center  sim.Vector2
+	radius  float32
+	outline []sim.Vector2
+}
+
+// Read casts the rays from the ship. A ship in pieces, or no ship at all,
+// sees nothing.
+func (s Sensor) Read(w *sim.World, ship *sim.PlayerShip) []Reading {
+	readings := make([]Reading, len(s.Angles))
+	for i, angle := range s.Angles {
+		readings[i] = Reading{Angle: angle, Distance: s.Range}
+	}
+	if ship == nil || ship.Collision {
+		return readings
+	}
+	obstacles := s.obstacles(w, ship)
+	for i := range readings {
+		direction := sim.GetDirection(ship.Orientation + readings[i].Angle)
+		for _, o := range obstacles {
+			if d, ok := o.cast(direction); ok && d < readings[i].Distance {
+				readings[i].Distance = d
+				readings[i].Kind = o.kind
+			}
+		}
+	}
+	return readings
+}
+
+// obstacles lists what the rays can hit within range, relative to the ship.
+// Everything is placed where it's closest to the ship going around the
+// edges, and again one screen over in every direction when that copy is in
+// range too.
+func (s Sensor) obstacles(w *sim.World, ship *sim.PlayerShip) []obstacle {
+	obstacles := []obstacle{}
+	add := func(kind Kind, pos sim.Vector2, radius float32, outline []sim.Vector2) {
+		delta := sim.WrappedDelta(ship.Pos, pos)
+		for _, dx := range []float32{0, -sim.SCREEN_SIZE_X, sim.SCREEN_SIZE_X} {
+			for _, dy := range []float32{0, -sim.SCREEN_SIZE_Y, sim.SCREEN_SIZE_Y} {
+				center := sim.Vector2Add(delta, sim.NewVector2(dx, dy))
+				if sim.Vector2Length(center) > s.Range+radius {
+					continue
+				}
+				o := obstacle{kind: kind, center: center, radius: radius}
+				if outline != nil {
+					//El contorno queda relativo a la nave
+					offset := sim.Vector2Subtract(center, pos)
+					o.outline = make([]sim.Vector2, len(outline))
+					for i, point := range outline {
+						o.outline[i] = sim.Vector2Add(point, offset)
+					}
+				}
+				obstacles = append(obstacles, o)
+			}
+		}
+	}
+
+	for _, a := range w.Asteroids {
+		add(KindAsteroid, a.Pos, a.Radius(), a.Points())
+	}
+	duel := w.Rules.Mode == sim.ModeDuel
+	for _, other := range w.Ships {
+		if other == ship {
+			continue
+		}
+		if !other.Collision {
+			add(KindShip, other.Pos, other.Size*math.Sqrt2, other.Points())
+		}
+		if duel || w.Rules.FriendlyFire {
+			for _, p := range other.Projectiles {
+				add(KindShot, p.Pos, p.Size, nil)
+			}
+		}
+	}
+	if duel {
+		add(KindStar, sim.STAR_POS, sim.STAR_RADIUS, nil)
+	}
+	return obstacles
+}
+
+// cast returns how far a ray from the ship going in direction travels
+// before it hits the obstacle, if it does within the obstacle's reach. A
+// ship inside an obstacle hits it right away.
+func (o obstacle) cast(direction sim.Vector2) (float32, bool) {
+	along := sim.Vector2DotProduct(o.center, direction)
+	across := sim.Vector2CrossProduct(direction, o.center)
+	inside := sim.Vector2Length(o.center) <= o.radius
+	if !inside && (along < 0 || across*across > o.radius*o.radius) {
+		return 0, false
+	}
+	if o.outline == nil {
+		if inside {
+			return 0, true
+		}
+		//Distancia hasta donde el rayo entra al circulo
+		return along - float32(math.Sqrt(float64(o.radius*o.radius-across*across))), true
+	}
+	if inside && containsOrigin(o.outline) {
+		return 0, true
+	}
+	hit, found := float32(math.MaxFloat32), false
+	for i := range o.outline {
+		a, b := o.outline[i], o.outline[(i+1)%len(o.outline)]
+		if t, ok := crossSegment(direction, a, b); ok && t < hit {
+			hit, found = t, true
+		}
+	}
+	return hit, found
+}
+
+// crossSegment returns how far along a ray from the origin going in
+// direction it crosses the segment from a to b, if it does.
+func crossSegment(direction, a, b sim.Vector2) (float32, bool) {
+	edge := sim.Vector2Subtract(b, a)
+	denominator := sim.Vector2CrossProduct(direction, edge)
+	if denominator == 0 {
+		return 0, false
+	}
+	t := sim.Vector2CrossProduct(a, edge) / denominator
+	u := sim.Vector2CrossProduct(a, direction) / denominator
+	if t < 0 || u < 0 || u > 1 {
+		return 0, false
+	}
+	return t, true
+}
+
+// containsOrigin returns whether the polygon has the origin inside.
+func containsOrigin(points []sim.Vector2) bool {
+	inside := false
+	for i, j := 0, len(points)-1; i < len(points); j, i = i, i+1 {
+		if (points[i].Y > 0) != (points[j].Y > 0) &&
+			0 < (points[j].X-points[i].X)*(-points[i].Y)/(points[j].Y-points[i].Y)+points[i].X {
+			inside = !inside
+		}
+	}
+	return inside
+}
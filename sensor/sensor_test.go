@@ -0,0 +1,129 @@
+package sensor
+
+import (
+	"math"
+	"testing"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// ROCK is the half width of the square asteroids the tests put around.
+const ROCK = 20
+
+func rock(x, y float32) sim.Asteroid {
+	return sim.Asteroid{
+		Pos: sim.NewVector2(x, y),
+		Shape: []sim.Vector2{
+			sim.NewVector2(-ROCK, -ROCK), sim.NewVector2(ROCK, -ROCK),
+			sim.NewVector2(ROCK, ROCK), sim.NewVector2(-ROCK, ROCK),
+		},
+	}
+}
+
+func ship(x, y, orientation float32) *sim.PlayerShip {
+	return &sim.PlayerShip{Pos: sim.NewVector2(x, y), Orientation: orientation, Size: sim.PLAYER_SHIP_SIZE}
+}
+
+func TestRays(t *testing.T) {
+	const Y = sim.SCREEN_SIZE_Y / 2
+	other := ship(600, Y, math.Pi)
+	other.Projectiles = []sim.Projectile{{Pos: sim.NewVector2(300, Y+100), Size: sim.PROJECTILE_SIZE}}
+	tests := []struct {
+		name     string
+		world    sim.World
+		ship     *sim.PlayerShip
+		ray      int
+		kind     Kind
+		distance float32
+	}{
+		{
+			name:  "asteroid ahead",
+			world: sim.World{Asteroids: []sim.Asteroid{rock(300, Y)}},
+			ship:  ship(200, Y, 0), ray: 0,
+			kind: KindAsteroid, distance: 100 - ROCK,
+		},
+		{
+			name:  "asteroid at the left edge, seen going right past the right edge",
+			world: sim.World{Asteroids: []sim.Asteroid{rock(30, Y)}},
+			ship:  ship(sim.SCREEN_SIZE_X-40, Y, 0), ray: 0,
+			kind: KindAsteroid, distance: 70 - ROCK,
+		},
+		{
+			name:  "asteroid at the top, seen going down past the bottom edge",
+			world: sim.World{Asteroids: []sim.Asteroid{rock(200, 50)}},
+			ship:  ship(200, sim.SCREEN_SIZE_Y-50, 0), ray: 1,
+			kind: KindAsteroid, distance: 100 - ROCK,
+		},
+		{
+			name:  "asteroid just out of range",
+			world: sim.World{Asteroids: []sim.Asteroid{rock(200+DefaultRange+ROCK+5, Y)}},
+			ship:  ship(200, Y, 0), ray: 0,
+			kind: KindNone, distance: DefaultRange,
+		},
+		{
+			name:  "asteroid just in range",
+			world: sim.World{Asteroids: []sim.Asteroid{rock(200+DefaultRange+ROCK-5, Y)}},
+			ship:  ship(200, Y, 0), ray: 0,
+			kind: KindAsteroid, distance: DefaultRange - 5,
+		},
+		{
+			name:  "the ray turns with the ship",
+			world: sim.World{Asteroids: []sim.Asteroid{rock(300, Y)}},
+			ship:  ship(200, Y, math.Pi*0.5), ray: 3,
+			kind: KindAsteroid, distance: 100 - ROCK,
+		},
+		{
+			name:  "another ship, nose first",
+			world: sim.World{Ships: []*sim.PlayerShip{other}},
+			ship:  ship(300, Y, 0), ray: 0,
+			kind: KindShip, distance: 300 - sim.PLAYER_SHIP_SIZE,
+		},
+		{
+			name:  "shots of other ships are harmless in co-op",
+			world: sim.World{Ships: []*sim.PlayerShip{other}},
+			ship:  ship(300, Y, 0), ray: 1,
+			kind: KindNone, distance: DefaultRange,
+		},
+		{
+			name:  "shots of other ships with friendly fire",
+			world: sim.World{Rules: sim.Rules{FriendlyFire: true}, Ships: []*sim.PlayerShip{other}},
+			ship:  ship(300, Y, 0), ray: 1,
+			kind: KindShot, distance: 100 - sim.PROJECTILE_SIZE,
+		},
+		{
+			name:  "the star in a duel",
+			world: sim.World{Rules: sim.Rules{Mode: sim.ModeDuel}},
+			ship:  ship(sim.STAR_POS.X-200, sim.STAR_POS.Y, 0), ray: 0,
+			kind: KindStar, distance: 200 - sim.STAR_RADIUS,
+		},
+	}
+	sensor := Even(4, DefaultRange)
+	for _, test := range tests {
+		test.world.Ships = append(test.world.Ships, test.ship)
+		reading := sensor.Read(&test.world, test.ship)[test.ray]
+		if reading.Kind != test.kind || math.Abs(float64(reading.Distance-test.distance)) > 0.01 {
+			t.Errorf("%s: got %v at %v, want %v at %v", test.name, reading.Kind, reading.Distance, test.kind, test.distance)
+		}
+	}
+}
+
+func TestNoShipSeesNothing(t *testing.T) {
+	world := sim.World{Asteroids: []sim.Asteroid{rock(300, 300)}}
+	wrecked := ship(280, 300, 0)
+	wrecked.Collision = true
+	for _, s := range []*sim.PlayerShip{nil, wrecked} {
+		for _, reading := range Even(DefaultRays, DefaultRange).Read(&world, s) {
+			if reading.Kind != KindNone || reading.Distance != DefaultRange {
+				t.Errorf("a ship that isn't flying sees %v at %v", reading.Kind, reading.Distance)
+			}
+		}
+	}
+}
+
+func TestClosestHitWins(t *testing.T) {
+	world := sim.World{Asteroids: []sim.Asteroid{rock(400, 300), rock(300, 300)}}
+	s := ship(200, 300, 0)
+	if reading := Even(1, DefaultRange).Read(&world, s)[0]; reading.Distance != 100-ROCK {
+		t.Errorf("the ray stopped at %v, want the nearest asteroid at %v", reading.Distance, 100-ROCK)
+	}
+}
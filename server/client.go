@@ -0,0 +1,218 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/rodolfato/asteroids/sim"
+	"github.com/rodolfato/asteroids/wire"
+)
+
+// Client is a game's connection to a server. It's driven from the game loop
+// like a netplay session: Poll every frame to handle what arrived, Send the
+// local controls, and draw the Latest snapshot.
+type Client struct {
+	conn      net.Conn
+	tcp       bool
+	messages  chan []byte
+	hello     hello
+	welcome   welcome
+	welcomed  bool
+	snapshots [SNAPSHOT_HISTORY]*Snapshot
+	latest    *Snapshot
+	fresh     bool
+	seq       int
+	lastHello time.Time
+	heard     time.Time
+	err       error
+}
+
+// Connect asks the server at addr, over "tcp" or "udp", for a place in the
+// named match, which is created with the given rules if it doesn't exist.
+func Connect(network, addr, match string, rules sim.Rules) (*Client, error) {
+	if network != "tcp" && network != "udp" {
+		return nil, fmt.Errorf("unknown network %q, it can be tcp or udp", network)
+	}
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		conn:     conn,
+		tcp:      network == "tcp",
+		messages: make(chan []byte, 256),
+		hello:    hello{version: Version, match: match, rules: rules},
+		heard:    time.Now(),
+	}
+	go c.read()
+	return c, nil
+}
+
+// read runs on its own goroutine and hands every message to Poll. Messages
+// that don't fit in the queue are dropped like the network would.
+func (c *Client) read() {
+	defer close(c.messages)
+	if c.tcp {
+		r := bufio.NewReader(c.conn)
+		for {
+			data, err := readFrame(r)
+			if err != nil {
+				return
+			}
+			c.messages <- data
+		}
+	}
+	buf := make([]byte, MAX_MESSAGE_SIZE)
+	for {
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			//Un puerto cerrado del otro lado no corta la conexion UDP
+			continue
+		}
+		select {
+		case c.messages <- append([]byte(nil), buf[:n]...):
+		default:
+		}
+	}
+}
+
+// Close tells the server we're leaving and closes the connection.
+func (c *Client) Close() error {
+	c.write(encodeBye())
+	return c.conn.Close()
+}
+
+func (c *Client) write(data []byte) {
+	if c.tcp {
+		writeFrame(c.conn, data)
+	} else {
+		c.conn.Write(data)
+	}
+}
+
+// Players returns how many players have joined the match out of how many
+// it's for. Neither is known until the server welcomes us.
+func (c *Client) Players() (int, int) {
+	return c.welcome.joined, c.welcome.rules.Players
+}
+
+func (c *Client) Started() bool {
+	return c.welcome.started
+}
+
+// Local is the player we control.
+func (c *Client) Local() int {
+	return c.welcome.player
+}
+
+func (c *Client) Rules() sim.Rules {
+	return c.welcome.rules
+}
+
+// Latest returns the newest snapshot of the match, and whether it arrived
+// since the last call.
+func (c *Client) Latest() (*Snapshot, bool) {
+	fresh := c.fresh
+	c.fresh = false
+	return c.latest, fresh
+}
+
+// Poll handles every message that arrived since the last call and keeps the
+// handshake going. It returns the client's error, if any.
+func (c *Client) Poll() error {
+	if c.err != nil {
+		return c.err
+	}
+	for drained := false; !drained; {
+		select {
+		case data, ok := <-c.messages:
+			if !ok {
+				c.err = errors.New("the server closed the connection")
+				return c.err
+			}
+			if err := c.handle(data); err != nil {
+				log.Printf("client: bad message from the server: %v", err)
+			}
+			if c.err != nil {
+				return c.err
+			}
+		default:
+			drained = true
+		}
+	}
+
+	now := time.Now()
+	if now.Sub(c.heard) > TIMEOUT {
+		c.err = fmt.Errorf("no answer from %s", c.conn.RemoteAddr())
+		return c.err
+	}
+	if !c.Started() && now.Sub(c.lastHello) >= HANDSHAKE_INTERVAL {
+		c.lastHello = now
+		c.write(encodeHello(c.hello))
+	}
+	return nil
+}
+
+func (c *Client) handle(data []byte) error {
+	if len(data) == 0 {
+		return wire.ErrShortMessage
+	}
+	c.heard = time.Now()
+	r := wire.NewReader(data[1:])
+	switch messageKind(data[0]) {
+	case msgReject:
+		c.err = fmt.Errorf("the server won't let us join: %s", r.String())
+	case msgWelcome:
+		m, err := decodeWelcome(r)
+		if err != nil {
+			return err
+		}
+		c.welcome = m
+		c.welcomed = true
+	case msgSnapshot:
+		s, err := decodeSnapshot(r, c.snapshot)
+		if err != nil {
+			return err
+		}
+		if c.latest != nil && s.Tick <= c.latest.Tick {
+			return nil
+		}
+		c.snapshots[s.Tick%SNAPSHOT_HISTORY] = s
+		c.latest = s
+		c.fresh = true
+		//Si llegan instantaneas la partida ya empezo aunque se perdiera la bienvenida
+		c.welcome.started = c.welcomed
+	default:
+		return fmt.Errorf("unknown message %d", data[0])
+	}
+	return nil
+}
+
+func (c *Client) snapshot(tick int) *Snapshot {
+	s := c.snapshots[tick%SNAPSHOT_HISTORY]
+	if s == nil || s.Tick != tick {
+		return nil
+	}
+	return s
+}
+
+// Send sends the local controls for the latest tick, along with the
+// acknowledgement of the latest snapshot.
+func (c *Client) Send(in sim.Input) {
+	if !c.Started() {
+		return
+	}
+	c.seq += 1
+	m := input{seq: c.seq, input: in}
+	if c.latest != nil {
+		m.ack = c.latest.Tick
+	}
+	c.write(encodeInput(m))
+}
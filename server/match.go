@@ -0,0 +1,253 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// VALID_INPUTS are the controls a client can send. Any other bit is dropped.
+const VALID_INPUTS = sim.InputLeft | sim.InputRight | sim.InputThrust | sim.InputReverse | sim.InputFire | sim.InputStart
+
+// FIRE_COOLDOWN is the fewest ticks between two shots of the same ship. Nobody
+// taps a key faster, so a client firing more often isn't being played by hand.
+const FIRE_COOLDOWN = 4
+
+// presses are the controls that only count on the tick they're pressed.
+const presses = sim.InputFire | sim.InputStart
+
+// Match is one game on the server. Its world is only touched by its own
+// goroutine, which steps it at the tick rate, and by the clients' messages,
+// all under the match's lock.
+type Match struct {
+	server   *Server
+	name     string
+	rules    sim.Rules
+	mu       sync.Mutex
+	world    *sim.World
+	clients  []*client
+	held     []sim.Input
+	pressed  []sim.Input
+	cooldown []int
+	history  [SNAPSHOT_HISTORY]*Snapshot
+	started  bool
+	over     bool
+}
+
+func newMatch(s *Server, name string, rules sim.Rules) *Match {
+	return &Match{
+		server:   s,
+		name:     name,
+		rules:    rules,
+		clients:  make([]*client, rules.Players),
+		held:     make([]sim.Input, rules.Players),
+		pressed:  make([]sim.Input, rules.Players),
+		cooldown: make([]int, rules.Players),
+	}
+}
+
+func (m *Match) run() {
+	ticker := time.NewTicker(time.Second / sim.TICK_RATE)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !m.tick() {
+			break
+		}
+	}
+	m.server.remove(m)
+}
+
+// tick steps the world and sends everyone the snapshot of it. Before the
+// match starts it only checks nobody timed out. It returns false once the
+// match is over.
+func (m *Match) tick() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for _, c := range m.clients {
+		if c != nil && now.Sub(c.heard) > TIMEOUT {
+			m.drop(c, "timed out")
+		}
+	}
+	if m.empty() {
+		m.over = true
+		return false
+	}
+	if !m.started {
+		return true
+	}
+
+	m.world.Step(m.inputs())
+	if m.server.Spectators != nil {
+		m.server.Spectators.Publish(m.name, m.world)
+	}
+	s := Capture(m.world, m.baseline(m.world.Tick-1))
+	m.history[s.Tick%SNAPSHOT_HISTORY] = s
+	for _, c := range m.clients {
+		if c != nil {
+			c.send(encodeSnapshot(s, m.baseline(c.ack)))
+		}
+	}
+	return true
+}
+
+// baseline returns the snapshot of the tick if it's still kept.
+func (m *Match) baseline(tick int) *Snapshot {
+	s := m.history[tick%SNAPSHOT_HISTORY]
+	if tick <= 0 || s == nil || s.Tick != tick {
+		return nil
+	}
+	return s
+}
+
+func (m *Match) empty() bool {
+	for _, c := range m.clients {
+		if c != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// ended is true once the match stopped, so its name can be used again.
+func (m *Match) ended() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.over
+}
+
+func (m *Match) join(c *client) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.over {
+		return errors.New("the match is over")
+	}
+	if m.started {
+		return errors.New("the match already started")
+	}
+	player := -1
+	for i, other := range m.clients {
+		if other == nil {
+			player = i
+			break
+		}
+	}
+	if player < 0 {
+		return errors.New("the match is full")
+	}
+	m.clients[player] = c
+	c.player = player
+	c.heard = time.Now()
+	c.match.Store(m)
+	log.Printf("server: player %d joined match %q from %s", player+1, m.name, c.addr)
+	if m.joined() == len(m.clients) {
+		m.world = sim.NewWorld(rand.Uint64(), m.rules)
+		m.started = true
+		log.Printf("server: match %q started", m.name)
+	}
+	m.welcomeAll()
+	return nil
+}
+
+func (m *Match) joined() int {
+	joined := 0
+	for _, c := range m.clients {
+		if c != nil {
+			joined += 1
+		}
+	}
+	return joined
+}
+
+// hello answers a client that asked to join again, in case the welcome was
+// lost.
+func (m *Match) hello(c *client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c.match.Load() == m {
+		c.heard = time.Now()
+		c.send(encodeWelcome(m.welcome(c)))
+	}
+}
+
+func (m *Match) welcome(c *client) welcome {
+	return welcome{
+		match:   m.name,
+		player:  c.player,
+		joined:  m.joined(),
+		started: m.started,
+		rules:   m.rules,
+	}
+}
+
+func (m *Match) welcomeAll() {
+	for _, c := range m.clients {
+		if c != nil {
+			c.send(encodeWelcome(m.welcome(c)))
+		}
+	}
+}
+
+func (m *Match) leave(c *client, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.drop(c, reason)
+}
+
+// drop takes the client out of the match. Once the match started its ship
+// stays, with nobody at the controls.
+func (m *Match) drop(c *client, reason string) {
+	if c.match.Load() != m || m.clients[c.player] != c {
+		return
+	}
+	log.Printf("server: player %d %s from match %q", c.player+1, reason, m.name)
+	m.clients[c.player] = nil
+	m.held[c.player] = 0
+	m.pressed[c.player] = 0
+	c.match.Store(nil)
+	c.close()
+	if !m.started {
+		m.welcomeAll()
+	}
+}
+
+// input takes a client's controls after checking them. The client can only
+// ever control its own player, inputs older than the last one taken are
+// dropped, and so are any bits that aren't controls.
+func (m *Match) input(c *client, in input) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c.match.Load() != m || in.seq <= c.seq {
+		return
+	}
+	c.seq = in.seq
+	c.heard = time.Now()
+	if m.started && in.ack <= m.world.Tick {
+		c.ack = max(c.ack, in.ack)
+	}
+	controls := in.input & VALID_INPUTS
+	m.held[c.player] = controls &^ presses
+	//Un toque entre dos ticks cuenta aunque ya se haya soltado
+	m.pressed[c.player] |= controls & presses
+}
+
+// inputs returns every player's controls for the next tick, keeping ships
+// within the fire rate.
+func (m *Match) inputs() []sim.Input {
+	inputs := make([]sim.Input, len(m.clients))
+	for i := range inputs {
+		inputs[i] = m.held[i] | m.pressed[i]
+		m.pressed[i] = 0
+		if m.cooldown[i] > 0 {
+			m.cooldown[i] -= 1
+			inputs[i] &^= sim.InputFire
+		} else if inputs[i].Has(sim.InputFire) {
+			m.cooldown[i] = FIRE_COOLDOWN - 1
+		}
+	}
+	return inputs
+}
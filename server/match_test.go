@@ -0,0 +1,76 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// testMatch starts a one player co-op match with a client that drops
+// whatever it's sent.
+func testMatch(t *testing.T) (*Match, *client) {
+	t.Helper()
+	m := newMatch(New(), "test", sim.Rules{Mode: sim.ModeCoop, Players: 1})
+	c := &client{send: func([]byte) {}, close: func() {}}
+	if err := m.join(c); err != nil {
+		t.Fatal(err)
+	}
+	return m, c
+}
+
+func TestInputsComeInOrder(t *testing.T) {
+	m, c := testMatch(t)
+	m.input(c, input{seq: 2, input: sim.InputLeft})
+	m.input(c, input{seq: 1, input: sim.InputRight})
+	m.input(c, input{seq: 2, input: sim.InputThrust})
+	if got := m.inputs()[0]; got != sim.InputLeft {
+		t.Errorf("got %v, want only the first input with seq 2", got)
+	}
+	m.input(c, input{seq: 3, input: sim.InputRight})
+	if got := m.inputs()[0]; got != sim.InputRight {
+		t.Errorf("got %v, want the input with seq 3", got)
+	}
+}
+
+func TestOnlyValidInputsAreTaken(t *testing.T) {
+	m, c := testMatch(t)
+	m.input(c, input{seq: 1, input: ^sim.Input(0)})
+	if got := m.inputs()[0]; got&^VALID_INPUTS != 0 {
+		t.Errorf("got %v, which has bits that aren't controls", got)
+	}
+}
+
+func TestPressBetweenTicks(t *testing.T) {
+	m, c := testMatch(t)
+	//Se aprieta y se suelta el disparo antes del tick, igual tiene que contar
+	m.input(c, input{seq: 1, input: sim.InputFire})
+	m.input(c, input{seq: 2})
+	if !m.inputs()[0].Has(sim.InputFire) {
+		t.Error("a shot pressed and released between two ticks was lost")
+	}
+	if m.inputs()[0].Has(sim.InputFire) {
+		t.Error("a shot counted on two ticks")
+	}
+}
+
+func TestFireCooldown(t *testing.T) {
+	m, c := testMatch(t)
+	shots := 0
+	for seq := 1; seq <= FIRE_COOLDOWN*10; seq++ {
+		m.input(c, input{seq: seq, input: sim.InputFire})
+		if m.inputs()[0].Has(sim.InputFire) {
+			shots += 1
+		}
+	}
+	if shots != 10 {
+		t.Errorf("got %d shots in %d ticks, want 10", shots, FIRE_COOLDOWN*10)
+	}
+}
+
+func TestAcksCantBeFromTheFuture(t *testing.T) {
+	m, c := testMatch(t)
+	m.input(c, input{seq: 1, ack: m.world.Tick + 10})
+	if c.ack != 0 {
+		t.Errorf("the client acknowledged tick %d before it was played", c.ack)
+	}
+}
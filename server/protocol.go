@@ -0,0 +1,152 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rodolfato/asteroids/sim"
+	"github.com/rodolfato/asteroids/wire"
+)
+
+// Version has to match between the server and its clients. It goes up
+// whenever the messages change.
+const Version = 3
+
+const magic = "ASTS"
+
+type messageKind uint8
+
+const (
+	msgHello messageKind = iota + 1
+	msgReject
+	msgWelcome
+	msgInput
+	msgSnapshot
+	msgBye
+)
+
+// hello asks for a place in the match with the given name, creating it with
+// the given rules if there's no such match yet.
+type hello struct {
+	version uint32
+	match   string
+	rules   sim.Rules
+}
+
+// welcome tells a client which player it is in its match, and whether the
+// match started.
+type welcome struct {
+	match   string
+	player  int
+	joined  int
+	started bool
+	rules   sim.Rules
+}
+
+// input is a client's controls for the latest tick, numbered so the server
+// can tell old ones apart, along with the last snapshot the client got.
+type input struct {
+	seq   int
+	input sim.Input
+	ack   int
+}
+
+func encodeHello(m hello) []byte {
+	w := wire.NewWriter(uint8(msgHello))
+	w.Bytes([]byte(magic))
+	w.U32(m.version)
+	w.String(m.match)
+	writeRules(w, m.rules)
+	return w.Data
+}
+
+func decodeHello(r *wire.Reader) (hello, error) {
+	if string(r.Bytes(len(magic))) != magic {
+		return hello{}, errors.New("not an asteroids client")
+	}
+	m := hello{version: r.U32()}
+	if r.Err == nil && m.version != Version {
+		return m, nil
+	}
+	m.match = r.String()
+	m.rules = readRules(r)
+	return m, r.Err
+}
+
+func encodeReject(reason string) []byte {
+	w := wire.NewWriter(uint8(msgReject))
+	w.String(reason)
+	return w.Data
+}
+
+func encodeWelcome(m welcome) []byte {
+	w := wire.NewWriter(uint8(msgWelcome))
+	w.String(m.match)
+	w.U8(m.player)
+	w.U8(m.joined)
+	w.Bool(m.started)
+	writeRules(w, m.rules)
+	return w.Data
+}
+
+func decodeWelcome(r *wire.Reader) (welcome, error) {
+	m := welcome{
+		match:   r.String(),
+		player:  r.U8(),
+		joined:  r.U8(),
+		started: r.Bool(),
+		rules:   readRules(r),
+	}
+	if m.player >= m.rules.Players {
+		return m, errors.New("bad welcome")
+	}
+	return m, r.Err
+}
+
+func encodeInput(m input) []byte {
+	w := wire.NewWriter(uint8(msgInput))
+	w.U32(uint32(m.seq))
+	w.U8(int(m.input))
+	w.U32(uint32(m.ack))
+	return w.Data
+}
+
+func decodeInput(r *wire.Reader) (input, error) {
+	m := input{seq: int(r.U32()), input: sim.Input(r.U8()), ack: int(r.U32())}
+	return m, r.Err
+}
+
+func encodeBye() []byte {
+	return wire.NewWriter(uint8(msgBye)).Data
+}
+
+func writeRules(w *wire.Writer, rules sim.Rules) {
+	w.U8(int(rules.Mode))
+	w.U8(rules.Players)
+	w.Bool(rules.FriendlyFire)
+	w.U8(rules.DuelRounds)
+	w.Bool(rules.DuelAsteroids)
+}
+
+func readRules(r *wire.Reader) sim.Rules {
+	return sim.Rules{
+		Mode:          sim.Mode(r.U8()),
+		Players:       r.U8(),
+		FriendlyFire:  r.Bool(),
+		DuelRounds:    r.U8(),
+		DuelAsteroids: r.Bool(),
+	}
+}
+
+// checkRules returns why a match can't be played with the rules, if it can't.
+func checkRules(rules sim.Rules) error {
+	switch {
+	case rules.Mode != sim.ModeCoop && rules.Mode != sim.ModeDuel:
+		return errors.New("only co-op and versus can be played on a server")
+	case rules.Players < 1 || rules.Players > sim.MAX_SHIPS:
+		return fmt.Errorf("a match is for 1 to %d players", sim.MAX_SHIPS)
+	case rules.Mode == sim.ModeDuel && rules.Players != 2:
+		return errors.New("a duel is for 2 players")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/rodolfato/asteroids/sim"
+	"github.com/rodolfato/asteroids/wire"
+)
+
+func TestBadHellos(t *testing.T) {
+	good := encodeHello(hello{version: Version, match: "friday", rules: sim.Rules{Mode: sim.ModeCoop, Players: 2}})
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not an asteroids client", append([]byte("ASTX"), good[1+len(magic):]...)},
+		{"cut in the magic", good[1:3]},
+		{"cut in the version", good[1 : 1+len(magic)+2]},
+		{"cut in the name", good[1 : 1+len(magic)+4+3]},
+		{"cut in the rules", good[1 : len(good)-1]},
+	}
+	for _, test := range tests {
+		if _, err := decodeHello(wire.NewReader(test.data)); err == nil {
+			t.Errorf("%s: the hello was taken", test.name)
+		}
+	}
+	m, err := decodeHello(wire.NewReader(good[1:]))
+	if err != nil || m.match != "friday" || m.rules.Players != 2 {
+		t.Errorf("a good hello came out as %+v, %v", m, err)
+	}
+}
+
+func TestHelloFromAnotherVersion(t *testing.T) {
+	//Una version distinta puede mandar otra cosa despues, alcanza con saber cual es
+	data := encodeHello(hello{version: Version + 1})
+	m, err := decodeHello(wire.NewReader(data[1 : 1+len(magic)+4]))
+	if err != nil || m.version != Version+1 {
+		t.Errorf("got %+v, %v", m, err)
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	tests := []struct {
+		rules sim.Rules
+		ok    bool
+	}{
+		{sim.Rules{Mode: sim.ModeCoop, Players: 1}, true},
+		{sim.Rules{Mode: sim.ModeCoop, Players: sim.MAX_SHIPS}, true},
+		{sim.Rules{Mode: sim.ModeDuel, Players: 2}, true},
+		{sim.Rules{Mode: sim.ModeTurns, Players: 2}, false},
+		{sim.Rules{Mode: sim.ModeCoop, Players: 0}, false},
+		{sim.Rules{Mode: sim.ModeCoop, Players: sim.MAX_SHIPS + 1}, false},
+		{sim.Rules{Mode: sim.ModeDuel, Players: 3}, false},
+	}
+	for _, test := range tests {
+		if err := checkRules(test.rules); (err == nil) != test.ok {
+			t.Errorf("%+v: got %v", test.rules, err)
+		}
+	}
+}
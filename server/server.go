@@ -0,0 +1,322 @@
+// Package server runs matches with no window for clients that connect over
+// TCP or UDP. Only the server simulates: the clients send it their controls,
+// it checks them and steps every match at the tick rate, and it sends every
+// client a snapshot of its match after each tick, delta compressed against
+// the last snapshot the client acknowledged.
+//
+// Clients ask for a match by name. The first to ask for a name creates the
+// match with its rules and the rest join it. A match starts once all its
+// players are in and ends when the last of them leaves, and the server can
+// run many at the same time.
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rodolfato/asteroids/spectate"
+	"github.com/rodolfato/asteroids/wire"
+)
+
+const (
+	DefaultMaxMatches = 64
+	// SNAPSHOT_HISTORY is how many ticks of snapshots are kept to encode the
+	// next ones against. A client whose last acknowledged snapshot is older
+	// gets a whole one.
+	SNAPSHOT_HISTORY   = 64
+	HANDSHAKE_INTERVAL = 200 * time.Millisecond
+	TIMEOUT            = 5 * time.Second
+	// MAX_MESSAGE_SIZE is the most a message can take, limited by the 16 bit
+	// length in front of every message sent over TCP.
+	MAX_MESSAGE_SIZE = 65535
+	// OUTGOING_QUEUE is how many messages can wait to be written to a TCP
+	// client. Snapshots that don't fit are dropped, as UDP would.
+	OUTGOING_QUEUE = 64
+)
+
+// client is a connection to the server, over either TCP or UDP.
+type client struct {
+	addr   net.Addr
+	send   func(data []byte)
+	close  func()
+	match  atomic.Pointer[Match]
+	player int
+	seq    int
+	ack    int
+	heard  time.Time
+}
+
+type Server struct {
+	MaxMatches int
+	// Spectators, if set, get every match streamed under its name.
+	Spectators *spectate.Hub
+	tcp        net.Listener
+	udp        net.PacketConn
+	mu         sync.Mutex
+	matches    map[string]*Match
+	remoteMu   sync.Mutex
+	remote     map[string]*client
+}
+
+func New() *Server {
+	return &Server{
+		MaxMatches: DefaultMaxMatches,
+		matches:    map[string]*Match{},
+		remote:     map[string]*client{},
+	}
+}
+
+// Listen opens the TCP and UDP sockets, both on the same port.
+func (s *Server) Listen(addr string) error {
+	tcp, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	udp, err := net.ListenPacket("udp", tcp.Addr().String())
+	if err != nil {
+		tcp.Close()
+		return err
+	}
+	s.tcp, s.udp = tcp, udp
+	return nil
+}
+
+// Serve accepts clients until the server is closed.
+func (s *Server) Serve() error {
+	go s.serveUDP()
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.serveTCP(conn)
+	}
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	if err := s.Listen(addr); err != nil {
+		return err
+	}
+	return s.Serve()
+}
+
+func (s *Server) Addr() net.Addr {
+	return s.tcp.Addr()
+}
+
+func (s *Server) Close() error {
+	s.udp.Close()
+	return s.tcp.Close()
+}
+
+// Matches returns how many matches are running.
+func (s *Server) Matches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.matches)
+}
+
+func (s *Server) serveUDP() {
+	buf := make([]byte, MAX_MESSAGE_SIZE)
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Println("server:", err)
+			}
+			return
+		}
+		s.remoteMu.Lock()
+		c := s.remote[addr.String()]
+		if c == nil && n > 0 && messageKind(buf[0]) == msgHello {
+			c = &client{addr: addr}
+			c.send = func(data []byte) {
+				s.udp.WriteTo(data, addr)
+			}
+			c.close = func() {
+				s.remoteMu.Lock()
+				delete(s.remote, addr.String())
+				s.remoteMu.Unlock()
+			}
+			s.remote[addr.String()] = c
+		}
+		s.remoteMu.Unlock()
+		if c == nil {
+			continue
+		}
+		if err := s.handle(c, buf[:n]); err != nil {
+			log.Printf("server: bad message from %s: %v", addr, err)
+			//Si no entro a ninguna partida no se lo recuerda, cualquiera puede mandar un saludo falso
+			if c.match.Load() == nil {
+				c.close()
+			}
+		}
+	}
+}
+
+func (s *Server) serveTCP(conn net.Conn) {
+	outgoing := make(chan []byte, OUTGOING_QUEUE)
+	var mu sync.Mutex
+	closed := false
+	c := &client{addr: conn.RemoteAddr()}
+	c.send = func(data []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case outgoing <- data:
+		default:
+		}
+	}
+	c.close = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			closed = true
+			close(outgoing)
+		}
+	}
+	go func() {
+		for data := range outgoing {
+			if err := writeFrame(conn, data); err != nil {
+				break
+			}
+		}
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		data, err := readFrame(r)
+		if err != nil {
+			break
+		}
+		if err := s.handle(c, data); err != nil {
+			log.Printf("server: bad message from %s: %v", c.addr, err)
+		}
+	}
+	if m := c.match.Load(); m != nil {
+		m.leave(c, "disconnected")
+	}
+	c.close()
+}
+
+func (s *Server) handle(c *client, data []byte) error {
+	if len(data) == 0 {
+		return wire.ErrShortMessage
+	}
+	r := wire.NewReader(data[1:])
+	switch messageKind(data[0]) {
+	case msgHello:
+		m, err := decodeHello(r)
+		if err != nil {
+			return err
+		}
+		s.join(c, m)
+	case msgInput:
+		m, err := decodeInput(r)
+		if err != nil {
+			return err
+		}
+		if match := c.match.Load(); match != nil {
+			match.input(c, m)
+		}
+	case msgBye:
+		if match := c.match.Load(); match != nil {
+			match.leave(c, "left")
+		}
+		c.close()
+	default:
+		return fmt.Errorf("unknown message %d", data[0])
+	}
+	return nil
+}
+
+// join puts the client in the match it asks for, creating the match if it
+// doesn't exist yet.
+func (s *Server) join(c *client, m hello) {
+	if match := c.match.Load(); match != nil {
+		//Ya esta en una partida, se le vuelve a dar la bienvenida por si se perdio
+		match.hello(c)
+		return
+	}
+	reason := ""
+	if m.version != Version {
+		reason = fmt.Sprintf("the server plays version %d and you %d", Version, m.version)
+	} else if m.match == "" {
+		reason = "no match name"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	match := s.matches[m.match]
+	if match != nil && match.ended() {
+		match = nil
+	}
+	if reason == "" && match == nil {
+		if err := checkRules(m.rules); err != nil {
+			reason = err.Error()
+		} else if len(s.matches) >= s.MaxMatches {
+			reason = "the server is full"
+		} else {
+			match = newMatch(s, m.match, m.rules)
+			s.matches[m.match] = match
+			log.Printf("server: match %q created for %d players", m.match, m.rules.Players)
+			go match.run()
+		}
+	}
+	if reason == "" {
+		if err := match.join(c); err != nil {
+			reason = err.Error()
+		}
+	}
+	if reason != "" {
+		c.send(encodeReject(reason))
+		c.close()
+	}
+}
+
+// remove forgets a match that ended.
+func (s *Server) remove(m *Match) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.matches[m.name] == m {
+		delete(s.matches, m.name)
+		if s.Spectators != nil {
+			s.Spectators.End(m.name)
+		}
+	}
+	log.Printf("server: match %q ended", m.name)
+}
+
+// writeFrame writes a message over a stream, preceded by its length.
+func writeFrame(w io.Writer, data []byte) error {
+	if len(data) > MAX_MESSAGE_SIZE {
+		return errors.New("message too long")
+	}
+	frame := binary.LittleEndian.AppendUint16(nil, uint16(len(data)))
+	_, err := w.Write(append(frame, data...))
+	return err
+}
+
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	var size [2]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.LittleEndian.Uint16(size[:]))
+	_, err := io.ReadFull(r, data)
+	return data, err
+}
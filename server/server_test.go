@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+func TestBadHellosAreForgotten(t *testing.T) {
+	s := New()
+	if err := s.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve()
+	defer s.Close()
+
+	good := encodeHello(hello{version: Version, match: "friday", rules: sim.Rules{Mode: sim.ModeCoop, Players: 2}})
+	for range 20 {
+		conn, err := net.Dial("udp", s.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write(good[:len(good)-1])
+		conn.Close()
+	}
+
+	//El servidor atiende en orden, cuando rechaza este saludo ya vio los anteriores
+	conn, err := net.Dial("udp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write(encodeHello(hello{version: Version + 1}))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, MAX_MESSAGE_SIZE)
+	n, err := conn.Read(buf)
+	if err != nil || n == 0 || messageKind(buf[0]) != msgReject {
+		t.Fatalf("the server didn't reject a hello from another version: %v", err)
+	}
+
+	s.remoteMu.Lock()
+	defer s.remoteMu.Unlock()
+	if len(s.remote) != 0 {
+		t.Errorf("the server remembers %d clients that never joined", len(s.remote))
+	}
+}
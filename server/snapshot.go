@@ -0,0 +1,399 @@
+package server
+
+import (
+	"errors"
+	"math"
+
+	"github.com/rodolfato/asteroids/sim"
+	"github.com/rodolfato/asteroids/wire"
+)
+
+const (
+	// SHAPE_SCALE is how many steps per pixel the asteroid outlines are sent
+	// with, as 16 bit offsets from the asteroid's center.
+	SHAPE_SCALE = 8
+	// DRIFT_TOLERANCE is how far, in pixels, an asteroid can get from where
+	// its track says it is before it's given a new track.
+	DRIFT_TOLERANCE = 0.25
+)
+
+// Snapshot is what a client sees of a match at one tick: everything needed to
+// draw it and nothing that's only needed to simulate it.
+type Snapshot struct {
+	Tick          int
+	Phase         sim.Phase
+	Wave          int
+	WaveStartTick int
+	WaveMass      float32
+	Round         int
+	Current       int
+	Players       []PlayerState
+	Ships         []ShipState
+	Projectiles   []ProjectileState
+	Asteroids     []AsteroidState
+	Events        []sim.Event
+}
+
+type PlayerState struct {
+	Score  int
+	Lives  int
+	Rounds int
+}
+
+type ShipState struct {
+	Player        int
+	Pos           sim.Vector2
+	Vel           sim.Vector2
+	Orientation   float32
+	Thrusting     bool
+	Collision     bool
+	DestroyedTime float64
+	Wreck         []sim.HullSegment
+}
+
+type ProjectileState struct {
+	Player int
+	Pos    sim.Vector2
+}
+
+// AsteroidState is an asteroid as the clients see it. Its position is always
+// the one its track gives for the snapshot's tick.
+type AsteroidState struct {
+	ID          int
+	Pos         sim.Vector2
+	Track       Track
+	Orientation float32
+	Class       int
+	Shape       []sim.Vector2
+}
+
+// Track is where an asteroid was at a tick and its velocity. Nothing pushes
+// an asteroid once it's made, so that's enough to know where it is at any
+// later tick, give or take the rounding of the simulation.
+type Track struct {
+	Tick int
+	Pos  sim.Vector2
+	Vel  sim.Vector2
+}
+
+// At returns the position of the asteroid at the tick. The products are
+// rounded on their own so every machine gets the same result.
+func (t Track) At(tick int) sim.Vector2 {
+	ticks := float32(tick - t.Tick)
+	pos := sim.NewVector2(t.Pos.X+float32(t.Vel.X*ticks), t.Pos.Y+float32(t.Vel.Y*ticks))
+	sim.ResetPosition(&pos)
+	return pos
+}
+
+// Capture takes a snapshot of the world as it is after its last step. The
+// asteroids keep their tracks from the previous snapshot, if there's one,
+// for as long as those stay within DRIFT_TOLERANCE of where they really are.
+func Capture(w *sim.World, previous *Snapshot) *Snapshot {
+	s := &Snapshot{
+		Tick:          w.Tick,
+		Phase:         w.Phase,
+		Wave:          w.Wave,
+		WaveStartTick: w.WaveStartTick,
+		WaveMass:      w.WaveMass,
+		Round:         w.Round,
+		Current:       w.Current,
+		Events:        append([]sim.Event(nil), w.Events...),
+	}
+	for _, p := range w.Players {
+		s.Players = append(s.Players, PlayerState{Score: p.Score, Lives: p.Lives, Rounds: p.Rounds})
+	}
+	for _, ship := range w.Ships {
+		s.Ships = append(s.Ships, ShipState{
+			Player:        ship.Player,
+			Pos:           ship.Pos,
+			Vel:           ship.Vel,
+			Orientation:   ship.Orientation,
+			Thrusting:     ship.Thrusting,
+			Collision:     ship.Collision,
+			DestroyedTime: ship.DestroyedTime,
+			Wreck:         append([]sim.HullSegment(nil), ship.Wreck...),
+		})
+		for _, p := range ship.Projectiles {
+			s.Projectiles = append(s.Projectiles, ProjectileState{Player: ship.Player, Pos: p.Pos})
+		}
+	}
+	tracks := map[int]Track{}
+	if previous != nil {
+		for _, a := range previous.Asteroids {
+			tracks[a.ID] = a.Track
+		}
+	}
+	for _, a := range w.Asteroids {
+		track, ok := tracks[a.ID]
+		if !ok || track.Vel != a.Vel || sim.Vector2Distance(track.At(w.Tick), a.Pos) > DRIFT_TOLERANCE {
+			track = Track{Tick: w.Tick, Pos: a.Pos, Vel: a.Vel}
+		}
+		s.Asteroids = append(s.Asteroids, AsteroidState{
+			ID:          a.ID,
+			Pos:         track.At(w.Tick),
+			Track:       track,
+			Orientation: a.Orientation,
+			Class:       a.Class,
+			Shape:       a.Shape,
+		})
+	}
+	return s
+}
+
+// World rebuilds a world out of the snapshot that the game can draw. It can't
+// be stepped: whatever isn't in the snapshot is left empty.
+func (s *Snapshot) World(rules sim.Rules) *sim.World {
+	w := &sim.World{
+		Rules:         rules,
+		Tick:          s.Tick,
+		Phase:         s.Phase,
+		Wave:          s.Wave,
+		WaveStartTick: s.WaveStartTick,
+		WaveMass:      s.WaveMass,
+		Round:         s.Round,
+		Current:       s.Current,
+		Events:        s.Events,
+	}
+	for _, p := range s.Players {
+		w.Players = append(w.Players, &sim.Player{Score: p.Score, Lives: p.Lives, Rounds: p.Rounds})
+	}
+	for _, state := range s.Ships {
+		ship := &sim.PlayerShip{
+			Pos:           state.Pos,
+			Vel:           state.Vel,
+			Orientation:   state.Orientation,
+			Size:          sim.PLAYER_SHIP_SIZE,
+			Thrusting:     state.Thrusting,
+			Collision:     state.Collision,
+			DestroyedTime: state.DestroyedTime,
+			Wreck:         state.Wreck,
+			Player:        state.Player,
+		}
+		for _, p := range s.Projectiles {
+			if p.Player == state.Player {
+				ship.Projectiles = append(ship.Projectiles, sim.Projectile{Pos: p.Pos, Size: sim.PROJECTILE_SIZE})
+			}
+		}
+		if state.Player < len(w.Players) {
+			w.Players[state.Player].Ship = ship
+		}
+		w.Ships = append(w.Ships, ship)
+	}
+	for _, a := range s.Asteroids {
+		w.Asteroids = append(w.Asteroids, sim.Asteroid{
+			ID:          a.ID,
+			Pos:         a.Pos,
+			Orientation: a.Orientation,
+			Class:       a.Class,
+			Shape:       a.Shape,
+		})
+	}
+	return w
+}
+
+// encodeSnapshot writes the snapshot as a delta from the baseline, a snapshot
+// the client is known to have. The players, ships and projectiles are small
+// and change every tick, so they always go whole. The asteroids are most of
+// the snapshot, and since their outlines never change only the new ones go
+// whole. The rest only go when they were given a new track, which hardly
+// ever happens, and the ones that are gone by their ids. Without a baseline
+// every asteroid is new. With a dozen asteroids on the field that takes a
+// delta from about 244 bytes, when every asteroid's position went every
+// tick, down to about 100.
+func encodeSnapshot(s, baseline *Snapshot) []byte {
+	w := wire.NewWriter(uint8(msgSnapshot))
+	w.U32(uint32(s.Tick))
+	base := map[int]AsteroidState{}
+	if baseline != nil {
+		w.U32(uint32(baseline.Tick))
+		for _, a := range baseline.Asteroids {
+			base[a.ID] = a
+		}
+	} else {
+		w.U32(0)
+	}
+	w.U8(int(s.Phase))
+	w.U16(s.Wave)
+	w.U32(uint32(s.WaveStartTick))
+	w.F32(s.WaveMass)
+	w.U8(s.Round)
+	w.U8(s.Current)
+
+	w.U8(len(s.Players))
+	for _, p := range s.Players {
+		w.U32(uint32(p.Score))
+		w.U8(p.Lives)
+		w.U8(p.Rounds)
+	}
+	w.U8(len(s.Ships))
+	for _, ship := range s.Ships {
+		w.U8(ship.Player)
+		writeVector(w, ship.Pos)
+		writeVector(w, ship.Vel)
+		w.F32(ship.Orientation)
+		w.Bool(ship.Thrusting)
+		w.Bool(ship.Collision)
+		w.F32(float32(ship.DestroyedTime))
+		w.U8(len(ship.Wreck))
+		for _, segment := range ship.Wreck {
+			writeVector(w, segment.Pos)
+			writeVector(w, segment.Half)
+			w.F32(segment.Angle)
+		}
+	}
+	w.U16(len(s.Projectiles))
+	for _, p := range s.Projectiles {
+		w.U8(p.Player)
+		writeVector(w, p.Pos)
+	}
+
+	moved, added := []AsteroidState{}, []AsteroidState{}
+	for _, a := range s.Asteroids {
+		old, ok := base[a.ID]
+		switch {
+		case !ok:
+			added = append(added, a)
+		case old.Track != a.Track:
+			moved = append(moved, a)
+		}
+		delete(base, a.ID)
+	}
+	//Lo que queda en la base son los asteroides que ya no estan
+	w.U16(len(base))
+	for id := range base {
+		w.U32(uint32(id))
+	}
+	w.U16(len(moved))
+	for _, a := range moved {
+		w.U32(uint32(a.ID))
+		writeTrack(w, a.Track)
+	}
+	w.U16(len(added))
+	for _, a := range added {
+		w.U32(uint32(a.ID))
+		writeTrack(w, a.Track)
+		w.F32(a.Orientation)
+		w.U8(a.Class)
+		w.U8(len(a.Shape))
+		for _, point := range a.Shape {
+			w.U16(int(int16(math.Round(float64(point.X * SHAPE_SCALE)))))
+			w.U16(int(int16(math.Round(float64(point.Y * SHAPE_SCALE)))))
+		}
+	}
+
+	w.U8(len(s.Events))
+	for _, e := range s.Events {
+		w.U8(int(e.Kind))
+		w.U8(e.Player)
+		writeVector(w, e.Pos)
+		writeVector(w, e.Vel)
+		w.F32(e.Angle)
+		w.U8(e.Class)
+		w.U8(int(e.Cause))
+	}
+	return w.Data
+}
+
+// decodeSnapshot reads a snapshot, looking up the baseline it was encoded
+// against. It fails if the baseline is one we no longer have.
+func decodeSnapshot(r *wire.Reader, baselines func(tick int) *Snapshot) (*Snapshot, error) {
+	s := &Snapshot{Tick: int(r.U32())}
+	var baseline *Snapshot
+	if tick := int(r.U32()); tick > 0 {
+		if baseline = baselines(tick); baseline == nil {
+			return nil, errors.New("unknown baseline")
+		}
+	}
+	s.Phase = sim.Phase(r.U8())
+	s.Wave = r.U16()
+	s.WaveStartTick = int(r.U32())
+	s.WaveMass = r.F32()
+	s.Round = r.U8()
+	s.Current = r.U8()
+
+	for range r.U8() {
+		s.Players = append(s.Players, PlayerState{Score: int(r.U32()), Lives: r.U8(), Rounds: r.U8()})
+	}
+	for range r.U8() {
+		ship := ShipState{
+			Player:        r.U8(),
+			Pos:           readVector(r),
+			Vel:           readVector(r),
+			Orientation:   r.F32(),
+			Thrusting:     r.Bool(),
+			Collision:     r.Bool(),
+			DestroyedTime: float64(r.F32()),
+		}
+		for range r.U8() {
+			ship.Wreck = append(ship.Wreck, sim.HullSegment{Pos: readVector(r), Half: readVector(r), Angle: r.F32()})
+		}
+		s.Ships = append(s.Ships, ship)
+	}
+	for range r.U16() {
+		s.Projectiles = append(s.Projectiles, ProjectileState{Player: r.U8(), Pos: readVector(r)})
+	}
+
+	gone := map[int]bool{}
+	for range r.U16() {
+		gone[int(r.U32())] = true
+	}
+	moved := map[int]Track{}
+	for range r.U16() {
+		id := int(r.U32())
+		moved[id] = readTrack(r)
+	}
+	if baseline != nil {
+		for _, a := range baseline.Asteroids {
+			if gone[a.ID] {
+				continue
+			}
+			if track, ok := moved[a.ID]; ok {
+				a.Track = track
+			}
+			a.Pos = a.Track.At(s.Tick)
+			s.Asteroids = append(s.Asteroids, a)
+		}
+	}
+	for range r.U16() {
+		a := AsteroidState{ID: int(r.U32()), Track: readTrack(r), Orientation: r.F32(), Class: int(int8(r.U8()))}
+		a.Pos = a.Track.At(s.Tick)
+		for range r.U8() {
+			x := float32(int16(r.U16())) / SHAPE_SCALE
+			y := float32(int16(r.U16())) / SHAPE_SCALE
+			a.Shape = append(a.Shape, sim.NewVector2(x, y))
+		}
+		s.Asteroids = append(s.Asteroids, a)
+	}
+
+	for range r.U8() {
+		s.Events = append(s.Events, sim.Event{
+			Kind:   sim.EventKind(r.U8()),
+			Player: r.U8(),
+			Pos:    readVector(r),
+			Vel:    readVector(r),
+			Angle:  r.F32(),
+			Class:  int(int8(r.U8())),
+			Cause:  sim.Cause(r.U8()),
+		})
+	}
+	return s, r.Err
+}
+
+func writeVector(w *wire.Writer, v sim.Vector2) {
+	w.F32(v.X)
+	w.F32(v.Y)
+}
+
+func readVector(r *wire.Reader) sim.Vector2 {
+	return sim.NewVector2(r.F32(), r.F32())
+}
+
+func writeTrack(w *wire.Writer, t Track) {
+	w.U32(uint32(t.Tick))
+	writeVector(w, t.Pos)
+	writeVector(w, t.Vel)
+}
+
+func readTrack(r *wire.Reader) Track {
+	return Track{Tick: int(r.U32()), Pos: readVector(r), Vel: readVector(r)}
+}
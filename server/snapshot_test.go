@@ -0,0 +1,163 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rodolfato/asteroids/sim"
+	"github.com/rodolfato/asteroids/wire"
+)
+
+const TEST_TICKS = 600
+
+// capture plays a co-op game with both ships turning and firing, so
+// asteroids break and new ones keep showing up, and takes a snapshot of
+// every tick.
+func capture() []*Snapshot {
+	w := sim.NewWorld(1, sim.Rules{Mode: sim.ModeCoop, Players: 2})
+	snapshots := []*Snapshot{}
+	var previous *Snapshot
+	for tick := range TEST_TICKS {
+		in := sim.InputLeft | sim.InputThrust*sim.Input(tick/50%2)
+		if tick%5 == 0 {
+			in |= sim.InputFire
+		}
+		w.Step([]sim.Input{in, in | sim.InputReverse})
+		previous = Capture(w, previous)
+		snapshots = append(snapshots, previous)
+	}
+	return snapshots
+}
+
+// sameSnapshot fails unless got is what the client should see of want. The
+// outlines lose some precision on the way, the time a ship was destroyed goes
+// as a float32 and the pieces of a wreck only as they're drawn, everything
+// else has to be the same.
+func sameSnapshot(t *testing.T, want, got *Snapshot) {
+	t.Helper()
+	if got.Tick != want.Tick || got.Phase != want.Phase || got.Wave != want.Wave || got.Current != want.Current {
+		t.Fatalf("tick %d: got the header of tick %d", want.Tick, got.Tick)
+	}
+	if !reflect.DeepEqual(got.Players, want.Players) || !reflect.DeepEqual(got.Projectiles, want.Projectiles) {
+		t.Fatalf("tick %d: the players or the projectiles changed on the way", want.Tick)
+	}
+	if len(got.Ships) != len(want.Ships) {
+		t.Fatalf("tick %d: got %d ships, want %d", want.Tick, len(got.Ships), len(want.Ships))
+	}
+	for i := range want.Ships {
+		ship := want.Ships[i]
+		ship.DestroyedTime = float64(float32(ship.DestroyedTime))
+		ship.Wreck = append([]sim.HullSegment(nil), ship.Wreck...)
+		for j := range ship.Wreck {
+			ship.Wreck[j].Vel, ship.Wreck[j].Spin = sim.Vector2{}, 0
+		}
+		if !reflect.DeepEqual(got.Ships[i], ship) {
+			t.Fatalf("tick %d: ship %d changed on the way", want.Tick, i)
+		}
+	}
+	if len(got.Asteroids) != len(want.Asteroids) {
+		t.Fatalf("tick %d: got %d asteroids, want %d", want.Tick, len(got.Asteroids), len(want.Asteroids))
+	}
+	asteroids := map[int]AsteroidState{}
+	for _, a := range got.Asteroids {
+		asteroids[a.ID] = a
+	}
+	for _, a := range want.Asteroids {
+		other, ok := asteroids[a.ID]
+		if !ok || other.Pos != a.Pos || other.Track != a.Track || other.Class != a.Class || len(other.Shape) != len(a.Shape) {
+			t.Fatalf("tick %d: asteroid %d changed on the way", want.Tick, a.ID)
+		}
+		for i := range a.Shape {
+			if sim.Vector2Distance(a.Shape[i], other.Shape[i]) > 1.0/SHAPE_SCALE {
+				t.Fatalf("tick %d: the outline of asteroid %d changed on the way", want.Tick, a.ID)
+			}
+		}
+	}
+	if len(got.Events) != len(want.Events) {
+		t.Fatalf("tick %d: got %d events, want %d", want.Tick, len(got.Events), len(want.Events))
+	}
+}
+
+func TestSnapshotWithoutBaseline(t *testing.T) {
+	for _, s := range capture() {
+		data := encodeSnapshot(s, nil)
+		got, err := decodeSnapshot(wire.NewReader(data[1:]), func(int) *Snapshot { return nil })
+		if err != nil {
+			t.Fatalf("tick %d: %v", s.Tick, err)
+		}
+		sameSnapshot(t, s, got)
+	}
+}
+
+func TestSnapshotAgainstBaseline(t *testing.T) {
+	snapshots := capture()
+	for _, age := range []int{1, 5, SNAPSHOT_HISTORY - 1} {
+		//Cada instantanea se decodifica contra la que decodifico el cliente, no la del servidor
+		decoded := map[int]*Snapshot{}
+		lookup := func(tick int) *Snapshot { return decoded[tick] }
+		for i, s := range snapshots {
+			var baseline *Snapshot
+			if i >= age {
+				baseline = snapshots[i-age]
+			}
+			data := encodeSnapshot(s, baseline)
+			got, err := decodeSnapshot(wire.NewReader(data[1:]), lookup)
+			if err != nil {
+				t.Fatalf("tick %d against %d ticks before: %v", s.Tick, age, err)
+			}
+			sameSnapshot(t, s, got)
+			decoded[s.Tick] = got
+		}
+	}
+}
+
+func TestSnapshotDeltaLeavesOutSteadyAsteroids(t *testing.T) {
+	snapshots := capture()
+	steady := 0
+	for i := 1; i < len(snapshots); i++ {
+		s, baseline := snapshots[i], snapshots[i-1]
+		if !reflect.DeepEqual(tracks(s), tracks(baseline)) {
+			continue
+		}
+		//Si ningun asteroide cambio, el delta pesa lo mismo que sin asteroides
+		empty := *s
+		empty.Asteroids = nil
+		if delta, want := len(encodeSnapshot(s, baseline)), len(encodeSnapshot(&empty, nil)); delta != want {
+			t.Errorf("tick %d: the delta takes %d bytes, want %d", s.Tick, delta, want)
+		}
+		steady += 1
+	}
+	if steady < TEST_TICKS/2 {
+		t.Errorf("only %d ticks of %d had no asteroid changing its track", steady, TEST_TICKS)
+	}
+}
+
+func tracks(s *Snapshot) map[int]Track {
+	tracks := map[int]Track{}
+	for _, a := range s.Asteroids {
+		tracks[a.ID] = a.Track
+	}
+	return tracks
+}
+
+func TestSnapshotNeedsItsBaseline(t *testing.T) {
+	snapshots := capture()
+	data := encodeSnapshot(snapshots[10], snapshots[9])
+	if _, err := decodeSnapshot(wire.NewReader(data[1:]), func(int) *Snapshot { return nil }); err == nil {
+		t.Error("a delta was decoded without its baseline")
+	}
+}
+
+func TestTracksStayOnTheAsteroids(t *testing.T) {
+	w := sim.NewWorld(2, sim.Rules{Mode: sim.ModeCoop, Players: 1})
+	var s *Snapshot
+	for range TEST_TICKS {
+		w.Step([]sim.Input{0})
+		s = Capture(w, s)
+		for i, a := range w.Asteroids {
+			if d := sim.Vector2Distance(s.Asteroids[i].Pos, a.Pos); d > DRIFT_TOLERANCE {
+				t.Fatalf("tick %d: asteroid %d is drawn %v pixels from where it is", w.Tick, a.ID, d)
+			}
+		}
+	}
+}
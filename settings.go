@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// Settings are the user's preferences, kept between games in a JSON file in
+// the user's configuration directory.
+type Settings struct {
+	MasterVolume  float32 `json:"master_volume"`
+	SFXVolume     float32 `json:"sfx_volume"`
+	MusicVolume   float32 `json:"music_volume"`
+	Muted         bool    `json:"muted"`
+	CoopShips     int     `json:"coop_ships"`
+	FriendlyFire  bool    `json:"friendly_fire"`
+	DuelRounds    int     `json:"duel_rounds"`
+	DuelAsteroids bool    `json:"duel_asteroids"`
+}
+
+func defaultSettings() Settings {
+	return Settings{
+		MasterVolume:  1,
+		SFXVolume:     1,
+		MusicVolume:   0.7,
+		Muted:         false,
+		CoopShips:     2,
+		FriendlyFire:  false,
+		DuelRounds:    3,
+		DuelAsteroids: false,
+	}
+}
+
+func settingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "asteroids", "settings.json"), nil
+}
+
+// loadSettings reads the settings file, falling back to the defaults for
+// anything it can't read.
+func loadSettings() Settings {
+	settings := defaultSettings()
+	path, err := settingsPath()
+	if err != nil {
+		log.Println("Can't find the settings file:", err)
+		return settings
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return settings
+	}
+	if err != nil {
+		log.Println("Can't read the settings file:", err)
+		return settings
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		log.Println("Can't parse the settings file:", err)
+		return defaultSettings()
+	}
+	settings.CoopShips = max(2, min(sim.MAX_SHIPS, settings.CoopShips))
+	settings.DuelRounds = max(1, min(MAX_DUEL_ROUNDS, settings.DuelRounds|1))
+	return settings
+}
+
+func (s Settings) save() error {
+	path, err := settingsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
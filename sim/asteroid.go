@@ -0,0 +1,208 @@
+package sim
+
+import "math"
+
+// SplitRule describes how an asteroid of a given class breaks when it's shot.
+// An asteroid belongs to the first class whose minMass it reaches.
+type SplitRule struct {
+	minMass    float32
+	cuts       int
+	speedScale float32
+}
+
+// SPLIT_RULES is indexed by the asteroid class, 0 being the biggest rocks.
+// Fragments lighter than the last class are turned into dust.
+var SPLIT_RULES = []SplitRule{
+	{minMass: 4500, cuts: 1, speedScale: 1.0},
+	{minMass: 1400, cuts: 1, speedScale: 2.0},
+	{minMass: 200, cuts: 0},
+}
+
+// Asteroid is a rock in the field. ID stays the same for as long as the rock
+// lasts, so it can be followed from one snapshot of the world to the next.
+type Asteroid struct {
+	ID          int
+	Pos         Vector2
+	Speed       float32
+	Vel         Vector2
+	Orientation float32
+	Size        float32
+	Shape       []Vector2
+	Class       int
+}
+
+func (w *World) generateAsteroids() []Asteroid {
+
+	asteroids := []Asteroid{}
+	positions := make(map[Vector2]bool)
+
+	tuning := w.Rules.Tuned()
+	for range tuning.Asteroids {
+		cdX := w.rng.Float32() * SCREEN_SIZE_X
+		cdY := w.rng.Float32() * SCREEN_SIZE_Y
+		orientation := w.rng.Float32() * (math.Pi * 2)
+		directionX := float32(math.Cos(float64(orientation)))
+		directionY := float32(math.Sin(float64(orientation)))
+		speed := w.rng.Float32() * tuning.AsteroidSpeed
+		_, ok := positions[NewVector2(cdX, cdY)]
+		for ok {
+			cdX := w.rng.Float32() * SCREEN_SIZE_X
+			cdY := w.rng.Float32() * SCREEN_SIZE_Y
+			_, ok = positions[NewVector2(cdX, cdY)]
+		}
+		positions[NewVector2(cdX, cdY)] = true
+		asteroid := Asteroid{
+			ID:          w.newID(),
+			Pos:         NewVector2(cdX, cdY),
+			Speed:       speed,
+			Vel:         Vector2Scale(NewVector2(directionX, directionY), speed),
+			Size:        ASTEROID_SIZE,
+			Orientation: orientation,
+			Shape:       w.generateShape(ASTEROID_SIZE),
+			Class:       0,
+		}
+		asteroids = append(asteroids, asteroid)
+	}
+	return asteroids
+}
+
+func (w *World) generateShape(size float32) []Vector2 {
+	shape := []Vector2{}
+	for i := range ASTEROID_POINTS {
+		angle := float32(i) * (math.Pi * 2) / ASTEROID_POINTS
+		shape = append(shape, Vector2Scale(GetDirection(angle), size*((w.rng.Float32()*0.6)+0.6)))
+	}
+	return shape
+}
+
+// newFragment turns a piece of a broken asteroid, given in screen
+// coordinates, into an asteroid centered on the piece's centroid.
+func newFragment(points []Vector2, vel Vector2) Asteroid {
+	center := polygonCentroid(points)
+	shape := make([]Vector2, len(points))
+	for i := range points {
+		shape[i] = Vector2Subtract(points[i], center)
+	}
+	mass := polygonArea(points)
+	return Asteroid{
+		Pos:         center,
+		Speed:       Vector2Length(vel),
+		Vel:         vel,
+		Size:        float32(math.Sqrt(float64(mass) / math.Pi)),
+		Orientation: 0,
+		Shape:       shape,
+		Class:       asteroidClass(mass),
+	}
+}
+
+// asteroidClass returns the SPLIT_RULES class for the given mass, or -1 if
+// it is too light to be an asteroid at all.
+func asteroidClass(mass float32) int {
+	for i, rule := range SPLIT_RULES {
+		if mass >= rule.minMass {
+			return i
+		}
+	}
+	return -1
+}
+
+// Points returns the outline of the asteroid in screen coordinates.
+func (a *Asteroid) Points() []Vector2 {
+	points := make([]Vector2, len(a.Shape))
+	for i := range a.Shape {
+		points[i] = Vector2Add(Vector2Rotate(a.Shape[i], a.Orientation), a.Pos)
+	}
+	return points
+}
+
+// Radius is how far the outline of the asteroid gets from its center.
+func (a *Asteroid) Radius() float32 {
+	r := float32(0)
+	for _, point := range a.Shape {
+		r = max(r, Vector2Length(point))
+	}
+	return r
+}
+
+func (a *Asteroid) mass() float32 {
+	return polygonArea(a.Shape)
+}
+
+// split fractures the asteroid along a line through the projectile's hit
+// point, following SPLIT_RULES. The fragments keep the pieces of the parent's
+// outline and carry all the momentum of the rock and the bullet between them,
+// slivers too small to be asteroids turning into dust.
+func (w *World) split(a *Asteroid, p Projectile) []Asteroid {
+	if a.Class < 0 || a.Class >= len(SPLIT_RULES) || SPLIT_RULES[a.Class].cuts < 1 {
+		return nil
+	}
+	rule := SPLIT_RULES[a.Class]
+
+	momentum := Vector2Add(Vector2Scale(a.Vel, a.mass()), Vector2Scale(p.Vel, PROJECTILE_MASS))
+
+	impact := Vector2Subtract(p.Vel, a.Vel)
+	impactAngle := float32(math.Atan2(float64(impact.Y), float64(impact.X)))
+	cutAngle := impactAngle + SPLIT_SPREAD_ANGLE*(w.rng.Float32()-0.5)
+
+	pieces := [][]Vector2{a.Points()}
+	for i := range rule.cuts {
+		direction := GetDirection(cutAngle + float32(i)*math.Pi/float32(rule.cuts))
+		cut := [][]Vector2{}
+		for _, piece := range pieces {
+			cut = append(cut, cutPolygon(piece, p.Pos, direction, polygonCentroid(piece))...)
+		}
+		pieces = cut
+	}
+
+	//Las astillas se vuelven polvo, el resto se lleva todo el momento
+	kept := [][]Vector2{}
+	keptMass := float32(0)
+	center := Vector2Zero()
+	for _, piece := range pieces {
+		mass := polygonArea(piece)
+		if asteroidClass(mass) < 0 {
+			continue
+		}
+		kept = append(kept, piece)
+		keptMass += mass
+		center = Vector2Add(center, Vector2Scale(polygonCentroid(piece), mass))
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	center = Vector2Scale(center, 1/keptMass)
+	centerVel := Vector2Scale(momentum, 1/keptMass)
+
+	//Los fragmentos se alejan del centro de masa de los que quedan, asi la suma de sus momentos es cero
+	kick := SPLIT_SPEED * rule.speedScale / max(a.Size, 1)
+	fragments := []Asteroid{}
+	for _, piece := range kept {
+		offset := Vector2Subtract(polygonCentroid(piece), center)
+		fragment := newFragment(piece, Vector2Add(centerVel, Vector2Scale(offset, kick)))
+		fragment.ID = w.newID()
+		fragment.Pos = Vector2Add(fragment.Pos, Vector2Scale(Vector2Normalize(offset), FRACTURE_GAP))
+		ResetPosition(&fragment.Pos)
+		fragments = append(fragments, fragment)
+	}
+	return fragments
+}
+
+func (w *World) newID() int {
+	w.nextID += 1
+	return w.nextID
+}
+
+func (w *World) moveAsteroids() {
+	for i := range w.Asteroids {
+		w.Asteroids[i].Pos = Vector2Add(w.Asteroids[i].Pos, w.Asteroids[i].Vel)
+		ResetPosition(&w.Asteroids[i].Pos)
+	}
+}
+
+// startWave fills the field with a new set of asteroids.
+func (w *World) startWave() {
+	w.Wave += 1
+	w.Asteroids = w.generateAsteroids()
+	w.WaveStartTick = w.Tick
+	w.WaveMass = w.AsteroidsMass()
+}
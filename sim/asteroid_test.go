@@ -0,0 +1,52 @@
+package sim
+
+import (
+	"math"
+	"testing"
+)
+
+// momentum sums the mass times the velocity of the asteroids.
+func momentum(asteroids []Asteroid) Vector2 {
+	total := Vector2Zero()
+	for i := range asteroids {
+		total = Vector2Add(total, Vector2Scale(asteroids[i].Vel, asteroids[i].mass()))
+	}
+	return total
+}
+
+func TestSplitKeepsMomentum(t *testing.T) {
+	for seed := range uint64(20) {
+		w := NewWorld(seed, Rules{Mode: ModeCoop, Players: 1})
+		for _, a := range w.Asteroids {
+			//Disparo desde un costado, pasando un poco por fuera del centro
+			direction := GetDirection(w.rng.Float32() * math.Pi * 2)
+			p := Projectile{
+				Pos: Vector2Add(a.Pos, Vector2Scale(Vector2Rotate(direction, math.Pi*0.5), a.Size*0.3)),
+				Vel: Vector2Scale(direction, PROJECTILE_SPEED),
+			}
+			before := Vector2Add(Vector2Scale(a.Vel, a.mass()), Vector2Scale(p.Vel, PROJECTILE_MASS))
+			fragments := w.split(&a, p)
+			if len(fragments) == 0 {
+				continue
+			}
+			//Margen para el redondeo de float32 en las areas, relativo al momento que se mueve
+			after, scale := momentum(fragments), Vector2Length(before)
+			for i := range fragments {
+				scale += fragments[i].mass() * Vector2Length(fragments[i].Vel)
+			}
+			if Vector2Distance(before, after) > scale*1e-4 {
+				t.Errorf("seed %d, asteroid %d: momentum went from %v to %v", seed, a.ID, before, after)
+			}
+		}
+	}
+}
+
+func TestRadius(t *testing.T) {
+	a := Asteroid{Shape: []Vector2{{-3, 0}, {0, 4}, {2, -2}}}
+	if r := a.Radius(); r != 4 {
+		t.Errorf("got %v, want the furthest point at 4", r)
+	}
+	if r := (&Asteroid{}).Radius(); r != 0 {
+		t.Errorf("an asteroid with no outline has radius %v", r)
+	}
+}
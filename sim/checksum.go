@@ -0,0 +1,86 @@
+package sim
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+)
+
+// Checksum hashes everything the next steps depend on, so that copies of the
+// same game running on different machines can check they're still in sync.
+func (w *World) Checksum() uint32 {
+	h := checksum{hash: fnv.New32a()}
+	h.ints(w.Tick, int(w.Phase), w.Wave, w.WaveStartTick, w.Current, w.Round, w.nextID)
+	h.floats(w.WaveMass)
+	for _, p := range w.Players {
+		h.ints(p.Score, p.NextExtraLife, p.Lives, p.Wave, p.Rounds)
+		h.floats(p.WaveMass)
+		h.asteroids(p.Asteroids)
+		h.ship(p.Ship)
+	}
+	h.ints(len(w.Ships))
+	for _, s := range w.Ships {
+		h.ints(s.Player)
+	}
+	h.asteroids(w.Asteroids)
+	state, _ := w.pcg.MarshalBinary()
+	h.hash.Write(state)
+	return h.hash.Sum32()
+}
+
+type checksum struct {
+	hash hash.Hash32
+	buf  [8]byte
+}
+
+func (h *checksum) ints(values ...int) {
+	for _, v := range values {
+		binary.LittleEndian.PutUint64(h.buf[:], uint64(v))
+		h.hash.Write(h.buf[:8])
+	}
+}
+
+func (h *checksum) floats(values ...float32) {
+	for _, v := range values {
+		binary.LittleEndian.PutUint32(h.buf[:], math.Float32bits(v))
+		h.hash.Write(h.buf[:4])
+	}
+}
+
+func (h *checksum) vectors(values ...Vector2) {
+	for _, v := range values {
+		h.floats(v.X, v.Y)
+	}
+}
+
+func (h *checksum) ship(s *PlayerShip) {
+	if s == nil {
+		h.ints(-1)
+		return
+	}
+	h.vectors(s.Pos, s.Vel, s.Spawn)
+	h.floats(s.Orientation, s.Size, s.Speed, float32(s.DestroyedTime))
+	h.ints(len(s.Projectiles), len(s.Wreck))
+	if s.Collision {
+		h.ints(1)
+	}
+	for _, p := range s.Projectiles {
+		h.vectors(p.Pos, p.Vel)
+		h.ints(p.TTL)
+	}
+	for _, segment := range s.Wreck {
+		h.vectors(segment.Pos, segment.Vel)
+		h.floats(segment.Angle)
+	}
+}
+
+func (h *checksum) asteroids(asteroids []Asteroid) {
+	h.ints(len(asteroids))
+	for _, a := range asteroids {
+		h.vectors(a.Pos, a.Vel)
+		h.floats(a.Orientation, a.Size)
+		h.vectors(a.Shape...)
+		h.ints(a.ID, a.Class)
+	}
+}
@@ -0,0 +1,75 @@
+package sim
+
+import "math"
+
+const epsilon = 1.1920929e-07
+
+// The collision checks are the same as raylib's, so the game plays the same
+// without a window.
+
+// checkCollisionLines returns whether two segments cross.
+func checkCollisionLines(startPos1, endPos1, startPos2, endPos2 Vector2) bool {
+	div := (endPos2.Y-startPos2.Y)*(endPos1.X-startPos1.X) - (endPos2.X-startPos2.X)*(endPos1.Y-startPos1.Y)
+	if abs(div) < epsilon {
+		return false
+	}
+	xi := ((startPos2.X-endPos2.X)*(startPos1.X*endPos1.Y-startPos1.Y*endPos1.X) - (startPos1.X-endPos1.X)*(startPos2.X*endPos2.Y-startPos2.Y*endPos2.X)) / div
+	yi := ((startPos2.Y-endPos2.Y)*(startPos1.X*endPos1.Y-startPos1.Y*endPos1.X) - (startPos1.Y-endPos1.Y)*(startPos2.X*endPos2.Y-startPos2.Y*endPos2.X)) / div
+
+	if abs(startPos1.X-endPos1.X) > epsilon && (xi < min(startPos1.X, endPos1.X) || xi > max(startPos1.X, endPos1.X)) {
+		return false
+	}
+	if abs(startPos2.X-endPos2.X) > epsilon && (xi < min(startPos2.X, endPos2.X) || xi > max(startPos2.X, endPos2.X)) {
+		return false
+	}
+	if abs(startPos1.Y-endPos1.Y) > epsilon && (yi < min(startPos1.Y, endPos1.Y) || yi > max(startPos1.Y, endPos1.Y)) {
+		return false
+	}
+	if abs(startPos2.Y-endPos2.Y) > epsilon && (yi < min(startPos2.Y, endPos2.Y) || yi > max(startPos2.Y, endPos2.Y)) {
+		return false
+	}
+	return true
+}
+
+// checkCollisionPointPoly returns whether the point is inside the polygon.
+func checkCollisionPointPoly(point Vector2, points []Vector2) bool {
+	inside := false
+	if len(points) < 3 {
+		return false
+	}
+	for i, j := 0, len(points)-1; i < len(points); j, i = i, i+1 {
+		if (points[i].Y > point.Y) != (points[j].Y > point.Y) &&
+			point.X < (points[j].X-points[i].X)*(point.Y-points[i].Y)/(points[j].Y-points[i].Y)+points[i].X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// checkCollisionPointLine returns whether the point is within threshold of
+// the segment going from p1 to p2.
+func checkCollisionPointLine(point Vector2, p1 Vector2, p2 Vector2, threshold float32) bool {
+	dxc := point.X - p1.X
+	dyc := point.Y - p1.Y
+	dxl := p2.X - p1.X
+	dyl := p2.Y - p1.Y
+	cross := dxc*dyl - dyc*dxl
+
+	if abs(cross) >= threshold*max(abs(dxl), abs(dyl)) {
+		return false
+	}
+	if abs(dxl) >= abs(dyl) {
+		if dxl > 0 {
+			return p1.X <= point.X && point.X <= p2.X
+		}
+		return p2.X <= point.X && point.X <= p1.X
+	}
+	if dyl > 0 {
+		return p1.Y <= point.Y && point.Y <= p2.Y
+	}
+	return p2.Y <= point.Y && point.Y <= p1.Y
+}
+
+func abs(x float32) float32 {
+	return float32(math.Abs(float64(x)))
+}
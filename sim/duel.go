@@ -0,0 +1,98 @@
+package sim
+
+const (
+	STAR_RADIUS  = 14
+	STAR_GRAVITY = 900
+)
+
+var STAR_POS = Vector2{X: SCREEN_SIZE_X / 2, Y: SCREEN_SIZE_Y / 2}
+
+// DUEL_SPAWNS are where the two ships start each round, in opposite corners.
+var DUEL_SPAWNS = []Vector2{
+	{X: SCREEN_SIZE_X * 0.2, Y: SCREEN_SIZE_Y * 0.8},
+	{X: SCREEN_SIZE_X * 0.8, Y: SCREEN_SIZE_Y * 0.2},
+}
+
+// starPull returns the acceleration the star gives to something at pos. The
+// shortest way around the screen edges is used, and the pull stops growing
+// inside the star so nothing gets flung out at absurd speeds.
+func starPull(pos Vector2) Vector2 {
+	delta := WrappedDelta(pos, STAR_POS)
+	distance := max(Vector2Length(delta), STAR_RADIUS)
+	return Vector2Scale(delta, STAR_GRAVITY/(distance*distance*distance))
+}
+
+func (w *World) applyGravity() {
+	for _, s := range w.Ships {
+		if s.Collision {
+			continue
+		}
+		s.Vel = Vector2Add(s.Vel, starPull(s.Pos))
+		for i := range s.Projectiles {
+			p := &s.Projectiles[i]
+			p.Vel = Vector2Add(p.Vel, starPull(p.Pos))
+		}
+	}
+}
+
+func touchesStar(pos Vector2, radius float32) bool {
+	return Vector2Length(WrappedDelta(pos, STAR_POS)) < STAR_RADIUS+radius
+}
+
+// swallowProjectiles removes the projectiles that fell into the star.
+func (w *World) swallowProjectiles() {
+	for _, s := range w.Ships {
+		remaining := s.Projectiles[:0]
+		for _, p := range s.Projectiles {
+			if !touchesStar(p.Pos, PROJECTILE_SIZE) {
+				remaining = append(remaining, p)
+			}
+		}
+		s.Projectiles = remaining
+	}
+}
+
+// startRound puts both ships back in their corners and, if they're turned
+// on, brings a new field of asteroids.
+func (w *World) startRound() {
+	w.Round += 1
+	for i, p := range w.Players {
+		spawn := DUEL_SPAWNS[i%len(DUEL_SPAWNS)]
+		p.Ship = w.newShip(spawn, i)
+	}
+	w.Ships = []*PlayerShip{}
+	for _, p := range w.Players {
+		w.Ships = append(w.Ships, p.Ship)
+	}
+	if w.Rules.DuelAsteroids {
+		w.startWave()
+	} else {
+		w.Asteroids = []Asteroid{}
+		w.WaveMass = 0
+	}
+	w.Phase = PhasePlaying
+}
+
+// endRound is called once a destroyed ship's pieces are gone. The other ship
+// wins the round, unless it was destroyed too.
+func (w *World) endRound(destroyed *PlayerShip) {
+	for _, s := range w.Ships {
+		if s != destroyed && !s.Collision {
+			w.Players[s.Player].Rounds += 1
+		}
+	}
+	w.Phase = PhaseRoundOver
+	if w.MatchWinner() != nil {
+		w.Phase = PhaseGameOver
+	}
+}
+
+// MatchWinner returns the player that has won most of the rounds, if any.
+func (w *World) MatchWinner() *Player {
+	for _, p := range w.Players {
+		if p.Rounds > w.Rules.DuelRounds/2 {
+			return p
+		}
+	}
+	return nil
+}
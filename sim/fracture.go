@@ -0,0 +1,67 @@
+package sim
+
+import "math"
+
+// polygonArea returns the unsigned area of a simple polygon.
+func polygonArea(points []Vector2) float32 {
+	area := float32(0)
+	for i := range points {
+		area += Vector2CrossProduct(points[i], points[(i+1)%len(points)])
+	}
+	return float32(math.Abs(float64(area))) * 0.5
+}
+
+// polygonCentroid returns the center of mass of a simple polygon, falling
+// back to the average of its points when it has no area.
+func polygonCentroid(points []Vector2) Vector2 {
+	centroid := Vector2Zero()
+	area := float32(0)
+	for i := range points {
+		cross := Vector2CrossProduct(points[i], points[(i+1)%len(points)])
+		area += cross
+		centroid = Vector2Add(centroid, Vector2Scale(Vector2Add(points[i], points[(i+1)%len(points)]), cross))
+	}
+	if math.Abs(float64(area)) < 1e-3 {
+		average := Vector2Zero()
+		for _, point := range points {
+			average = Vector2Add(average, point)
+		}
+		return Vector2Scale(average, 1/float32(max(len(points), 1)))
+	}
+	return Vector2Scale(centroid, 1/(3*area))
+}
+
+// clipPolygon keeps the part of the polygon on the side of the line through
+// origin that the normal points to (Sutherland-Hodgman on a single edge).
+func clipPolygon(points []Vector2, origin Vector2, normal Vector2) []Vector2 {
+	clipped := []Vector2{}
+	for i := range points {
+		current := points[i]
+		next := points[(i+1)%len(points)]
+		currentSide := Vector2DotProduct(Vector2Subtract(current, origin), normal)
+		nextSide := Vector2DotProduct(Vector2Subtract(next, origin), normal)
+		if currentSide >= 0 {
+			clipped = append(clipped, current)
+		}
+		if (currentSide >= 0) != (nextSide >= 0) {
+			t := currentSide / (currentSide - nextSide)
+			clipped = append(clipped, Vector2Lerp(current, next, t))
+		}
+	}
+	return clipped
+}
+
+// cutPolygon splits the polygon in two along the line through origin with the
+// given direction. If that line misses the polygon it's moved to go through
+// fallback instead, so the polygon always breaks.
+func cutPolygon(points []Vector2, origin Vector2, direction Vector2, fallback Vector2) [][]Vector2 {
+	normal := NewVector2(-direction.Y, direction.X)
+	for _, o := range []Vector2{origin, fallback} {
+		front := clipPolygon(points, o, normal)
+		back := clipPolygon(points, o, Vector2Negate(normal))
+		if len(front) >= 3 && len(back) >= 3 {
+			return [][]Vector2{front, back}
+		}
+	}
+	return [][]Vector2{points}
+}
@@ -0,0 +1,91 @@
+package sim
+
+import "fmt"
+
+// Input is what a player wants their ship to do during one tick, one bit per
+// control. The keyboard, bots and the network all come down to it.
+type Input uint8
+
+const (
+	InputLeft Input = 1 << iota
+	InputRight
+	InputThrust
+	InputReverse
+	InputFire
+	InputStart
+)
+
+func (in Input) Has(control Input) bool {
+	return in&control != 0
+}
+
+func inputFor(inputs []Input, player int) Input {
+	if player < 0 || player >= len(inputs) {
+		return 0
+	}
+	return inputs[player]
+}
+
+// pressed is true if any player pressed the control.
+func pressed(inputs []Input, control Input) bool {
+	for _, in := range inputs {
+		if in.Has(control) {
+			return true
+		}
+	}
+	return false
+}
+
+type EventKind int
+
+const (
+	// EventGameStart is sent when a new game begins, also after a game over.
+	EventGameStart EventKind = iota
+	// EventTurn is sent when Player gets the turn, if there's more than one.
+	EventTurn
+	// EventThrust is sent every tick a ship's engine is on. Angle is where
+	// the exhaust goes.
+	EventThrust
+	EventFire
+	// EventAsteroidHit is sent when a projectile breaks an asteroid of the
+	// given Class at Pos. Vel is the asteroid's velocity and Angle points back
+	// to where the projectile came from.
+	EventAsteroidHit
+	// EventShipDestroyed is sent when Player's ship breaks up, with the Cause.
+	// Class is the asteroid's when one hit it.
+	EventShipDestroyed
+	EventExtraLife
+)
+
+// Cause is what destroyed a ship.
+type Cause int
+
+const (
+	CauseNone Cause = iota
+	CauseAsteroid
+	// CauseShot is a projectile of another ship, in a duel or with friendly
+	// fire.
+	CauseShot
+	CauseStar
+)
+
+var CAUSE_NAMES = []string{"none", "asteroid", "shot", "star"}
+
+func (c Cause) String() string {
+	if c < 0 || int(c) >= len(CAUSE_NAMES) {
+		return fmt.Sprintf("Cause(%d)", int(c))
+	}
+	return CAUSE_NAMES[c]
+}
+
+// Event is something that happened during a step that can be seen or heard.
+// The fields that don't apply to its kind are left empty.
+type Event struct {
+	Kind   EventKind
+	Player int
+	Pos    Vector2
+	Vel    Vector2
+	Angle  float32
+	Class  int
+	Cause  Cause
+}
@@ -0,0 +1,162 @@
+package sim
+
+import "slices"
+
+const (
+	EXTRA_LIFE_SCORE = 10000
+	MAX_SHIPS        = 4
+)
+
+// SCORES are the points for shooting an asteroid, indexed by its class.
+var SCORES = []int{20, 50, 100}
+
+// Player keeps a player's score, lives and ship. When players take turns it
+// also keeps their field of asteroids while the other one is playing.
+type Player struct {
+	Score         int
+	NextExtraLife int
+	Lives         int
+	Asteroids     []Asteroid
+	Wave          int
+	WaveMass      float32
+	Ship          *PlayerShip
+	Rounds        int
+}
+
+// startGame sets up the players. Taking turns every player gets a field of
+// asteroids of their own and the first one starts; in co-op every ship flies
+// at once over the same field, and in a duel two ships fight around a star.
+func (w *World) startGame() {
+	players := max(0, min(MAX_SHIPS, w.Rules.Players))
+	mode := w.Rules.Mode
+	tuning := w.Rules.Tuned()
+	w.Players = []*Player{}
+	for i := range players {
+		p := &Player{
+			NextExtraLife: EXTRA_LIFE_SCORE,
+			Lives:         tuning.Lives,
+		}
+		spawn := NewVector2(SCREEN_SIZE_X/2, SCREEN_SIZE_Y/2)
+		if mode == ModeCoop || mode == ModeDuel {
+			spawn.X = SCREEN_SIZE_X * float32(i+1) / float32(players+1)
+		}
+		p.Ship = w.newShip(spawn, i)
+		if mode == ModeTurns {
+			p.Asteroids = w.generateAsteroids()
+			p.Wave = 1
+			for _, a := range p.Asteroids {
+				p.WaveMass += a.mass()
+			}
+		}
+		w.Players = append(w.Players, p)
+	}
+
+	w.Phase = PhasePlaying
+	w.emit(Event{Kind: EventGameStart})
+	if mode == ModeTurns && players > 0 {
+		w.loadPlayer(0)
+		return
+	}
+	if mode == ModeDuel {
+		w.Round = 0
+		w.startRound()
+		return
+	}
+	w.Ships = []*PlayerShip{}
+	for _, p := range w.Players {
+		w.Ships = append(w.Ships, p.Ship)
+	}
+	w.Wave = 0
+	w.startWave()
+}
+
+// savePlayer stores the asteroids of the player that has the turn. While a
+// player has the turn only the world keeps their asteroids, so the two never
+// share a slice that playing changes.
+func (w *World) savePlayer() {
+	p := w.Players[w.Current]
+	p.Asteroids = w.Asteroids
+	p.Wave = w.Wave
+	p.WaveMass = w.WaveMass
+}
+
+// loadPlayer gives the turn to a player, bringing back their asteroids.
+func (w *World) loadPlayer(i int) {
+	p := w.Players[i]
+	w.Current = i
+	w.Asteroids = p.Asteroids
+	p.Asteroids = nil
+	w.Wave = p.Wave
+	w.WaveMass = p.WaveMass
+	w.WaveStartTick = w.Tick
+	w.Ships = []*PlayerShip{p.Ship}
+	p.Ship.Projectiles = p.Ship.Projectiles[:0]
+	if len(w.Players) > 1 {
+		w.emit(Event{Kind: EventTurn, Player: i})
+	}
+}
+
+// passTurn gives the turn to the next player that still has lives, which
+// may be the same one. It returns false when nobody has lives left.
+func (w *World) passTurn() bool {
+	for i := 1; i <= len(w.Players); i++ {
+		next := (w.Current + i) % len(w.Players)
+		if w.Players[next].Lives > 0 {
+			if next != w.Current {
+				w.savePlayer()
+				w.loadPlayer(next)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func (w *World) addScore(player int, points int) {
+	p := w.Players[player]
+	p.Score += points
+	if p.Score >= p.NextExtraLife {
+		p.Lives += 1
+		p.NextExtraLife += EXTRA_LIFE_SCORE
+		w.emit(Event{Kind: EventExtraLife, Player: player})
+	}
+}
+
+func scoreFor(class int) int {
+	return SCORES[max(0, min(len(SCORES)-1, class))]
+}
+
+// restartGame brings back a destroyed ship, if its player has lives left.
+func (w *World) restartGame(s *PlayerShip) {
+	s.Pos = s.Spawn
+	s.Orientation = PLAYER_SHIP_INITIAL_ORIENTATION
+	s.Vel = Vector2{
+		X: 0,
+		Y: 0,
+	}
+	s.Wreck = nil
+	s.Collision = false
+	s.DestroyedTime = SHIP_TIME_IN_PIECES
+	if w.Rules.Mode == ModeDuel {
+		w.endRound(s)
+		return
+	}
+	p := w.Players[s.Player]
+	p.Lives = p.Lives - 1
+
+	if w.Rules.Mode == ModeTurns {
+		//Con dos jugadores el turno pasa al otro cuando uno muere
+		if !w.passTurn() {
+			w.Phase = PhaseGameOver
+		}
+		return
+	}
+	if p.Lives < 1 {
+		w.Ships = slices.DeleteFunc(w.Ships, func(other *PlayerShip) bool {
+			return other == s
+		})
+	}
+	if len(w.Ships) == 0 {
+		w.Phase = PhaseGameOver
+	}
+}
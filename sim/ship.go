@@ -0,0 +1,257 @@
+package sim
+
+import "math"
+
+func (w *World) newShip(spawn Vector2, player int) *PlayerShip {
+	return &PlayerShip{
+		Pos:         spawn,
+		Size:        PLAYER_SHIP_SIZE,
+		Orientation: PLAYER_SHIP_INITIAL_ORIENTATION,
+		Speed:       w.Rules.Tuned().Thrust,
+		Vel: Vector2{
+			X: 0,
+			Y: 0,
+		},
+		Projectiles:   []Projectile{},
+		DestroyedTime: SHIP_TIME_IN_PIECES,
+		Spawn:         spawn,
+		Player:        player,
+	}
+}
+
+func (w *World) steer(s *PlayerShip, in Input) {
+	tuning := w.Rules.Tuned()
+	if in.Has(InputRight) {
+		newOrientation := s.Orientation + tuning.TurnSpeed
+		if newOrientation >= 2*math.Pi {
+			s.Orientation = 0.0
+		} else if newOrientation <= -2*math.Pi {
+			s.Orientation = 0.0
+		} else {
+			s.Orientation = newOrientation
+		}
+
+	}
+	if in.Has(InputLeft) {
+		newOrientation := s.Orientation - tuning.TurnSpeed
+		if newOrientation >= 2*math.Pi {
+			s.Orientation = 0.0
+		} else if newOrientation <= -2*math.Pi {
+			s.Orientation = 0.0
+		} else {
+			s.Orientation = newOrientation
+		}
+	}
+
+	//Sentido y orientacion de la nave
+	directionX := float32(math.Cos(float64(s.Orientation)))
+	directionY := float32(math.Sin(float64(s.Orientation)))
+
+	//Este vector es el sentido y orientacion de la nave
+	newVector := NewVector2(directionX, directionY)
+
+	if in.Has(InputThrust) {
+		//Agregarle la rapidez
+		s.Vel = Vector2Add(
+			s.Vel,
+			Vector2Scale(newVector, s.Speed),
+		)
+		s.Thrusting = true
+		w.emit(Event{Kind: EventThrust, Player: s.Player, Pos: s.Pos, Vel: s.Vel, Angle: s.Orientation + math.Pi})
+	}
+
+	if in.Has(InputReverse) {
+		//Agregarle la rapidez
+		s.Vel = Vector2Subtract(
+			s.Vel,
+			Vector2Scale(newVector, s.Speed),
+		)
+	}
+
+	if in.Has(InputFire) {
+		s.shoot(tuning.ProjectileTTL)
+		w.emit(Event{Kind: EventFire, Player: s.Player, Pos: s.Pos})
+	}
+
+	s.Pos = Vector2Add(s.Pos, s.Vel)
+
+}
+
+func (s *PlayerShip) shoot(ttl int) {
+	circleX := s.Pos.X + (s.Size+10)*float32(math.Cos(float64(s.Orientation)))
+	circleY := s.Pos.Y + (s.Size+10)*float32(math.Sin(float64(s.Orientation)))
+	initialPosVector := NewVector2(circleX, circleY)
+
+	//Sentido y orientacion de la nave
+	directionX := float32(math.Cos(float64(s.Orientation)))
+	directionY := float32(math.Sin(float64(s.Orientation)))
+	//Este vector es el sentido y orientacion de la nave
+	newVector := NewVector2(directionX, directionY)
+	//Con el sentido y orientación de la nave se puede escalar con la rapidez para obtener la velocidad
+	projectileVelocity := Vector2Add(
+		s.Vel,
+		Vector2Scale(newVector, PROJECTILE_SPEED+PLAYER_SHIP_SPEED),
+	)
+
+	projectile := Projectile{
+		Pos:         initialPosVector,
+		Speed:       PROJECTILE_SPEED,
+		Vel:         projectileVelocity,
+		TTL:         ttl,
+		Orientation: s.Orientation,
+		Size:        PROJECTILE_SIZE,
+	}
+	s.Projectiles = append(s.Projectiles, projectile)
+
+}
+
+func (s *PlayerShip) moveProjectiles() {
+	for i := range s.Projectiles {
+		s.Projectiles[i].Pos = Vector2Add(s.Projectiles[i].Pos, s.Projectiles[i].Vel)
+		ResetPosition(&s.Projectiles[i].Pos)
+		s.Projectiles[i].TTL -= 1
+	}
+}
+
+func (s *PlayerShip) removeProjectiles() {
+	for i, p := range s.Projectiles {
+		if p.TTL < 1 {
+			removeItem(&s.Projectiles, i)
+		}
+	}
+}
+
+// Points returns the outline of the ship, closed back on its nose.
+func (s *PlayerShip) Points() []Vector2 {
+	verticalDirection := Vector2Scale(GetDirection(s.Orientation), s.Size)
+	horizontalDirection := Vector2Scale(GetDirection(s.Orientation+math.Pi*0.5), s.Size)
+
+	points := []Vector2{
+		Vector2Add(s.Pos, verticalDirection),
+		Vector2Subtract(Vector2Subtract(s.Pos, verticalDirection), horizontalDirection),
+		s.Pos,
+		Vector2Add(Vector2Subtract(s.Pos, verticalDirection), horizontalDirection),
+		Vector2Add(s.Pos, verticalDirection),
+	}
+	return points
+}
+
+// explode breaks the ship outline into its line segments. Every segment
+// keeps the ship's velocity plus a random push away from the hull and a spin.
+func (w *World) explode(s *PlayerShip) {
+	points := s.Points()
+	s.Wreck = []HullSegment{}
+	for i := range points {
+		start := points[i]
+		end := points[(i+1)%len(points)]
+		if Vector2Distance(start, end) < 0.01 {
+			continue
+		}
+		center := Vector2Lerp(start, end, 0.5)
+		away := Vector2Normalize(Vector2Subtract(center, s.Pos))
+		impulse := Vector2Add(
+			Vector2Scale(away, w.rng.Float32()*HULL_SEGMENT_IMPULSE),
+			Vector2Scale(GetDirection(w.rng.Float32()*(math.Pi*2)), w.rng.Float32()*HULL_SEGMENT_IMPULSE*0.5),
+		)
+		s.Wreck = append(s.Wreck, HullSegment{
+			Pos:  center,
+			Half: Vector2Subtract(end, center),
+			Vel:  Vector2Add(s.Vel, impulse),
+			Spin: (w.rng.Float32() - 0.5) * 2 * HULL_SEGMENT_SPIN,
+		})
+	}
+}
+
+func (s *PlayerShip) moveWreck() {
+	for i := range s.Wreck {
+		segment := &s.Wreck[i]
+		segment.Pos = Vector2Add(segment.Pos, segment.Vel)
+		ResetPosition(&segment.Pos)
+		segment.Angle += segment.Spin
+	}
+}
+
+func (w *World) checkColissions(s *PlayerShip) (Asteroid, bool) {
+	shipPoints := s.Points()
+
+	for i := range shipPoints {
+		for _, a := range w.Asteroids {
+			points := a.Points()
+			for k := range points {
+				if checkCollisionLines(shipPoints[i], shipPoints[(i+1)%len(shipPoints)], points[k], points[(k+1)%len(points)]) {
+					return a, true
+				}
+			}
+		}
+	}
+	return Asteroid{}, false
+}
+
+func projectileHits(p Projectile, points []Vector2) bool {
+	if checkCollisionPointPoly(p.Pos, points) {
+		return true
+	}
+	for k := range points {
+		if checkCollisionPointLine(p.Pos, points[k], points[(k+1)%len(points)], 20) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *World) checkProjectileCollisions() {
+
+	for _, s := range w.Ships {
+		remaining := s.Projectiles[:0]
+		for _, p := range s.Projectiles {
+			if !w.projectileHitAsteroid(s, p) && !w.projectileHitShip(s, p) {
+				remaining = append(remaining, p)
+			}
+		}
+		s.Projectiles = remaining
+	}
+}
+
+func (w *World) projectileHitAsteroid(s *PlayerShip, p Projectile) bool {
+	for j, a := range w.Asteroids {
+		if projectileHits(p, a.Points()) {
+			removeItem(&w.Asteroids, j)
+			w.Asteroids = append(w.Asteroids, w.split(&a, p)...)
+			w.emit(Event{
+				Kind:   EventAsteroidHit,
+				Player: s.Player,
+				Pos:    p.Pos,
+				Vel:    a.Vel,
+				Angle:  float32(math.Atan2(float64(-p.Vel.Y), float64(-p.Vel.X))),
+				Class:  a.Class,
+			})
+			w.addScore(s.Player, scoreFor(a.Class))
+			return true
+		}
+	}
+	return false
+}
+
+// projectileHitShip destroys any other ship the projectile hits, when
+// friendly fire is on or in a duel.
+func (w *World) projectileHitShip(s *PlayerShip, p Projectile) bool {
+	if !w.Rules.FriendlyFire && w.Rules.Mode != ModeDuel {
+		return false
+	}
+	for _, other := range w.Ships {
+		if other == s || other.Collision {
+			continue
+		}
+		if checkCollisionPointPoly(p.Pos, other.Points()) {
+			w.destroyShip(other, CauseShot, 0)
+			return true
+		}
+	}
+	return false
+}
+
+func (w *World) destroyShip(s *PlayerShip, cause Cause, class int) {
+	s.Collision = true
+	w.explode(s)
+	w.emit(Event{Kind: EventShipDestroyed, Player: s.Player, Pos: s.Pos, Vel: s.Vel, Class: class, Cause: cause})
+}
@@ -0,0 +1,241 @@
+// Package sim is the game itself: ships, projectiles, asteroids and the rules
+// of every game mode, with no window, sound or clock. A World only changes
+// through Step, which takes the input of every player for one tick, and all
+// of its randomness comes from a seeded generator. Two worlds made with the
+// same seed and rules that are given the same inputs stay identical, which is
+// what networked games and headless runs are built on.
+package sim
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+const (
+	PLAYER_SHIP_SIZE                = 20
+	PLAYER_SHIP_INITIAL_ORIENTATION = math.Pi + (math.Pi * 0.5)
+	PLAYER_SHIP_TURN_SPEED          = 0.02 * math.Pi
+	PLAYER_SHIP_SPEED               = 0.3
+	SCREEN_SIZE_X                   = 1024
+	SCREEN_SIZE_Y                   = 768
+	PROJECTILE_SPEED                = PLAYER_SHIP_SPEED + 15
+	TTL_PRJECTILE                   = 45
+	PROJECTILE_SIZE                 = 2.5
+	MAX_SPEED                       = 5
+	MAX_ASTEROIDS                   = 12
+	ASTEROID_SPEED                  = 1
+	ASTEROID_SIZE                   = 50.0
+	ASTEROID_POINTS                 = 11
+	SHIP_TIME_IN_PIECES             = 5
+	LIVES                           = 3
+	PROJECTILE_MASS                 = 40.0
+	SPLIT_SPREAD_ANGLE              = math.Pi * 0.5
+	SPLIT_SPEED                     = 3.0
+	FRACTURE_GAP                    = 1.5
+	HULL_SEGMENT_IMPULSE            = 1.5
+	HULL_SEGMENT_SPIN               = 0.15
+	TICK_RATE                       = 60
+)
+
+type Mode int
+
+const (
+	ModeTurns Mode = iota
+	ModeCoop
+	ModeDuel
+)
+
+// Phase is where the game is at. Only PhasePlaying moves the ships.
+type Phase int
+
+const (
+	PhasePlaying Phase = iota
+	PhaseRoundOver
+	PhaseGameOver
+)
+
+// Rules are everything that is chosen before a game starts. A world with no
+// players is just a field of asteroids drifting by, like the one behind the
+// title screen.
+type Rules struct {
+	Mode          Mode
+	Players       int
+	FriendlyFire  bool
+	DuelRounds    int
+	DuelAsteroids bool
+	Tuning        Tuning
+}
+
+type World struct {
+	Rules         Rules
+	Tick          int
+	Phase         Phase
+	Players       []*Player
+	Ships         []*PlayerShip
+	Asteroids     []Asteroid
+	Wave          int
+	WaveStartTick int
+	WaveMass      float32
+	Current       int
+	Round         int
+	Events        []Event
+	nextID        int
+	pcg           *rand.PCG
+	rng           *rand.Rand
+}
+
+type PlayerShip struct {
+	Pos           Vector2
+	Orientation   float32
+	Size          float32
+	Speed         float32
+	Vel           Vector2
+	Projectiles   []Projectile
+	Wreck         []HullSegment
+	Thrusting     bool
+	Collision     bool
+	DestroyedTime float64
+	Spawn         Vector2
+	Player        int
+}
+
+// HullSegment is one of the lines of a destroyed ship's outline.
+type HullSegment struct {
+	Pos   Vector2
+	Half  Vector2
+	Vel   Vector2
+	Angle float32
+	Spin  float32
+}
+
+type Projectile struct {
+	Pos         Vector2
+	Speed       float32
+	Vel         Vector2
+	TTL         int
+	Orientation float32
+	Size        float32
+}
+
+// NewWorld starts a game with the given rules. Everything random in it comes
+// from the seed.
+func NewWorld(seed uint64, rules Rules) *World {
+	pcg := rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)
+	w := &World{
+		Rules: rules,
+		pcg:   pcg,
+		rng:   rand.New(pcg),
+	}
+	w.startGame()
+	return w
+}
+
+// Step advances the world by one tick. inputs has the input of each player,
+// in the same order as Players, and missing ones count as doing nothing. What
+// happened during the tick is left in Events until the next step.
+func (w *World) Step(inputs []Input) {
+	w.Events = w.Events[:0]
+	for _, s := range w.Ships {
+		s.Thrusting = false
+	}
+	switch w.Phase {
+	case PhasePlaying:
+		w.play(inputs)
+	case PhaseRoundOver:
+		if pressed(inputs, InputStart) {
+			w.startRound()
+		}
+	case PhaseGameOver:
+		if pressed(inputs, InputStart) {
+			w.startGame()
+		}
+	}
+	w.moveAsteroids()
+	w.Tick += 1
+}
+
+func (w *World) play(inputs []Input) {
+	for _, s := range w.Ships {
+		if !s.Collision {
+			w.steer(s, inputFor(inputs, s.Player))
+		}
+	}
+	if w.Rules.Mode == ModeDuel {
+		w.applyGravity()
+	}
+	for _, s := range w.Ships {
+		s.Pos = *ResetPosition(&s.Pos)
+		s.moveProjectiles()
+		s.removeProjectiles()
+	}
+	w.checkProjectileCollisions()
+	if w.Rules.Mode == ModeDuel {
+		w.swallowProjectiles()
+	} else if len(w.Asteroids) == 0 {
+		w.startWave()
+	}
+
+	//Reaparecer puede cambiar las naves en juego
+	maxSpeed := w.Rules.Tuned().MaxSpeed
+	for _, s := range slices.Clone(w.Ships) {
+		s.Pos = Vector2Add(s.Pos, s.Vel)
+		if s.Vel.X > maxSpeed {
+			s.Vel.X = maxSpeed
+		}
+		if s.Vel.Y > maxSpeed {
+			s.Vel.Y = maxSpeed
+		}
+		if s.Vel.X < -maxSpeed {
+			s.Vel.X = -maxSpeed
+		}
+		if s.Vel.Y < -maxSpeed {
+			s.Vel.Y = -maxSpeed
+		}
+
+		if s.Collision {
+			s.moveWreck()
+			s.DestroyedTime -= 0.1
+			if s.DestroyedTime < 0 {
+				w.restartGame(s)
+			}
+		} else {
+			if a, hit := w.checkColissions(s); hit {
+				w.destroyShip(s, CauseAsteroid, a.Class)
+			} else if w.Rules.Mode == ModeDuel && touchesStar(s.Pos, s.Size*0.5) {
+				w.destroyShip(s, CauseStar, 0)
+			}
+		}
+	}
+}
+
+func (w *World) emit(e Event) {
+	w.Events = append(w.Events, e)
+}
+
+// AsteroidsMass is the mass of every rock still flying.
+func (w *World) AsteroidsMass() float32 {
+	mass := float32(0)
+	for _, a := range w.Asteroids {
+		mass += a.mass()
+	}
+	return mass
+}
+
+// Respawning is true while every ship in play is in pieces.
+func (w *World) Respawning() bool {
+	for _, s := range w.Ships {
+		if !s.Collision {
+			return false
+		}
+	}
+	return true
+}
+
+func removeItem[T any](slice *[]T, index int) {
+	if index < 0 || index >= len(*slice) {
+		return
+	}
+	copy((*slice)[index:], (*slice)[index+1:])
+	*slice = (*slice)[:len(*slice)-1]
+}
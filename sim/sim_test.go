@@ -0,0 +1,70 @@
+package sim
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+const TEST_TICKS = 2000
+
+// testRules are a game of every mode, with as many players as it takes.
+var testRules = []Rules{
+	{Mode: ModeTurns, Players: 1},
+	{Mode: ModeTurns, Players: 2},
+	{Mode: ModeTurns, Players: 4},
+	{Mode: ModeCoop, Players: 2},
+	{Mode: ModeCoop, Players: 4, FriendlyFire: true},
+	{Mode: ModeDuel, Players: 2, DuelRounds: 3},
+	{Mode: ModeDuel, Players: 2, DuelRounds: 3, DuelAsteroids: true},
+}
+
+func modeName(r Rules) string {
+	return [...]string{"turns", "coop", "duel"}[r.Mode]
+}
+
+// testInputs returns the inputs of every tick for a game, made up from the
+// seed: players hold keys for a while, fire now and then and press start.
+func testInputs(seed uint64, players int, ticks int) [][]Input {
+	rng := rand.New(rand.NewPCG(seed, 7))
+	held := make([]Input, players)
+	inputs := make([][]Input, ticks)
+	for tick := range inputs {
+		inputs[tick] = make([]Input, players)
+		for i := range held {
+			if rng.IntN(20) == 0 {
+				held[i] = Input(rng.IntN(16))
+			}
+			inputs[tick][i] = held[i]
+			if rng.IntN(8) == 0 {
+				inputs[tick][i] |= InputFire
+			}
+			if rng.IntN(200) == 0 {
+				inputs[tick][i] |= InputStart
+			}
+		}
+	}
+	return inputs
+}
+
+func TestStepIsDeterministic(t *testing.T) {
+	for _, rules := range testRules {
+		for seed := range uint64(3) {
+			inputs := testInputs(seed, rules.Players, TEST_TICKS)
+			a, b := NewWorld(seed, rules), NewWorld(seed, rules)
+			for tick, in := range inputs {
+				a.Step(in)
+				b.Step(in)
+				if a.Checksum() != b.Checksum() {
+					t.Fatalf("%s with %d players, seed %d: the checksums differ at tick %d", modeName(rules), rules.Players, seed, tick)
+				}
+			}
+		}
+	}
+}
+
+func TestSeedsPlayDifferentGames(t *testing.T) {
+	rules := Rules{Mode: ModeCoop, Players: 1}
+	if NewWorld(1, rules).Checksum() == NewWorld(2, rules).Checksum() {
+		t.Error("two seeds started the same game")
+	}
+}
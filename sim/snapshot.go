@@ -0,0 +1,42 @@
+package sim
+
+import (
+	"math/rand/v2"
+	"slices"
+)
+
+// Clone returns a copy of the world that shares nothing that Step changes, so
+// it can be kept as a snapshot and played forward again later. The asteroid
+// outlines are shared since they never change once made.
+func (w *World) Clone() *World {
+	clone := *w
+	pcg := *w.pcg
+	clone.pcg = &pcg
+	clone.rng = rand.New(clone.pcg)
+	clone.Events = nil
+	clone.Asteroids = slices.Clone(w.Asteroids)
+	clone.Players = make([]*Player, len(w.Players))
+	for i, p := range w.Players {
+		player := *p
+		player.Asteroids = slices.Clone(p.Asteroids)
+		if p.Ship != nil {
+			ship := *p.Ship
+			ship.Projectiles = slices.Clone(p.Ship.Projectiles)
+			ship.Wreck = slices.Clone(p.Ship.Wreck)
+			player.Ship = &ship
+		}
+		clone.Players[i] = &player
+	}
+	//Las naves en juego siempre son las de los jugadores
+	clone.Ships = make([]*PlayerShip, len(w.Ships))
+	for i, s := range w.Ships {
+		clone.Ships[i] = clone.Players[s.Player].Ship
+	}
+	return &clone
+}
+
+// Restore turns the world back into a snapshot taken with Clone. The world
+// keeps its own copy, so the snapshot can be restored again.
+func (w *World) Restore(snapshot *World) {
+	*w = *snapshot.Clone()
+}
@@ -0,0 +1,58 @@
+package sim
+
+import "testing"
+
+// TestRestoreReplaysTheSameGame takes snapshots along games of every mode,
+// and plays again from each one with the same inputs, which has to go
+// through exactly the same worlds.
+func TestRestoreReplaysTheSameGame(t *testing.T) {
+	const every, replay = 89, 60
+	for _, rules := range testRules {
+		for seed := range uint64(3) {
+			inputs := testInputs(seed, rules.Players, TEST_TICKS)
+			w := NewWorld(seed, rules)
+			sums := make([]uint32, len(inputs))
+			snapshots := map[int]*World{}
+			for tick, in := range inputs {
+				if tick%every == 0 {
+					snapshots[tick] = w.Clone()
+					if snapshots[tick].Checksum() != w.Checksum() {
+						t.Fatalf("%s with %d players, seed %d: the clone at tick %d has another checksum", modeName(rules), rules.Players, seed, tick)
+					}
+				}
+				w.Step(in)
+				sums[tick] = w.Checksum()
+			}
+
+			for start, snapshot := range snapshots {
+				replayed := NewWorld(seed+1, rules)
+				replayed.Restore(snapshot)
+				for tick := start; tick < min(start+replay, len(inputs)); tick++ {
+					replayed.Step(inputs[tick])
+					if replayed.Checksum() != sums[tick] {
+						t.Fatalf("%s with %d players, seed %d: replaying from tick %d differs at tick %d", modeName(rules), rules.Players, seed, start, tick)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestCloneSharesNothing steps a world and checks its snapshot didn't move.
+func TestCloneSharesNothing(t *testing.T) {
+	for _, rules := range testRules {
+		inputs := testInputs(1, rules.Players, TEST_TICKS)
+		w := NewWorld(1, rules)
+		for _, in := range inputs[:TEST_TICKS/2] {
+			w.Step(in)
+		}
+		snapshot := w.Clone()
+		sum := snapshot.Checksum()
+		for _, in := range inputs[TEST_TICKS/2:] {
+			w.Step(in)
+		}
+		if snapshot.Checksum() != sum {
+			t.Errorf("%s with %d players: playing on changed the snapshot", modeName(rules), rules.Players)
+		}
+	}
+}
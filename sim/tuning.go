@@ -0,0 +1,61 @@
+package sim
+
+// Tuning are the constants of the game that a game can play with other
+// values, to try out how it plays. A zero leaves the constant as it is.
+// Only games with no network change them: the rules sent over the network
+// don't carry a tuning, so networked games always play with the constants.
+type Tuning struct {
+	// TurnSpeed is PLAYER_SHIP_TURN_SPEED, the radians a ship turns a tick.
+	TurnSpeed float32 `json:"turn_speed,omitempty"`
+	// Thrust is PLAYER_SHIP_SPEED, what the engine adds to the velocity a
+	// tick.
+	Thrust   float32 `json:"thrust,omitempty"`
+	MaxSpeed float32 `json:"max_speed,omitempty"`
+	// ProjectileTTL is TTL_PRJECTILE, the ticks a shot lasts.
+	ProjectileTTL int `json:"projectile_ttl,omitempty"`
+	// AsteroidSpeed is ASTEROID_SPEED, the fastest a new asteroid goes.
+	AsteroidSpeed float32 `json:"asteroid_speed,omitempty"`
+	// Asteroids is MAX_ASTEROIDS, how many there are at the start of a wave.
+	Asteroids int `json:"asteroids,omitempty"`
+	Lives     int `json:"lives,omitempty"`
+}
+
+func DefaultTuning() Tuning {
+	return Tuning{
+		TurnSpeed:     PLAYER_SHIP_TURN_SPEED,
+		Thrust:        PLAYER_SHIP_SPEED,
+		MaxSpeed:      MAX_SPEED,
+		ProjectileTTL: TTL_PRJECTILE,
+		AsteroidSpeed: ASTEROID_SPEED,
+		Asteroids:     MAX_ASTEROIDS,
+		Lives:         LIVES,
+	}
+}
+
+// Tuned returns the tuning the rules play with, the constants filling in
+// what isn't changed.
+func (r Rules) Tuned() Tuning {
+	t, d := r.Tuning, DefaultTuning()
+	if t.TurnSpeed == 0 {
+		t.TurnSpeed = d.TurnSpeed
+	}
+	if t.Thrust == 0 {
+		t.Thrust = d.Thrust
+	}
+	if t.MaxSpeed == 0 {
+		t.MaxSpeed = d.MaxSpeed
+	}
+	if t.ProjectileTTL == 0 {
+		t.ProjectileTTL = d.ProjectileTTL
+	}
+	if t.AsteroidSpeed == 0 {
+		t.AsteroidSpeed = d.AsteroidSpeed
+	}
+	if t.Asteroids == 0 {
+		t.Asteroids = d.Asteroids
+	}
+	if t.Lives == 0 {
+		t.Lives = d.Lives
+	}
+	return t
+}
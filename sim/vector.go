@@ -0,0 +1,106 @@
+package sim
+
+import "math"
+
+// Vector2 is a point or a direction on the screen. It has the same layout as
+// raylib's Vector2, so the game converts between the two with a plain type
+// conversion, and the helpers below work like raylib's raymath ones.
+type Vector2 struct {
+	X float32
+	Y float32
+}
+
+func NewVector2(x, y float32) Vector2 {
+	return Vector2{X: x, Y: y}
+}
+
+func Vector2Zero() Vector2 {
+	return Vector2{}
+}
+
+func Vector2Add(v1, v2 Vector2) Vector2 {
+	return Vector2{X: v1.X + v2.X, Y: v1.Y + v2.Y}
+}
+
+func Vector2Subtract(v1, v2 Vector2) Vector2 {
+	return Vector2{X: v1.X - v2.X, Y: v1.Y - v2.Y}
+}
+
+func Vector2Scale(v Vector2, scale float32) Vector2 {
+	return Vector2{X: v.X * scale, Y: v.Y * scale}
+}
+
+func Vector2Negate(v Vector2) Vector2 {
+	return Vector2{X: -v.X, Y: -v.Y}
+}
+
+func Vector2Length(v Vector2) float32 {
+	return float32(math.Sqrt(float64((v.X * v.X) + (v.Y * v.Y))))
+}
+
+func Vector2Distance(v1, v2 Vector2) float32 {
+	return float32(math.Sqrt(float64((v1.X-v2.X)*(v1.X-v2.X) + (v1.Y-v2.Y)*(v1.Y-v2.Y))))
+}
+
+func Vector2DotProduct(v1, v2 Vector2) float32 {
+	return v1.X*v2.X + v1.Y*v2.Y
+}
+
+func Vector2CrossProduct(v1, v2 Vector2) float32 {
+	return v1.X*v2.Y - v1.Y*v2.X
+}
+
+func Vector2Normalize(v Vector2) Vector2 {
+	if l := Vector2Length(v); l > 0 {
+		return Vector2Scale(v, 1/l)
+	}
+	return v
+}
+
+func Vector2Lerp(v1, v2 Vector2, amount float32) Vector2 {
+	return NewVector2(v1.X+amount*(v2.X-v1.X), v1.Y+amount*(v2.Y-v1.Y))
+}
+
+func Vector2Rotate(v Vector2, angle float32) Vector2 {
+	cosres := float32(math.Cos(float64(angle)))
+	sinres := float32(math.Sin(float64(angle)))
+	return Vector2{
+		X: v.X*cosres - v.Y*sinres,
+		Y: v.X*sinres + v.Y*cosres,
+	}
+}
+
+func GetDirection(orientation float32) Vector2 {
+	circleX := float32(math.Cos(float64(orientation)))
+	circleY := float32(math.Sin(float64(orientation)))
+
+	return NewVector2(circleX, circleY)
+}
+
+// WrappedDelta returns the shortest vector going from one point to the other,
+// taking into account that the screen wraps around its edges.
+func WrappedDelta(from Vector2, to Vector2) Vector2 {
+	delta := Vector2Subtract(to, from)
+	delta.X = float32(math.Remainder(float64(delta.X), SCREEN_SIZE_X))
+	delta.Y = float32(math.Remainder(float64(delta.Y), SCREEN_SIZE_Y))
+	return delta
+}
+
+func ResetPosition(position *Vector2) *Vector2 {
+
+	position.X = float32(
+		math.Mod(
+			float64(position.X), float64(SCREEN_SIZE_X)))
+
+	position.Y = float32(
+		math.Mod(
+			float64(position.Y), float64(SCREEN_SIZE_Y)))
+
+	if position.X <= 0 {
+		position.X = SCREEN_SIZE_X
+	}
+	if position.Y <= 0 {
+		position.Y = SCREEN_SIZE_Y
+	}
+	return position
+}
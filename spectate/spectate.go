@@ -0,0 +1,314 @@
+// Package spectate streams games to web browsers. A Hub serves a small
+// viewer page, and a websocket that sends the viewer a JSON frame with the
+// world of the game it's watching after every tick: the ships, shots and
+// asteroid outlines in screen coordinates, and the scores.
+//
+// A hub can stream several games at once, each published under its own
+// name, like the matches of a server.
+package spectate
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+//go:embed viewer.html
+var viewerPage []byte
+
+const (
+	// VIEWER_QUEUE is how many frames can wait to be sent to a viewer. A
+	// viewer that can't keep up misses frames instead of slowing the game
+	// down.
+	VIEWER_QUEUE = 4
+	// WRITE_TIMEOUT is how long a viewer can take to read a frame before
+	// it's disconnected.
+	WRITE_TIMEOUT = 5 * time.Second
+)
+
+// Hello is the first message a viewer gets, with what it needs to draw the
+// frames that follow.
+type Hello struct {
+	Stream     string  `json:"stream"`
+	Width      float32 `json:"width"`
+	Height     float32 `json:"height"`
+	ShipSize   float32 `json:"shipSize"`
+	ShotSize   float32 `json:"shotSize"`
+	StarRadius float32 `json:"starRadius"`
+}
+
+// Frame is the world after a tick. Numbers are rounded to a tenth of a
+// pixel, which is plenty to draw them.
+type Frame struct {
+	Tick      int             `json:"tick"`
+	Mode      sim.Mode        `json:"mode"`
+	Phase     sim.Phase       `json:"phase"`
+	Wave      int             `json:"wave"`
+	Round     int             `json:"round,omitempty"`
+	Current   int             `json:"current"`
+	Players   []FramePlayer   `json:"players"`
+	Ships     []FrameShip     `json:"ships"`
+	Shots     [][3]float32    `json:"shots"`
+	Asteroids []FrameAsteroid `json:"asteroids"`
+}
+
+type FramePlayer struct {
+	Score  int `json:"score"`
+	Lives  int `json:"lives"`
+	Rounds int `json:"rounds,omitempty"`
+}
+
+// FrameShip is a ship in play. A ship in pieces has its Wreck instead, as
+// the two ends of every segment.
+type FrameShip struct {
+	Player int          `json:"player"`
+	X      float32      `json:"x"`
+	Y      float32      `json:"y"`
+	Angle  float32      `json:"angle"`
+	Thrust bool         `json:"thrust,omitempty"`
+	Wreck  [][4]float32 `json:"wreck,omitempty"`
+}
+
+// FrameAsteroid is an asteroid's outline, as x and y one after the other.
+type FrameAsteroid struct {
+	ID     int       `json:"id"`
+	Points []float32 `json:"points"`
+}
+
+type viewer struct {
+	out chan []byte
+}
+
+type stream struct {
+	viewers   map[*viewer]bool
+	published time.Time
+}
+
+// Hub keeps the viewers of every stream. Games publish to it from any
+// goroutine.
+type Hub struct {
+	mu      sync.Mutex
+	streams map[string]*stream
+}
+
+func NewHub() *Hub {
+	return &Hub{streams: map[string]*stream{}}
+}
+
+// ListenAndServe serves the hub's viewer and streams on addr until it fails.
+func (h *Hub) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("spectate: watch on http://%s/", listener.Addr())
+	return http.Serve(listener, h)
+}
+
+func (h *Hub) stream(name string) *stream {
+	s := h.streams[name]
+	if s == nil {
+		s = &stream{viewers: map[*viewer]bool{}}
+		h.streams[name] = s
+	}
+	return s
+}
+
+// Publish sends the world to everyone watching the stream. The frame is only
+// made if someone is.
+func (h *Hub) Publish(name string, w *sim.World) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.stream(name)
+	s.published = time.Now()
+	if len(s.viewers) == 0 {
+		return
+	}
+	data, err := json.Marshal(NewFrame(w))
+	if err != nil {
+		log.Println("spectate:", err)
+		return
+	}
+	for v := range s.viewers {
+		select {
+		case v.out <- data:
+		default:
+		}
+	}
+}
+
+// End takes a stream off the list once its game is over. Its viewers stay
+// connected in case it comes back, and the stream is forgotten when the last
+// of them leaves.
+func (h *Hub) End(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s := h.streams[name]; s != nil {
+		s.published = time.Time{}
+		h.prune(name)
+	}
+}
+
+// prune forgets the stream if nobody watches it and its game is over, or
+// never started.
+func (h *Hub) prune(name string) {
+	if s := h.streams[name]; s != nil && len(s.viewers) == 0 && s.published.IsZero() {
+		delete(h.streams, name)
+	}
+}
+
+// Streams returns the names of the streams published in the last second.
+func (h *Hub) Streams() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	names := []string{}
+	for name, s := range h.streams {
+		if time.Since(s.published) < time.Second {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(viewerPage)
+	case "/streams":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.Streams())
+	case "/ws":
+		h.watch(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// watch streams to a viewer until it goes away. Its frames are only ever
+// answered with a pong or the end of the connection.
+func (h *Hub) watch(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("stream")
+	conn, reader, err := upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	//Un navegador trabado no puede dejar la conexion abierta para siempre
+	send := func(opcode byte, payload []byte) error {
+		conn.SetWriteDeadline(time.Now().Add(WRITE_TIMEOUT))
+		return writeFrame(conn, opcode, payload)
+	}
+
+	v := &viewer{out: make(chan []byte, VIEWER_QUEUE)}
+	hello, _ := json.Marshal(Hello{
+		Stream:     name,
+		Width:      sim.SCREEN_SIZE_X,
+		Height:     sim.SCREEN_SIZE_Y,
+		ShipSize:   sim.PLAYER_SHIP_SIZE,
+		ShotSize:   sim.PROJECTILE_SIZE,
+		StarRadius: sim.STAR_RADIUS,
+	})
+	if err := send(opText, hello); err != nil {
+		return
+	}
+	h.mu.Lock()
+	h.stream(name).viewers[v] = true
+	h.mu.Unlock()
+
+	control := make(chan []byte, 1)
+	go func() {
+		defer close(control)
+		for {
+			opcode, payload, err := readFrame(reader)
+			if err != nil || opcode == opClose {
+				return
+			}
+			if opcode == opPing {
+				select {
+				case control <- payload:
+				default:
+				}
+			}
+		}
+	}()
+
+	for done := false; !done; {
+		select {
+		case data := <-v.out:
+			done = send(opText, data) != nil
+		case payload, ok := <-control:
+			if !ok {
+				send(opClose, nil)
+				done = true
+			} else {
+				done = send(opPong, payload) != nil
+			}
+		}
+	}
+	h.mu.Lock()
+	delete(h.stream(name).viewers, v)
+	h.prune(name)
+	h.mu.Unlock()
+}
+
+// NewFrame makes the frame of the world as it is now.
+func NewFrame(w *sim.World) Frame {
+	f := Frame{
+		Tick:      w.Tick,
+		Mode:      w.Rules.Mode,
+		Phase:     w.Phase,
+		Wave:      w.Wave,
+		Round:     w.Round,
+		Current:   w.Current,
+		Players:   []FramePlayer{},
+		Ships:     []FrameShip{},
+		Shots:     [][3]float32{},
+		Asteroids: []FrameAsteroid{},
+	}
+	for _, p := range w.Players {
+		f.Players = append(f.Players, FramePlayer{Score: p.Score, Lives: p.Lives, Rounds: p.Rounds})
+	}
+	for _, s := range w.Ships {
+		ship := FrameShip{
+			Player: s.Player,
+			X:      round(s.Pos.X),
+			Y:      round(s.Pos.Y),
+			Angle:  float32(math.Round(float64(s.Orientation)*1000) / 1000),
+			Thrust: s.Thrusting,
+		}
+		if s.Collision {
+			for _, segment := range s.Wreck {
+				half := sim.Vector2Rotate(segment.Half, segment.Angle)
+				a, b := sim.Vector2Subtract(segment.Pos, half), sim.Vector2Add(segment.Pos, half)
+				ship.Wreck = append(ship.Wreck, [4]float32{round(a.X), round(a.Y), round(b.X), round(b.Y)})
+			}
+		}
+		f.Ships = append(f.Ships, ship)
+		for _, p := range s.Projectiles {
+			f.Shots = append(f.Shots, [3]float32{round(p.Pos.X), round(p.Pos.Y), float32(s.Player)})
+		}
+	}
+	for _, a := range w.Asteroids {
+		asteroid := FrameAsteroid{ID: a.ID}
+		for _, point := range a.Points() {
+			asteroid.Points = append(asteroid.Points, round(point.X), round(point.Y))
+		}
+		f.Asteroids = append(f.Asteroids, asteroid)
+	}
+	return f
+}
+
+func round(v float32) float32 {
+	return float32(math.Round(float64(v)*10) / 10)
+}
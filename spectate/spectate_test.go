@@ -0,0 +1,120 @@
+package spectate
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rodolfato/asteroids/sim"
+)
+
+// streams returns how many streams the hub keeps.
+func streams(h *Hub) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.streams)
+}
+
+// connect opens a websocket to the stream and waits for its hello.
+func connect(t *testing.T, server *httptest.Server, name string) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", server.URL+"/ws?stream="+name, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Write(conn)
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("the websocket wasn't opened: %v", err)
+	}
+	if opcode, err := readServerFrame(reader); err != nil || opcode != opText {
+		t.Fatalf("no hello from the stream: %v", err)
+	}
+	return conn
+}
+
+// readServerFrame reads a frame from the hub, which unlike the browser's
+// aren't masked, and returns its opcode.
+func readServerFrame(r *bufio.Reader) (byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, err
+	}
+	size := int64(header[1] & 0x7f)
+	switch size {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return 0, err
+		}
+		size = int64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return 0, err
+		}
+		size = int64(binary.BigEndian.Uint64(extended[:]))
+	}
+	_, err := io.CopyN(io.Discard, r, size)
+	return header[0] & 0x0f, err
+}
+
+func TestEndForgetsStreamsNobodyWatches(t *testing.T) {
+	h := NewHub()
+	w := sim.NewWorld(1, sim.Rules{Mode: sim.ModeCoop, Players: 1})
+	for _, name := range []string{"a", "b", "c"} {
+		h.Publish(name, w)
+		h.End(name)
+	}
+	if n := streams(h); n != 0 {
+		t.Errorf("the hub keeps %d streams of games that are over", n)
+	}
+}
+
+func TestLastViewerTakesTheEndedStream(t *testing.T) {
+	h := NewHub()
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	conn := connect(t, server, "game")
+	h.Publish("game", sim.NewWorld(1, sim.Rules{Mode: sim.ModeCoop, Players: 1}))
+	h.End("game")
+	if n := streams(h); n != 1 {
+		t.Fatalf("the hub keeps %d streams, want the one still watched", n)
+	}
+	conn.Close()
+
+	//El visor se va de a poco, se espera a que el hub se entere
+	deadline := time.Now().Add(5 * time.Second)
+	for streams(h) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the stream is still kept after its last viewer left")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestViewerOfALiveStreamLeaves(t *testing.T) {
+	h := NewHub()
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	h.Publish("game", sim.NewWorld(1, sim.Rules{Mode: sim.ModeCoop, Players: 1}))
+	connect(t, server, "game").Close()
+	time.Sleep(50 * time.Millisecond)
+	if names := h.Streams(); len(names) != 1 || names[0] != "game" {
+		t.Errorf("got streams %v, want the game still being played", names)
+	}
+}
@@ -0,0 +1,155 @@
+package spectate
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// The bits of RFC 6455 a viewer needs: the handshake, and frames small
+// enough to fit in memory. Messages split over several frames aren't needed
+// since viewers only ever send control frames.
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// MAX_CLIENT_FRAME is the most a viewer can send in a frame.
+const MAX_CLIENT_FRAME = 4096
+
+// upgrade answers the websocket handshake and takes over the connection.
+func upgrade(w http.ResponseWriter, r *http.Request) (net.Conn, *bufio.Reader, error) {
+	if !headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "this is a websocket", http.StatusBadRequest)
+		return nil, nil, errors.New("not a websocket handshake")
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "only the viewer served here can watch", http.StatusForbidden)
+		return nil, nil, errors.New("websocket from another origin")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusBadRequest)
+		return nil, nil, errors.New("unsupported websocket version")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "can't take over the connection", http.StatusInternalServerError)
+		return nil, nil, errors.New("the connection can't be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	rw.WriteString("Upgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, rw.Reader, nil
+}
+
+// sameOrigin is true if the page asking for the websocket came from this
+// same host, so no other page open in a browser can watch. Anything that
+// isn't a browser sends no origin and is let in.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func headerHas(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeFrame writes a whole message in a single unmasked frame, as a server
+// has to.
+func writeFrame(w io.Writer, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// readFrame reads a frame from a viewer, which always comes masked.
+func readFrame(r io.Reader) (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := header[0] & 0x0F
+	if header[1]&0x80 == 0 {
+		return 0, nil, errors.New("unmasked frame from a client")
+	}
+	size := uint64(header[1] & 0x7F)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	if size > MAX_CLIENT_FRAME {
+		return 0, nil, errors.New("frame too big")
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
@@ -0,0 +1,46 @@
+package spectate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWatchChecksTheOrigin(t *testing.T) {
+	server := httptest.NewServer(NewHub())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		origin string
+		want   int
+	}{
+		{"", http.StatusSwitchingProtocols},
+		{server.URL, http.StatusSwitchingProtocols},
+		{"http://evil.example", http.StatusForbidden},
+		{"http://" + host + ".evil.example", http.StatusForbidden},
+		{"://", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", server.URL+"/ws?stream=game", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.want {
+			t.Errorf("origin %q: status %d, want %d", test.origin, resp.StatusCode, test.want)
+		}
+		if test.want == http.StatusSwitchingProtocols && resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+			t.Errorf("origin %q: accepted with %q", test.origin, resp.Header.Get("Sec-WebSocket-Accept"))
+		}
+	}
+}
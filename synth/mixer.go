@@ -0,0 +1,163 @@
+package synth
+
+import "math"
+
+// Bus groups voices that share a volume control.
+type Bus int
+
+const (
+	BusSFX Bus = iota
+	BusMusic
+	busCount
+)
+
+// DefaultMaxVoices is how many voices a new mixer plays at once.
+const DefaultMaxVoices = 24
+
+// Voice is a buffer being played by a Mixer.
+type Voice struct {
+	bus     Bus
+	samples []float32
+	pos     int
+	loop    bool
+	volume  float32
+	pan     float32
+	done    bool
+}
+
+func (v *Voice) Stop() {
+	v.done = true
+}
+
+func (v *Voice) SetVolume(volume float32) {
+	v.volume = volume
+}
+
+// SetPan moves the voice from -1, only on the left speaker, to 1, only on
+// the right one.
+func (v *Voice) SetPan(pan float32) {
+	v.pan = max(-1, min(1, pan))
+}
+
+func (v *Voice) Playing() bool {
+	return !v.done
+}
+
+// Mixer adds together every playing voice into a single stereo stream. Each
+// voice goes through its bus volume and then the master volume. When more
+// than MaxVoices are playing the oldest sound effect is cut off.
+type Mixer struct {
+	voices    []*Voice
+	buses     [busCount]float32
+	master    float32
+	muted     bool
+	MaxVoices int
+}
+
+func NewMixer() *Mixer {
+	return &Mixer{
+		buses:     [busCount]float32{1, 1},
+		master:    1,
+		MaxVoices: DefaultMaxVoices,
+	}
+}
+
+func (m *Mixer) SetBusVolume(bus Bus, volume float32) {
+	m.buses[bus] = max(0, min(1, volume))
+}
+
+func (m *Mixer) SetMasterVolume(volume float32) {
+	m.master = max(0, min(1, volume))
+}
+
+func (m *Mixer) SetMuted(muted bool) {
+	m.muted = muted
+}
+
+func (m *Mixer) Play(bus Bus, samples []float32, volume float32, loop bool) *Voice {
+	v := &Voice{
+		bus:     bus,
+		samples: samples,
+		loop:    loop,
+		volume:  volume,
+		done:    len(samples) == 0,
+	}
+	if len(m.voices) >= m.MaxVoices {
+		m.steal()
+	}
+	m.voices = append(m.voices, v)
+	return v
+}
+
+// steal stops the oldest sound effect that isn't looping. Music and loops are
+// only stopped when nothing else is left.
+func (m *Mixer) steal() {
+	for _, v := range m.voices {
+		if v.bus == BusSFX && !v.loop && !v.done {
+			v.done = true
+			m.drop()
+			return
+		}
+	}
+	if len(m.voices) > 0 {
+		m.voices[0].done = true
+		m.drop()
+	}
+}
+
+// drop removes the voices that finished.
+func (m *Mixer) drop() {
+	playing := m.voices[:0]
+	for _, v := range m.voices {
+		if !v.done {
+			playing = append(playing, v)
+		}
+	}
+	clear(m.voices[len(playing):])
+	m.voices = playing
+}
+
+// Mix fills out with the next interleaved left and right samples of every
+// voice, dropping the voices that finished.
+func (m *Mixer) Mix(out []float32) {
+	clear(out)
+	for _, v := range m.voices {
+		volume := v.volume * m.buses[v.bus] * m.master
+		if m.muted {
+			volume = 0
+		}
+		left := volume * min(1, 1-v.pan)
+		right := volume * min(1, 1+v.pan)
+		for i := 0; i+1 < len(out) && !v.done; i += 2 {
+			out[i] += v.samples[v.pos] * left
+			out[i+1] += v.samples[v.pos] * right
+			v.pos++
+			if v.pos >= len(v.samples) {
+				if v.loop {
+					v.pos = 0
+				} else {
+					v.done = true
+				}
+			}
+		}
+	}
+	m.drop()
+	for i := range out {
+		out[i] = softClip(out[i])
+	}
+}
+
+// softClip leaves quiet samples alone and smoothly squashes the loud ones
+// into the -1 to 1 range instead of clipping them.
+func softClip(sample float32) float32 {
+	const knee = 0.8
+	magnitude := float64(max(sample, -sample))
+	if magnitude <= knee {
+		return sample
+	}
+	squashed := float32(knee + (1-knee)*math.Tanh((magnitude-knee)/(1-knee)))
+	if sample < 0 {
+		return -squashed
+	}
+	return squashed
+}
@@ -0,0 +1,59 @@
+package synth
+
+var (
+	Fire = Sound{
+		{Wave: Square, Duty: 0.25, Freq: 1400, FreqEnd: 220, Duration: 0.08, Volume: 0.3,
+			Envelope: Envelope{Attack: 0.002, Decay: 0.04, Sustain: 0.5, Release: 0.06}},
+	}
+	Thrust = Sound{
+		{Wave: Noise, Freq: 900, Duration: 0.5, Volume: 0.25,
+			Envelope: Envelope{Sustain: 1}},
+	}
+	ExplosionSmall = Sound{
+		{Wave: Noise, Freq: 6000, FreqEnd: 1500, Duration: 0.05, Volume: 0.5,
+			Envelope: Envelope{Attack: 0.001, Decay: 0.05, Sustain: 0.6, Release: 0.2}},
+	}
+	ExplosionMedium = Sound{
+		{Wave: Noise, Freq: 4000, FreqEnd: 700, Duration: 0.1, Volume: 0.6,
+			Envelope: Envelope{Attack: 0.001, Decay: 0.08, Sustain: 0.6, Release: 0.4}},
+		{Wave: Sine, Freq: 140, FreqEnd: 60, Duration: 0.1, Volume: 0.4,
+			Envelope: Envelope{Attack: 0.001, Decay: 0.1, Sustain: 0.5, Release: 0.3}},
+	}
+	ExplosionLarge = Sound{
+		{Wave: Noise, Freq: 3000, FreqEnd: 300, Duration: 0.2, Volume: 0.55,
+			Envelope: Envelope{Attack: 0.002, Decay: 0.15, Sustain: 0.6, Release: 0.8}},
+		{Wave: Sine, Freq: 100, FreqEnd: 35, Duration: 0.2, Volume: 0.35,
+			Envelope: Envelope{Attack: 0.002, Decay: 0.2, Sustain: 0.5, Release: 0.6}},
+	}
+	SaucerSiren = Sound{
+		{Wave: Square, Freq: 800, VibratoRate: 6, VibratoDepth: 0.2, Duration: 1, Volume: 0.2,
+			Envelope: Envelope{Sustain: 1}},
+	}
+	BeatLow = Sound{
+		{Wave: Triangle, Freq: 62, FreqEnd: 50, Duration: 0.07, Volume: 0.7,
+			Envelope: Envelope{Attack: 0.002, Decay: 0.05, Sustain: 0.7, Release: 0.06}},
+	}
+	BeatHigh = Sound{
+		{Wave: Triangle, Freq: 70, FreqEnd: 56, Duration: 0.07, Volume: 0.7,
+			Envelope: Envelope{Attack: 0.002, Decay: 0.05, Sustain: 0.7, Release: 0.06}},
+	}
+	ExtraLife = Sound{
+		{Wave: Square, Freq: 1047, Delay: 0.00, Duration: 0.07, Volume: 0.25, Envelope: Envelope{Attack: 0.005, Sustain: 1, Release: 0.02}},
+		{Wave: Square, Freq: 1319, Delay: 0.08, Duration: 0.07, Volume: 0.25, Envelope: Envelope{Attack: 0.005, Sustain: 1, Release: 0.02}},
+		{Wave: Square, Freq: 1568, Delay: 0.16, Duration: 0.07, Volume: 0.25, Envelope: Envelope{Attack: 0.005, Sustain: 1, Release: 0.02}},
+		{Wave: Square, Freq: 2093, Delay: 0.24, Duration: 0.2, Volume: 0.25, Envelope: Envelope{Attack: 0.005, Sustain: 1, Release: 0.15}},
+	}
+)
+
+// Presets are all the game's sounds by name.
+var Presets = map[string]Sound{
+	"fire":             Fire,
+	"thrust":           Thrust,
+	"explosion_small":  ExplosionSmall,
+	"explosion_medium": ExplosionMedium,
+	"explosion_large":  ExplosionLarge,
+	"saucer_siren":     SaucerSiren,
+	"extra_life":       ExtraLife,
+	"beat_low":         BeatLow,
+	"beat_high":        BeatHigh,
+}
@@ -0,0 +1,232 @@
+package synth
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	rest = -1
+	hold = -2
+)
+
+// Pattern is a loop of steps for a few tracks, four steps per beat.
+type Pattern struct {
+	Tempo  float64
+	Tracks []Track
+}
+
+// Track is one voice of a pattern. Each step is a MIDI note number, a rest
+// or a hold that keeps the previous note sounding.
+type Track struct {
+	Name     string
+	Wave     Waveform
+	Volume   float64
+	Envelope Envelope
+	Steps    []int
+}
+
+var waveforms = map[string]Waveform{
+	"sine":     Sine,
+	"square":   Square,
+	"triangle": Triangle,
+	"sawtooth": Sawtooth,
+	"noise":    Noise,
+}
+
+var noteNames = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+
+// DefaultEnvelope is used by every track of a parsed pattern.
+var DefaultEnvelope = Envelope{Attack: 0.005, Decay: 0.08, Sustain: 0.6, Release: 0.05}
+
+// ParsePattern reads a pattern written as text:
+//
+//	# comments are words starting with a hash
+//	tempo 120
+//	track bass triangle 0.3
+//	A2 - - - . . A2 .
+//
+// Every track line is followed by its steps, which may span several lines.
+// A step is a note like C4, F#3 or Bb2, a "." rest or a "-" hold.
+func ParsePattern(r io.Reader) (Pattern, error) {
+	pattern := Pattern{Tempo: 120}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		for i, field := range fields {
+			if strings.HasPrefix(field, "#") {
+				fields = fields[:i]
+				break
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "tempo":
+			if len(fields) != 2 {
+				return Pattern{}, fmt.Errorf("line %d: expected tempo <bpm>", line)
+			}
+			tempo, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil || tempo <= 0 {
+				return Pattern{}, fmt.Errorf("line %d: invalid tempo %q", line, fields[1])
+			}
+			pattern.Tempo = tempo
+		case "track":
+			if len(fields) != 4 {
+				return Pattern{}, fmt.Errorf("line %d: expected track <name> <wave> <volume>", line)
+			}
+			wave, ok := waveforms[fields[2]]
+			if !ok {
+				return Pattern{}, fmt.Errorf("line %d: unknown wave %q", line, fields[2])
+			}
+			volume, err := strconv.ParseFloat(fields[3], 64)
+			if err != nil {
+				return Pattern{}, fmt.Errorf("line %d: invalid volume %q", line, fields[3])
+			}
+			pattern.Tracks = append(pattern.Tracks, Track{
+				Name:     fields[1],
+				Wave:     wave,
+				Volume:   volume,
+				Envelope: DefaultEnvelope,
+			})
+		default:
+			if len(pattern.Tracks) == 0 {
+				return Pattern{}, fmt.Errorf("line %d: steps before any track", line)
+			}
+			track := &pattern.Tracks[len(pattern.Tracks)-1]
+			for _, field := range fields {
+				step, err := parseStep(field)
+				if err != nil {
+					return Pattern{}, fmt.Errorf("line %d: %w", line, err)
+				}
+				track.Steps = append(track.Steps, step)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Pattern{}, err
+	}
+	return pattern, nil
+}
+
+// MustParsePattern is like ParsePattern for patterns written in code.
+func MustParsePattern(text string) Pattern {
+	pattern, err := ParsePattern(strings.NewReader(text))
+	if err != nil {
+		panic(err)
+	}
+	return pattern
+}
+
+func LoadPattern(path string) (Pattern, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Pattern{}, err
+	}
+	defer f.Close()
+	return ParsePattern(f)
+}
+
+func parseStep(field string) (int, error) {
+	switch field {
+	case ".":
+		return rest, nil
+	case "-":
+		return hold, nil
+	}
+	semitone, ok := noteNames[field[0]]
+	if !ok || len(field) < 2 {
+		return 0, fmt.Errorf("invalid note %q", field)
+	}
+	octave := field[1:]
+	switch field[1] {
+	case '#':
+		semitone++
+		octave = field[2:]
+	case 'b':
+		semitone--
+		octave = field[2:]
+	}
+	n, err := strconv.Atoi(octave)
+	if err != nil {
+		return 0, fmt.Errorf("invalid note %q", field)
+	}
+	return 12*(n+1) + semitone, nil
+}
+
+func noteFrequency(note int) float64 {
+	return 440 * math.Pow(2, float64(note-69)/12)
+}
+
+// Steps returns the length of the pattern, which is its longest track.
+func (p Pattern) Steps() int {
+	steps := 0
+	for _, t := range p.Tracks {
+		steps = max(steps, len(t.Steps))
+	}
+	return steps
+}
+
+// Render returns every track of the pattern mixed together.
+func (p Pattern) Render(sampleRate int, loop bool) []float32 {
+	return p.RenderTracks(sampleRate, loop, nil)
+}
+
+// RenderTracks returns the named tracks of the pattern mixed together, or
+// all of them if names is empty. Looped patterns have the notes still ringing
+// at the end wrapped around to the start, so they repeat seamlessly.
+func (p Pattern) RenderTracks(sampleRate int, loop bool, names []string) []float32 {
+	stepLength := 60 / p.Tempo / 4
+	length := int(float64(p.Steps()) * stepLength * float64(sampleRate))
+	tail := 0
+	tones := Sound{}
+	for _, t := range p.Tracks {
+		if len(names) > 0 && !slices.Contains(names, t.Name) {
+			continue
+		}
+		for i := 0; i < len(t.Steps); i++ {
+			if t.Steps[i] < 0 {
+				continue
+			}
+			held := 1
+			for i+held < len(t.Steps) && t.Steps[i+held] == hold {
+				held++
+			}
+			tone := Tone{
+				Wave:     t.Wave,
+				Freq:     noteFrequency(t.Steps[i]),
+				Delay:    float64(i) * stepLength,
+				Duration: float64(held) * stepLength,
+				Volume:   t.Volume,
+				Envelope: t.Envelope,
+			}
+			tones = append(tones, tone)
+			tail = max(tail, int(math.Ceil(tone.Length()*float64(sampleRate)))-length)
+		}
+	}
+
+	samples := make([]float32, length+max(tail, 0))
+	for _, t := range tones {
+		t.Render(samples, sampleRate)
+	}
+	if loop && length > 0 {
+		for i := length; i < len(samples); i++ {
+			samples[i%length] += samples[i]
+		}
+		samples = samples[:length]
+	}
+	for i := range samples {
+		samples[i] = max(-1, min(1, samples[i]))
+	}
+	return samples
+}
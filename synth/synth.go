@@ -0,0 +1,162 @@
+// Package synth generates the game's sound effects from scratch. Sounds are
+// made of tones built from oscillators, noise, envelopes and pitch sweeps and
+// are rendered to PCM buffers, so no audio assets are needed.
+package synth
+
+import "math"
+
+const SampleRate = 44100
+
+type Waveform int
+
+const (
+	Sine Waveform = iota
+	Square
+	Triangle
+	Sawtooth
+	Noise
+)
+
+// Envelope is a classic ADSR envelope. Attack, Decay and Release are in
+// seconds and Sustain is the level held until the note is released.
+type Envelope struct {
+	Attack  float64
+	Decay   float64
+	Sustain float64
+	Release float64
+}
+
+// Level returns the envelope's volume t seconds after the note started, for a
+// note held for hold seconds.
+func (e Envelope) Level(t float64, hold float64) float64 {
+	if t < 0 {
+		return 0
+	}
+	if t >= hold {
+		if e.Release <= 0 {
+			return 0
+		}
+		released := t - hold
+		if released >= e.Release {
+			return 0
+		}
+		return e.Level(hold-1e-9, hold) * (1 - released/e.Release)
+	}
+	if t < e.Attack {
+		return t / e.Attack
+	}
+	if t < e.Attack+e.Decay {
+		return 1 - (1-e.Sustain)*(t-e.Attack)/e.Decay
+	}
+	return e.Sustain
+}
+
+// Tone is a single oscillator note. The pitch sweeps exponentially from Freq
+// to FreqEnd over the whole note and can wobble with a vibrato. For Noise,
+// Freq is how often the random value changes, lower sounding rougher.
+type Tone struct {
+	Wave         Waveform
+	Duty         float64
+	Freq         float64
+	FreqEnd      float64
+	VibratoRate  float64
+	VibratoDepth float64
+	Delay        float64
+	Duration     float64
+	Volume       float64
+	Envelope     Envelope
+}
+
+// Length returns the seconds from the start of the sound until the tone is
+// silent.
+func (t Tone) Length() float64 {
+	return t.Delay + t.Duration + t.Envelope.Release
+}
+
+// Render adds the tone to the samples buffer at the given sample rate.
+func (t Tone) Render(samples []float32, sampleRate int) {
+	total := t.Duration + t.Envelope.Release
+	start := int(t.Delay * float64(sampleRate))
+	length := int(total * float64(sampleRate))
+	duty := t.Duty
+	if duty <= 0 || duty >= 1 {
+		duty = 0.5
+	}
+
+	noise := newNoise(uint32(t.Freq*1000) + 1)
+	value := noise.next()
+	phase := 0.0
+	for i := 0; i < length && start+i < len(samples); i++ {
+		time := float64(i) / float64(sampleRate)
+		freq := t.Freq
+		if t.FreqEnd > 0 && total > 0 {
+			freq = t.Freq * math.Pow(t.FreqEnd/t.Freq, time/total)
+		}
+		if t.VibratoDepth > 0 {
+			freq *= 1 + t.VibratoDepth*math.Sin(2*math.Pi*t.VibratoRate*time)
+		}
+
+		phase += freq / float64(sampleRate)
+		if phase >= 1 {
+			phase -= math.Floor(phase)
+			value = noise.next()
+		}
+
+		var sample float64
+		switch t.Wave {
+		case Sine:
+			sample = math.Sin(2 * math.Pi * phase)
+		case Square:
+			sample = 1
+			if phase >= duty {
+				sample = -1
+			}
+		case Triangle:
+			sample = 4*math.Abs(phase-0.5) - 1
+		case Sawtooth:
+			sample = 2*phase - 1
+		case Noise:
+			sample = value
+		}
+		samples[start+i] += float32(sample * t.Volume * t.Envelope.Level(time, t.Duration))
+	}
+}
+
+// Sound is a group of tones played together.
+type Sound []Tone
+
+func (s Sound) Length() float64 {
+	length := 0.0
+	for _, t := range s {
+		length = max(length, t.Length())
+	}
+	return length
+}
+
+// Render returns the sound as mono samples between -1 and 1.
+func (s Sound) Render(sampleRate int) []float32 {
+	samples := make([]float32, int(math.Ceil(s.Length()*float64(sampleRate))))
+	for _, t := range s {
+		t.Render(samples, sampleRate)
+	}
+	for i := range samples {
+		samples[i] = max(-1, min(1, samples[i]))
+	}
+	return samples
+}
+
+// noise is a xorshift generator, so the same sound always renders the same.
+type noise struct {
+	state uint32
+}
+
+func newNoise(seed uint32) *noise {
+	return &noise{state: seed}
+}
+
+func (n *noise) next() float64 {
+	n.state ^= n.state << 13
+	n.state ^= n.state >> 17
+	n.state ^= n.state << 5
+	return float64(n.state)/float64(math.MaxUint32)*2 - 1
+}
@@ -0,0 +1,96 @@
+package synth
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEnvelopeLevel(t *testing.T) {
+	e := Envelope{Attack: 0.1, Decay: 0.2, Sustain: 0.5, Release: 0.4}
+	hold := 1.0
+	tests := []struct {
+		name string
+		t    float64
+		want float64
+	}{
+		{"before the start", -0.1, 0},
+		{"start", 0, 0},
+		{"half the attack", 0.05, 0.5},
+		{"end of the attack", 0.1, 1},
+		{"half the decay", 0.2, 0.75},
+		{"end of the decay", 0.3, 0.5},
+		{"sustain", 0.9, 0.5},
+		{"released", hold, 0.5},
+		{"half the release", hold + 0.2, 0.25},
+		{"end of the release", hold + 0.4, 0},
+		{"after the release", hold + 1, 0},
+	}
+	for _, test := range tests {
+		if got := e.Level(test.t, hold); math.Abs(got-test.want) > 1e-6 {
+			t.Errorf("%s: Level(%v) = %v, want %v", test.name, test.t, got, test.want)
+		}
+	}
+}
+
+func TestEnvelopeReleasedDuringAttack(t *testing.T) {
+	e := Envelope{Attack: 1, Sustain: 1, Release: 1}
+	if got := e.Level(0.5, 0.5); math.Abs(got-0.5) > 1e-6 {
+		t.Errorf("Level at the release = %v, want the level the attack got to, 0.5", got)
+	}
+	if got := e.Level(1, 0.5); math.Abs(got-0.25) > 1e-6 {
+		t.Errorf("Level half way through the release = %v, want 0.25", got)
+	}
+}
+
+func TestEnvelopeWithoutRelease(t *testing.T) {
+	e := Envelope{Sustain: 1}
+	if got := e.Level(0.5, 1); got != 1 {
+		t.Errorf("Level while held = %v, want 1", got)
+	}
+	if got := e.Level(1, 1); got != 0 {
+		t.Errorf("Level once released = %v, want 0", got)
+	}
+}
+
+func TestSoundRenderLength(t *testing.T) {
+	s := Sound{
+		{Wave: Sine, Freq: 440, Duration: 0.5, Volume: 1, Envelope: Envelope{Sustain: 1, Release: 0.25}},
+		{Wave: Square, Freq: 220, Delay: 0.5, Duration: 0.5, Volume: 1, Envelope: Envelope{Sustain: 1}},
+	}
+	if got := s.Length(); got != 1 {
+		t.Fatalf("Length() = %v, want 1", got)
+	}
+	if got := len(s.Render(1000)); got != 1000 {
+		t.Errorf("len(Render(1000)) = %d, want 1000", got)
+	}
+	if got := len(Sound{}.Render(SampleRate)); got != 0 {
+		t.Errorf("len of an empty sound = %d, want 0", got)
+	}
+}
+
+func TestSoundRenderClips(t *testing.T) {
+	loud := Tone{Wave: Square, Freq: 100, Duration: 0.1, Volume: 1, Envelope: Envelope{Sustain: 1}}
+	samples := Sound{loud, loud, loud}.Render(SampleRate)
+	clipped := 0
+	for i, s := range samples {
+		if s < -1 || s > 1 {
+			t.Fatalf("sample %d = %v, outside -1 and 1", i, s)
+		}
+		if s == 1 || s == -1 {
+			clipped += 1
+		}
+	}
+	if clipped == 0 {
+		t.Error("three square waves at full volume didn't clip")
+	}
+}
+
+func TestSoundRenderIsDeterministic(t *testing.T) {
+	s := Sound{{Wave: Noise, Freq: 2000, Duration: 0.1, Volume: 0.5, Envelope: Envelope{Sustain: 1}}}
+	a, b := s.Render(SampleRate), s.Render(SampleRate)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("sample %d is %v the first time and %v the second", i, a[i], b[i])
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package synth
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// EncodeWAV writes interleaved samples between -1 and 1 as a 16 bit PCM WAV.
+func EncodeWAV(w io.Writer, samples []float32, sampleRate int, channels int) error {
+	dataSize := uint32(len(samples) * 2)
+	header := []any{
+		[4]byte{'R', 'I', 'F', 'F'},
+		36 + dataSize,
+		[4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '},
+		uint32(16),
+		uint16(1),
+		uint16(channels),
+		uint32(sampleRate),
+		uint32(sampleRate * channels * 2),
+		uint16(channels * 2),
+		uint16(16),
+		[4]byte{'d', 'a', 't', 'a'},
+		dataSize,
+	}
+	for _, field := range header {
+		if err := binary.Write(w, binary.LittleEndian, field); err != nil {
+			return err
+		}
+	}
+
+	pcm := make([]int16, len(samples))
+	for i, s := range samples {
+		pcm[i] = int16(max(-1, min(1, s)) * 32767)
+	}
+	return binary.Write(w, binary.LittleEndian, pcm)
+}
+
+// SaveWAV renders the sound as a mono WAV file.
+func SaveWAV(path string, sound Sound) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := EncodeWAV(f, sound.Render(SampleRate), SampleRate, 1); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// ExportPresets writes every preset to dir as <name>.wav.
+func ExportPresets(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for name, sound := range Presets {
+		if err := SaveWAV(filepath.Join(dir, name+".wav"), sound); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package synth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestEncodeWAV(t *testing.T) {
+	samples := []float32{0, 0.5, -0.5, 1, -1, 2, -2, 0.25}
+	var buf bytes.Buffer
+	if err := EncodeWAV(&buf, samples, 22050, 2); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if len(data) != 44+len(samples)*2 {
+		t.Fatalf("the WAV is %d bytes, want a 44 byte header and %d of samples", len(data), len(samples)*2)
+	}
+
+	le := binary.LittleEndian
+	for _, field := range []struct {
+		at   int
+		want string
+	}{{0, "RIFF"}, {8, "WAVE"}, {12, "fmt "}, {36, "data"}} {
+		if got := string(data[field.at : field.at+4]); got != field.want {
+			t.Errorf("bytes %d to %d are %q, want %q", field.at, field.at+4, got, field.want)
+		}
+	}
+	for _, field := range []struct {
+		name string
+		got  uint32
+		want uint32
+	}{
+		{"RIFF size", le.Uint32(data[4:]), uint32(len(data) - 8)},
+		{"fmt size", le.Uint32(data[16:]), 16},
+		{"format", uint32(le.Uint16(data[20:])), 1},
+		{"channels", uint32(le.Uint16(data[22:])), 2},
+		{"sample rate", le.Uint32(data[24:]), 22050},
+		{"byte rate", le.Uint32(data[28:]), 22050 * 2 * 2},
+		{"block align", uint32(le.Uint16(data[32:])), 4},
+		{"bits per sample", uint32(le.Uint16(data[34:])), 16},
+		{"data size", le.Uint32(data[40:]), uint32(len(samples) * 2)},
+	} {
+		if field.got != field.want {
+			t.Errorf("%s = %d, want %d", field.name, field.got, field.want)
+		}
+	}
+
+	want := []int16{0, 16383, -16383, 32767, -32767, 32767, -32767, 8191}
+	for i, w := range want {
+		if got := int16(le.Uint16(data[44+i*2:])); got != w {
+			t.Errorf("sample %d = %d, want %d", i, got, w)
+		}
+	}
+}
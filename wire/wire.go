@@ -0,0 +1,108 @@
+// Package wire reads and writes the little endian binary messages that the
+// networked parts of the game send. Every message starts with a byte saying
+// what kind of message it is.
+package wire
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var ErrShortMessage = errors.New("message too short")
+
+type Writer struct {
+	Data []byte
+}
+
+func NewWriter(kind uint8) *Writer {
+	return &Writer{Data: []byte{kind}}
+}
+
+func (w *Writer) U8(v int) {
+	w.Data = append(w.Data, byte(v))
+}
+
+func (w *Writer) U16(v int) {
+	w.Data = binary.LittleEndian.AppendUint16(w.Data, uint16(v))
+}
+
+func (w *Writer) U32(v uint32) {
+	w.Data = binary.LittleEndian.AppendUint32(w.Data, v)
+}
+
+func (w *Writer) U64(v uint64) {
+	w.Data = binary.LittleEndian.AppendUint64(w.Data, v)
+}
+
+func (w *Writer) F32(v float32) {
+	w.U32(math.Float32bits(v))
+}
+
+func (w *Writer) Bool(v bool) {
+	if v {
+		w.U8(1)
+	} else {
+		w.U8(0)
+	}
+}
+
+// String writes up to 255 bytes of the string, prefixed by its length.
+func (w *Writer) String(v string) {
+	v = v[:min(len(v), 255)]
+	w.U8(len(v))
+	w.Data = append(w.Data, v...)
+}
+
+func (w *Writer) Bytes(v []byte) {
+	w.Data = append(w.Data, v...)
+}
+
+// Reader reads a message field by field. After the first field that doesn't
+// fit every read returns zero and Err is set.
+type Reader struct {
+	data []byte
+	Err  error
+}
+
+func NewReader(data []byte) *Reader {
+	return &Reader{data: data}
+}
+
+func (r *Reader) Bytes(n int) []byte {
+	if r.Err != nil || n < 0 || len(r.data) < n {
+		r.Err = ErrShortMessage
+		return make([]byte, max(n, 0))
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *Reader) U8() int {
+	return int(r.Bytes(1)[0])
+}
+
+func (r *Reader) U16() int {
+	return int(binary.LittleEndian.Uint16(r.Bytes(2)))
+}
+
+func (r *Reader) U32() uint32 {
+	return binary.LittleEndian.Uint32(r.Bytes(4))
+}
+
+func (r *Reader) U64() uint64 {
+	return binary.LittleEndian.Uint64(r.Bytes(8))
+}
+
+func (r *Reader) F32() float32 {
+	return math.Float32frombits(r.U32())
+}
+
+func (r *Reader) Bool() bool {
+	return r.U8() != 0
+}
+
+func (r *Reader) String() string {
+	return string(r.Bytes(r.U8()))
+}
@@ -0,0 +1,96 @@
+package wire
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	w := NewWriter(7)
+	w.U8(200)
+	w.U16(60000)
+	w.U32(4000000000)
+	w.U64(1 << 60)
+	w.F32(-1.5)
+	w.Bool(true)
+	w.String("friday")
+	w.Bytes([]byte{1, 2})
+
+	if w.Data[0] != 7 {
+		t.Fatalf("the message starts with %d, want its kind 7", w.Data[0])
+	}
+	r := NewReader(w.Data[1:])
+	if v := r.U8(); v != 200 {
+		t.Errorf("U8: got %d", v)
+	}
+	if v := r.U16(); v != 60000 {
+		t.Errorf("U16: got %d", v)
+	}
+	if v := r.U32(); v != 4000000000 {
+		t.Errorf("U32: got %d", v)
+	}
+	if v := r.U64(); v != 1<<60 {
+		t.Errorf("U64: got %d", v)
+	}
+	if v := r.F32(); v != -1.5 {
+		t.Errorf("F32: got %v", v)
+	}
+	if v := r.Bool(); !v {
+		t.Error("Bool: got false")
+	}
+	if v := r.String(); v != "friday" {
+		t.Errorf("String: got %q", v)
+	}
+	if v := r.Bytes(2); v[0] != 1 || v[1] != 2 {
+		t.Errorf("Bytes: got %v", v)
+	}
+	if r.Err != nil {
+		t.Error(r.Err)
+	}
+}
+
+func TestShortReads(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		read func(r *Reader)
+	}{
+		{"U8 of nothing", nil, func(r *Reader) { r.U8() }},
+		{"U16 of one byte", []byte{1}, func(r *Reader) { r.U16() }},
+		{"U32 of three bytes", []byte{1, 2, 3}, func(r *Reader) { r.U32() }},
+		{"U64 of seven bytes", make([]byte, 7), func(r *Reader) { r.U64() }},
+		{"F32 of two bytes", []byte{1, 2}, func(r *Reader) { r.F32() }},
+		{"String longer than the message", []byte{5, 'a', 'b'}, func(r *Reader) { _ = r.String() }},
+		{"negative Bytes", []byte{1}, func(r *Reader) { r.Bytes(-1) }},
+	}
+	for _, test := range tests {
+		r := NewReader(test.data)
+		test.read(r)
+		if !errors.Is(r.Err, ErrShortMessage) {
+			t.Errorf("%s: got %v, want %v", test.name, r.Err, ErrShortMessage)
+		}
+	}
+}
+
+func TestReadsAfterAnErrorAreZero(t *testing.T) {
+	//Falla el U32 pero quedan bytes, lo que sigue igual tiene que dar cero
+	r := NewReader([]byte{9, 9, 9})
+	if v := r.U32(); v != 0 {
+		t.Errorf("the short U32 read %d", v)
+	}
+	if v := r.U8(); v != 0 || r.Err == nil {
+		t.Errorf("the U8 after the error read %d with error %v", v, r.Err)
+	}
+	if v := r.String(); v != "" {
+		t.Errorf("the String after the error read %q", v)
+	}
+}
+
+func TestLongStringsAreCut(t *testing.T) {
+	w := NewWriter(0)
+	w.String(strings.Repeat("a", 300))
+	if v := NewReader(w.Data[1:]).String(); len(v) != 255 {
+		t.Errorf("got %d bytes back, want 255", len(v))
+	}
+}